@@ -0,0 +1,516 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/tester/shared"
+)
+
+// Coverage output formats
+const (
+	coverageFormatLCOV      = "lcov"
+	coverageFormatCobertura = "cobertura"
+)
+
+// Determine coverage output format from the file extension.
+// Cobertura is XML format so ".xml" file is output as Cobertura, otherwise LCOV
+func coverageFormat(file string) string {
+	if strings.EqualFold(filepath.Ext(file), ".xml") {
+		return coverageFormatCobertura
+	}
+	return coverageFormatLCOV
+}
+
+// Write coverage data to the file which is specified in --coverage-out option
+func writeCoverageOut(file string, c *shared.CoverageFactory) error {
+	files, err := collectCoverageFiles(c)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	fp, err := os.Create(file)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer fp.Close()
+
+	switch coverageFormat(file) {
+	case coverageFormatCobertura:
+		return writeCobertura(fp, files, time.Now())
+	default:
+		return writeLCOV(fp, files)
+	}
+}
+
+type coverageFunction struct {
+	Name string
+	Line int
+	Hits uint64
+}
+
+type coverageBranch struct {
+	Line   int
+	Block  int
+	Branch int
+	Hits   uint64
+}
+
+// Per-line coverage data of single file
+type coverageFile struct {
+	Name      string
+	Functions []*coverageFunction
+	Lines     map[int]uint64
+	Branches  []*coverageBranch
+}
+
+// Sorted line numbers which have statements
+func (f *coverageFile) lineNumbers() []int {
+	lines := make([]int, 0, len(f.Lines))
+	for line := range f.Lines {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Coverage ids are formatted as [type]_[line]_[position](_[suffix])(@[file]), see interpreter/coverage.go
+type coverageID struct {
+	Line     int
+	Position int
+	Suffix   string
+}
+
+func parseCoverageID(id string) (coverageID, bool) {
+	// File is taken from the node token so it is not needed here
+	id, _, _ = strings.Cut(id, "@")
+	spl := strings.SplitN(id, "_", 4)
+	if len(spl) < 3 {
+		return coverageID{}, false
+	}
+	line, err := strconv.Atoi(spl[1])
+	if err != nil {
+		return coverageID{}, false
+	}
+	position, err := strconv.Atoi(spl[2])
+	if err != nil {
+		return coverageID{}, false
+	}
+	cid := coverageID{Line: line, Position: position}
+	if len(spl) == 4 {
+		cid.Suffix = spl[3]
+	}
+	return cid, true
+}
+
+// Order of branch suffix: numbered branches of if/switch statement are ordered by number,
+// and "true" comes before "false" for if expression
+func branchOrder(suffix string) int {
+	switch suffix {
+	case "true":
+		return 0
+	case "false":
+		return 1
+	}
+	if n, err := strconv.Atoi(suffix); err == nil {
+		return n
+	}
+	return 0
+}
+
+// Group coverage data by file, and convert node ids to line based data
+func collectCoverageFiles(c *shared.CoverageFactory) ([]*coverageFile, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	type branchKey struct {
+		file     string
+		line     int
+		position int
+	}
+	type branchEntry struct {
+		id   coverageID
+		hits uint64
+	}
+	branches := make(map[branchKey][]*branchEntry)
+
+	fileMap := make(map[string]*coverageFile)
+	for id, tok := range c.NodeMap {
+		cid, ok := parseCoverageID(id)
+		if !ok {
+			continue
+		}
+
+		file := tok.File
+		if strings.EqualFold(filepath.Ext(file), ".vcl") {
+			if rel, err := filepath.Rel(cwd, file); err == nil && !strings.HasPrefix(rel, "..") {
+				file = rel
+			}
+		}
+		file = filepath.ToSlash(file)
+
+		f, ok := fileMap[file]
+		if !ok {
+			f = &coverageFile{
+				Name:  file,
+				Lines: make(map[int]uint64),
+			}
+			fileMap[file] = f
+		}
+
+		switch {
+		case strings.HasPrefix(id, "sub_"):
+			name := c.NameMap[id]
+			if name == "" {
+				name = id
+			}
+			f.Functions = append(f.Functions, &coverageFunction{
+				Name: name,
+				Line: cid.Line,
+				Hits: c.Subroutines[id],
+			})
+		case strings.HasPrefix(id, "stmt_"):
+			// Some statements may be on the same line, take executed one
+			if hits, ok := f.Lines[cid.Line]; !ok || c.Statements[id] > hits {
+				f.Lines[cid.Line] = c.Statements[id]
+			}
+		case strings.HasPrefix(id, "branch_"):
+			key := branchKey{file: file, line: cid.Line, position: cid.Position}
+			branches[key] = append(branches[key], &branchEntry{
+				id:   cid,
+				hits: c.Branches[id],
+			})
+		}
+	}
+
+	// Branches which share the same node are treated as the same block
+	keys := make([]branchKey, 0, len(branches))
+	for key := range branches {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].file != keys[j].file {
+			return keys[i].file < keys[j].file
+		}
+		if keys[i].line != keys[j].line {
+			return keys[i].line < keys[j].line
+		}
+		return keys[i].position < keys[j].position
+	})
+	blocks := make(map[string]int)
+	for _, key := range keys {
+		entries := branches[key]
+		sort.Slice(entries, func(i, j int) bool {
+			return branchOrder(entries[i].id.Suffix) < branchOrder(entries[j].id.Suffix)
+		})
+		f := fileMap[key.file]
+		for i, e := range entries {
+			f.Branches = append(f.Branches, &coverageBranch{
+				Line:   key.line,
+				Block:  blocks[key.file],
+				Branch: i,
+				Hits:   e.hits,
+			})
+		}
+		blocks[key.file]++
+	}
+
+	files := make([]*coverageFile, 0, len(fileMap))
+	for _, f := range fileMap {
+		sort.Slice(f.Functions, func(i, j int) bool {
+			return f.Functions[i].Line < f.Functions[j].Line
+		})
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	return files, nil
+}
+
+// Write coverage in LCOV tracefile format.
+// See https://github.com/linux-test-project/lcov/blob/master/man/geninfo.1
+func writeLCOV(w io.Writer, files []*coverageFile) error {
+	var out strings.Builder
+
+	for _, f := range files {
+		out.WriteString("TN:\n")
+		fmt.Fprintf(&out, "SF:%s\n", f.Name)
+
+		var fnh int
+		for _, fn := range f.Functions {
+			fmt.Fprintf(&out, "FN:%d,%s\n", fn.Line, fn.Name)
+		}
+		for _, fn := range f.Functions {
+			fmt.Fprintf(&out, "FNDA:%d,%s\n", fn.Hits, fn.Name)
+			if fn.Hits > 0 {
+				fnh++
+			}
+		}
+		fmt.Fprintf(&out, "FNF:%d\n", len(f.Functions))
+		fmt.Fprintf(&out, "FNH:%d\n", fnh)
+
+		var brh int
+		for _, b := range f.Branches {
+			fmt.Fprintf(&out, "BRDA:%d,%d,%d,%d\n", b.Line, b.Block, b.Branch, b.Hits)
+			if b.Hits > 0 {
+				brh++
+			}
+		}
+		fmt.Fprintf(&out, "BRF:%d\n", len(f.Branches))
+		fmt.Fprintf(&out, "BRH:%d\n", brh)
+
+		var lh int
+		for _, line := range f.lineNumbers() {
+			fmt.Fprintf(&out, "DA:%d,%d\n", line, f.Lines[line])
+			if f.Lines[line] > 0 {
+				lh++
+			}
+		}
+		fmt.Fprintf(&out, "LF:%d\n", len(f.Lines))
+		fmt.Fprintf(&out, "LH:%d\n", lh)
+		out.WriteString("end_of_record\n")
+	}
+
+	if _, err := io.WriteString(w, out.String()); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Cobertura XML structures.
+// See https://github.com/cobertura/cobertura/blob/master/cobertura/src/site/htdocs/xml/coverage-04.dtd
+type coberturaCoverage struct {
+	XMLName         xml.Name            `xml:"coverage"`
+	LineRate        string              `xml:"line-rate,attr"`
+	BranchRate      string              `xml:"branch-rate,attr"`
+	LinesCovered    int                 `xml:"lines-covered,attr"`
+	LinesValid      int                 `xml:"lines-valid,attr"`
+	BranchesCovered int                 `xml:"branches-covered,attr"`
+	BranchesValid   int                 `xml:"branches-valid,attr"`
+	Complexity      int                 `xml:"complexity,attr"`
+	Version         string              `xml:"version,attr"`
+	Timestamp       int64               `xml:"timestamp,attr"`
+	Sources         []string            `xml:"sources>source"`
+	Packages        []*coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string            `xml:"name,attr"`
+	LineRate   string            `xml:"line-rate,attr"`
+	BranchRate string            `xml:"branch-rate,attr"`
+	Complexity int               `xml:"complexity,attr"`
+	Classes    []*coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string             `xml:"name,attr"`
+	Filename   string             `xml:"filename,attr"`
+	LineRate   string             `xml:"line-rate,attr"`
+	BranchRate string             `xml:"branch-rate,attr"`
+	Complexity int                `xml:"complexity,attr"`
+	Methods    []*coberturaMethod `xml:"methods>method"`
+	Lines      []*coberturaLine   `xml:"lines>line"`
+}
+
+type coberturaMethod struct {
+	Name       string           `xml:"name,attr"`
+	Signature  string           `xml:"signature,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Lines      []*coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number            int    `xml:"number,attr"`
+	Hits              uint64 `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr,omitempty"`
+
+	// Branch counts of the line, used for calculating branch rate of method
+	branches, branchesCovered int
+}
+
+// Accumulator of covered and valid counts
+type coberturaRate struct {
+	lines, linesCovered       int
+	branches, branchesCovered int
+}
+
+func (r *coberturaRate) add(o coberturaRate) {
+	r.lines += o.lines
+	r.linesCovered += o.linesCovered
+	r.branches += o.branches
+	r.branchesCovered += o.branchesCovered
+}
+
+func (r coberturaRate) lineRate() string {
+	return rate(r.linesCovered, r.lines)
+}
+
+func (r coberturaRate) branchRate() string {
+	return rate(r.branchesCovered, r.branches)
+}
+
+func rate(covered, valid int) string {
+	// Nothing to cover is treated as fully covered
+	r := float64(1)
+	if valid > 0 {
+		r = float64(covered) / float64(valid)
+	}
+	return strconv.FormatFloat(r, 'f', 4, 64)
+}
+
+// Convert file coverage to Cobertura lines, the line which has branches holds condition coverage
+func coberturaLines(f *coverageFile) ([]*coberturaLine, coberturaRate) {
+	type branchCount struct {
+		total, covered int
+		hits           uint64
+	}
+	conditions := make(map[int]*branchCount)
+	for _, b := range f.Branches {
+		c, ok := conditions[b.Line]
+		if !ok {
+			c = &branchCount{}
+			conditions[b.Line] = c
+		}
+		c.total++
+		c.hits += b.Hits
+		if b.Hits > 0 {
+			c.covered++
+		}
+	}
+
+	hits := make(map[int]uint64)
+	for line, v := range f.Lines {
+		hits[line] = v
+	}
+	// Branch like switch case may not be on the statement line, then treat it as line
+	for line, c := range conditions {
+		if _, ok := hits[line]; !ok {
+			hits[line] = c.hits
+		}
+	}
+	numbers := make([]int, 0, len(hits))
+	for line := range hits {
+		numbers = append(numbers, line)
+	}
+	sort.Ints(numbers)
+
+	var r coberturaRate
+	lines := make([]*coberturaLine, 0, len(numbers))
+	for _, n := range numbers {
+		line := &coberturaLine{
+			Number: n,
+			Hits:   hits[n],
+		}
+		r.lines++
+		if line.Hits > 0 {
+			r.linesCovered++
+		}
+		if c, ok := conditions[n]; ok {
+			line.Branch = true
+			line.ConditionCoverage = fmt.Sprintf("%d%% (%d/%d)", c.covered*100/c.total, c.covered, c.total)
+			line.branches = c.total
+			line.branchesCovered = c.covered
+			r.branches += c.total
+			r.branchesCovered += c.covered
+		}
+		lines = append(lines, line)
+	}
+	return lines, r
+}
+
+// Write coverage in Cobertura XML format
+func writeCobertura(w io.Writer, files []*coverageFile, now time.Time) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	pkg := &coberturaPackage{
+		Name: "falco",
+	}
+	var total coberturaRate
+	for _, f := range files {
+		lines, r := coberturaLines(f)
+		total.add(r)
+
+		class := &coberturaClass{
+			Name:       strings.TrimSuffix(f.Name, filepath.Ext(f.Name)),
+			Filename:   f.Name,
+			LineRate:   r.lineRate(),
+			BranchRate: r.branchRate(),
+			Methods:    []*coberturaMethod{},
+			Lines:      lines,
+		}
+
+		// Subroutines could not be nested, so the lines belong to the nearest preceding subroutine
+		for i, fn := range f.Functions {
+			end := -1
+			if i+1 < len(f.Functions) {
+				end = f.Functions[i+1].Line
+			}
+			method := &coberturaMethod{
+				Name:  fn.Name,
+				Lines: []*coberturaLine{},
+			}
+			var mr coberturaRate
+			for _, line := range lines {
+				if line.Number < fn.Line || (end > 0 && line.Number >= end) {
+					continue
+				}
+				method.Lines = append(method.Lines, line)
+				mr.lines++
+				if line.Hits > 0 {
+					mr.linesCovered++
+				}
+				mr.branches += line.branches
+				mr.branchesCovered += line.branchesCovered
+			}
+			method.LineRate = mr.lineRate()
+			method.BranchRate = mr.branchRate()
+			class.Methods = append(class.Methods, method)
+		}
+		pkg.Classes = append(pkg.Classes, class)
+	}
+	pkg.LineRate = total.lineRate()
+	pkg.BranchRate = total.branchRate()
+
+	report := &coberturaCoverage{
+		LineRate:        total.lineRate(),
+		BranchRate:      total.branchRate(),
+		LinesCovered:    total.linesCovered,
+		LinesValid:      total.lines,
+		BranchesCovered: total.branchesCovered,
+		BranchesValid:   total.branches,
+		Version:         version,
+		Timestamp:       now.Unix(),
+		Sources:         []string{cwd},
+		Packages:        []*coberturaPackage{pkg},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.WithStack(err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ysugimoto/falco/tester/shared"
+	"github.com/ysugimoto/falco/token"
+)
+
+func testCoverageFactory() *shared.CoverageFactory {
+	tok := func(line, position int) token.Token {
+		return token.Token{File: "snippet::main", Line: line, Position: position}
+	}
+	return &shared.CoverageFactory{
+		Subroutines: shared.CoverageFactoryItem{
+			"sub_1_1": 2,
+			"sub_9_1": 0,
+		},
+		Statements: shared.CoverageFactoryItem{
+			"stmt_2_3":  2,
+			"stmt_3_3":  2,
+			"stmt_3_20": 0,
+			"stmt_10_3": 0,
+		},
+		Branches: shared.CoverageFactoryItem{
+			"branch_3_3_1":      2,
+			"branch_3_3_2":      0,
+			"branch_2_20_true":  0,
+			"branch_2_20_false": 2,
+		},
+		NodeMap: map[string]token.Token{
+			"sub_1_1":           tok(1, 1),
+			"sub_9_1":           tok(9, 1),
+			"stmt_2_3":          tok(2, 3),
+			"stmt_3_3":          tok(3, 3),
+			"stmt_3_20":         tok(3, 20),
+			"stmt_10_3":         tok(10, 3),
+			"branch_3_3_1":      tok(3, 3),
+			"branch_3_3_2":      tok(3, 3),
+			"branch_2_20_true":  tok(2, 20),
+			"branch_2_20_false": tok(2, 20),
+		},
+		NameMap: map[string]string{
+			"sub_1_1": "vcl_recv",
+			"sub_9_1": "vcl_fetch",
+		},
+	}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	files, err := collectCoverageFiles(testCoverageFactory())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := writeLCOV(&buf, files); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expect := `TN:
+SF:snippet::main
+FN:1,vcl_recv
+FN:9,vcl_fetch
+FNDA:2,vcl_recv
+FNDA:0,vcl_fetch
+FNF:2
+FNH:1
+BRDA:2,0,0,0
+BRDA:2,0,1,2
+BRDA:3,1,0,2
+BRDA:3,1,1,0
+BRF:4
+BRH:2
+DA:2,2
+DA:3,2
+DA:10,0
+LF:3
+LH:2
+end_of_record
+`
+	if diff := cmp.Diff(expect, buf.String()); diff != "" {
+		t.Errorf("LCOV output mismatch, diff=%s", diff)
+	}
+}
+
+func TestWriteCobertura(t *testing.T) {
+	files, err := collectCoverageFiles(testCoverageFactory())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := writeCobertura(&buf, files, time.Unix(0, 0)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var report coberturaCoverage
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode Cobertura XML: %s", err)
+	}
+	if report.LinesValid != 3 || report.LinesCovered != 2 {
+		t.Errorf("Unexpected line counts: valid=%d, covered=%d", report.LinesValid, report.LinesCovered)
+	}
+	if report.BranchesValid != 4 || report.BranchesCovered != 2 {
+		t.Errorf("Unexpected branch counts: valid=%d, covered=%d", report.BranchesValid, report.BranchesCovered)
+	}
+
+	class := report.Packages[0].Classes[0]
+	if len(class.Methods) != 2 {
+		t.Fatalf("Expected 2 methods, got %d", len(class.Methods))
+	}
+	recv := class.Methods[0]
+	if recv.Name != "vcl_recv" || len(recv.Lines) != 2 || recv.BranchRate != "0.5000" {
+		t.Errorf("Unexpected method: %+v", recv)
+	}
+	if diff := cmp.Diff("50% (1/2)", recv.Lines[1].ConditionCoverage); diff != "" {
+		t.Errorf("Condition coverage mismatch, diff=%s", diff)
+	}
+	fetch := class.Methods[1]
+	if fetch.Name != "vcl_fetch" || len(fetch.Lines) != 1 || fetch.LineRate != "0.0000" {
+		t.Errorf("Unexpected method: %+v", fetch)
+	}
+}
+
+func TestWriteLCOVIncludedFiles(t *testing.T) {
+	tok := func(file string) token.Token {
+		return token.Token{File: file, Line: 2, Position: 3}
+	}
+	// Statements in included files are placed at the same position
+	files, err := collectCoverageFiles(&shared.CoverageFactory{
+		Subroutines: shared.CoverageFactoryItem{},
+		Statements: shared.CoverageFactoryItem{
+			"stmt_2_3@snippet::a": 1,
+			"stmt_2_3@snippet::b": 0,
+		},
+		Branches: shared.CoverageFactoryItem{},
+		NodeMap: map[string]token.Token{
+			"stmt_2_3@snippet::a": tok("snippet::a"),
+			"stmt_2_3@snippet::b": tok("snippet::b"),
+		},
+		NameMap: map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := writeLCOV(&buf, files); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expect := `TN:
+SF:snippet::a
+FNF:0
+FNH:0
+BRF:0
+BRH:0
+DA:2,1
+LF:1
+LH:1
+end_of_record
+TN:
+SF:snippet::b
+FNF:0
+FNH:0
+BRF:0
+BRH:0
+DA:2,0
+LF:1
+LH:0
+end_of_record
+`
+	if diff := cmp.Diff(expect, buf.String()); diff != "" {
+		t.Errorf("LCOV output mismatch, diff=%s", diff)
+	}
+}
+
+func TestCoverageFormat(t *testing.T) {
+	if v := coverageFormat("coverage.XML"); v != coverageFormatCobertura {
+		t.Errorf("Expected cobertura, got %s", v)
+	}
+	if v := coverageFormat("lcov.info"); v != coverageFormatLCOV {
+		t.Errorf("Expected lcov, got %s", v)
+	}
+}
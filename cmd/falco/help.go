@@ -13,6 +13,8 @@ func printHelp(cmd string) {
 		printSimulateHelp()
 	case subcommandDAP:
 		printDAPHelp()
+	case subcommandLSP:
+		printLSPHelp()
 	case subcommandStats:
 		printStatsHelp()
 	case subcommandTest:
@@ -51,6 +53,7 @@ Subcommands:
     stats     : Analyze VCL statistics
     simulate  : Run simulator server with provided VCLs
     dap       : Launch DAP server to debug VCLs
+    lsp       : Launch Language Server Protocol server for editors
     test      : Run local testing for provided VCLs
     console   : Run terminal console
     fmt       : Run formatter for provided VCLs
@@ -109,6 +112,20 @@ Execute this command by using your editor's DAP support.
 	`))
 }
 
+func printLSPHelp() {
+	writeln(white, strings.TrimSpace(`
+Usage:
+    falco lsp [flags]
+
+Flags:
+    -I, --include_path : Add include path
+    -h, --help         : Show this help
+
+This command launches Language Server Protocol server which communicates via stdio.
+Execute this command by using your editor's LSP support.
+	`))
+}
+
 func printSimulateHelp() {
 	writeln(white, strings.TrimSpace(`
 Usage:
@@ -166,9 +183,12 @@ Flags:
     -json              : Output results as JSON
     -request           : Override request config
     --timeout          : Set timeout to running test
+    --parallel         : Run test files concurrently up to specified number
     --max_backends     : Override max backends limitation
     --max_acls         : Override max acls limitation
     --coverage         : Report code coverage
+    --coverage-out     : Write coverage data to file in LCOV, or Cobertura if the file has .xml extension
+    --reporter         : Output results in specified format (junit, tap, json)
 
 Local testing example:
     falco test -I . -I ./tests /path/to/vcl/main.vcl
@@ -189,6 +209,9 @@ Flags:
     -json              : Output results as JSON (very verbose)
     --generated        : Lint for Fastly generated VCL
     --refresh          : Refresh remote snippet cache
+    --format           : Output results in specified format (sarif, checkstyle, github)
+    --fix              : Apply suggested fixes and format fixed files
+    --fix-dry-run      : Output fixed results without overwriting files
 
 Simple linting with very verbose example:
     falco lint -I . -vv /path/to/vcl/main.vcl
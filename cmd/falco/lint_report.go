@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/linter"
+	"github.com/ysugimoto/falco/token"
+)
+
+// Lint result output formats
+const (
+	lintFormatSARIF      = "sarif"
+	lintFormatCheckstyle = "checkstyle"
+	lintFormatGitHub     = "github"
+)
+
+// Rule identifiers for the problems which do not have linter rule
+const (
+	parseErrorRule = "parse-error"
+	lintErrorRule  = "lint-error"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	falcoURI     = "https://github.com/ysugimoto/falco"
+)
+
+func isValidLintFormat(format string) bool {
+	switch format {
+	case lintFormatSARIF, lintFormatCheckstyle, lintFormatGitHub:
+		return true
+	default:
+		return false
+	}
+}
+
+// lintProblem is normalized lint result of both lint error and parse error
+type lintProblem struct {
+	File      string
+	Line      int
+	Column    int
+	EndColumn int
+	Severity  linter.Severity
+	Rule      string
+	Message   string
+	Reference string
+}
+
+// Collect problems from runner result ordered by file name, and apply severity overrides
+func (r *Runner) lintProblems(result *RunnerResult) []*lintProblem {
+	var problems []*lintProblem
+
+	files := slices.Sorted(maps.Keys(result.ParseErrors))
+	for _, file := range files {
+		pe := result.ParseErrors[file]
+		problems = append(problems, &lintProblem{
+			File:      reportPath(pe.Token.File),
+			Line:      pe.Token.Line,
+			Column:    pe.Token.Position,
+			EndColumn: endColumn(pe.Token),
+			Severity:  linter.ERROR,
+			Rule:      parseErrorRule,
+			Message:   pe.Message,
+		})
+	}
+
+	files = slices.Sorted(maps.Keys(result.LintErrors))
+	for _, file := range files {
+		// Linter reports some errors on leaving the scope, so sort them by the position
+		errs := slices.Clone(result.LintErrors[file])
+		slices.SortStableFunc(errs, func(a, b *linter.LintError) int {
+			if a.Token.Line != b.Token.Line {
+				return a.Token.Line - b.Token.Line
+			}
+			return a.Token.Position - b.Token.Position
+		})
+		for _, le := range errs {
+			severity := le.Severity
+			if v, ok := r.overrides[string(le.Rule)]; ok {
+				severity = v
+			}
+			rule := string(le.Rule)
+			if rule == "" {
+				rule = lintErrorRule
+			}
+			problems = append(problems, &lintProblem{
+				File:      reportPath(le.Token.File),
+				Line:      le.Token.Line,
+				Column:    le.Token.Position,
+				EndColumn: endColumn(le.Token),
+				Severity:  severity,
+				Rule:      rule,
+				Message:   le.Message,
+				Reference: le.Reference,
+			})
+		}
+	}
+
+	return problems
+}
+
+// Code scanning tools expect the relative path from the repository root,
+// so report the path relative to working directory if possible
+func reportPath(file string) string {
+	if !filepath.IsAbs(file) {
+		return filepath.ToSlash(file)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(cwd, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func endColumn(t token.Token) int {
+	return t.Position + len([]rune(t.Literal)) + t.Offset
+}
+
+// Write lint result in the specified format
+func (r *Runner) WriteLintReport(w io.Writer, format string, result *RunnerResult) error {
+	problems := r.lintProblems(result)
+
+	switch format {
+	case lintFormatSARIF:
+		return writeSARIF(w, problems)
+	case lintFormatCheckstyle:
+		return writeCheckstyle(w, problems)
+	case lintFormatGitHub:
+		return writeGitHubAnnotations(w, problems)
+	default:
+		return fmt.Errorf("unsupported lint output format: %s", format)
+	}
+}
+
+// SARIF structures, only necessary fields are defined.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Version string      `json:"version"`
+	Schema  string      `json:"$schema"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	Version        string       `json:"version,omitempty"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string           `json:"ruleId"`
+	RuleIndex int              `json:"ruleIndex"`
+	Level     string           `json:"level"`
+	Message   sarifMessage     `json:"message"`
+	Locations []*sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+func sarifLevel(s linter.Severity) string {
+	switch s {
+	case linter.ERROR:
+		return "error"
+	case linter.WARNING:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func writeSARIF(w io.Writer, problems []*lintProblem) error {
+	run := &sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "falco",
+				Version:        version,
+				InformationURI: falcoURI,
+				Rules:          []*sarifRule{},
+			},
+		},
+		Results: []*sarifResult{},
+	}
+
+	ruleIndexes := make(map[string]int)
+	for _, p := range problems {
+		if p.Severity == linter.IGNORE {
+			continue
+		}
+		index, ok := ruleIndexes[p.Rule]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndexes[p.Rule] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &sarifRule{
+				ID:               p.Rule,
+				ShortDescription: sarifMessage{Text: p.Rule},
+				HelpURI:          p.Reference,
+			})
+		}
+		run.Results = append(run.Results, &sarifResult{
+			RuleID:    p.Rule,
+			RuleIndex: index,
+			Level:     sarifLevel(p.Severity),
+			Message:   sarifMessage{Text: p.Message},
+			Locations: []*sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: p.File},
+						Region: sarifRegion{
+							StartLine:   p.Line,
+							StartColumn: p.Column,
+							EndColumn:   p.EndColumn,
+						},
+					},
+				},
+			},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(&sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []*sarifRun{run},
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Checkstyle XML structures
+type checkstyleReport struct {
+	XMLName xml.Name          `xml:"checkstyle"`
+	Version string            `xml:"version,attr"`
+	Files   []*checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string             `xml:"name,attr"`
+	Errors []*checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+func checkstyleSeverity(s linter.Severity) string {
+	switch s {
+	case linter.ERROR:
+		return "error"
+	case linter.WARNING:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+func writeCheckstyle(w io.Writer, problems []*lintProblem) error {
+	report := &checkstyleReport{
+		Version: "4.3",
+	}
+
+	files := make(map[string]*checkstyleFile)
+	for _, p := range problems {
+		if p.Severity == linter.IGNORE {
+			continue
+		}
+		f, ok := files[p.File]
+		if !ok {
+			f = &checkstyleFile{Name: p.File}
+			files[p.File] = f
+			report.Files = append(report.Files, f)
+		}
+		message := p.Message
+		if p.Reference != "" {
+			message += " (" + p.Reference + ")"
+		}
+		f.Errors = append(f.Errors, &checkstyleError{
+			Line:     p.Line,
+			Column:   p.Column,
+			Severity: checkstyleSeverity(p.Severity),
+			Message:  message,
+			Source:   "falco." + p.Rule,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.WithStack(err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// GitHub Actions workflow command annotations.
+// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+func githubCommand(s linter.Severity) string {
+	switch s {
+	case linter.ERROR:
+		return "error"
+	case linter.WARNING:
+		return "warning"
+	default:
+		return "notice"
+	}
+}
+
+var (
+	githubDataEscaper = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	)
+	githubPropertyEscaper = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	)
+)
+
+func writeGitHubAnnotations(w io.Writer, problems []*lintProblem) error {
+	for _, p := range problems {
+		if p.Severity == linter.IGNORE {
+			continue
+		}
+		message := p.Message
+		if p.Reference != "" {
+			message += "\nSee reference documentation: " + p.Reference
+		}
+		if _, err := fmt.Fprintf(
+			w,
+			"::%s file=%s,line=%d,col=%d,endColumn=%d,title=%s::%s\n",
+			githubCommand(p.Severity),
+			githubPropertyEscaper.Replace(p.File),
+			p.Line,
+			p.Column,
+			p.EndColumn,
+			githubPropertyEscaper.Replace("falco "+p.Rule),
+			githubDataEscaper.Replace(message),
+		); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/ysugimoto/falco/config"
+	"github.com/ysugimoto/falco/resolver"
+)
+
+func runLintReport(t *testing.T, fileName, format string) string {
+	c := &config.Config{
+		Linter: &config.LinterConfig{
+			VerboseInfo:       true,
+			IgnoreSubroutines: []string{"vcl_pipe"},
+			OutputFormat:      format,
+		},
+	}
+	resolvers, err := resolver.NewFileResolvers(fileName, c.IncludePaths)
+	if err != nil {
+		t.Fatalf("Unexpected runner creation error: %s", err)
+	}
+	runner := NewRunner(c, nil)
+	ret, err := runner.Run(resolvers[0])
+	if err != nil {
+		t.Fatalf("Unexpected error running Run(): %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := runner.WriteLintReport(&buf, format, ret); err != nil {
+		t.Fatalf("Unexpected error writing report: %s", err)
+	}
+	return buf.String()
+}
+
+func TestLintReportSARIF(t *testing.T) {
+	out := runLintReport(t, "../../examples/linter/default03.vcl", lintFormatSARIF)
+
+	var log sarifLog
+	if err := json.Unmarshal([]byte(out), &log); err != nil {
+		t.Fatalf("Failed to decode SARIF output: %s", err)
+	}
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected SARIF log: %s", out)
+	}
+	run := log.Runs[0]
+	if len(run.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(run.Results))
+	}
+	for _, r := range run.Results {
+		if r.Level != "note" {
+			t.Errorf("Expected note level for info severity, got %s", r.Level)
+		}
+		if run.Tool.Driver.Rules[r.RuleIndex].ID != r.RuleID {
+			t.Errorf("Rule index %d does not point to rule %s", r.RuleIndex, r.RuleID)
+		}
+		region := r.Locations[0].PhysicalLocation.Region
+		if region.StartLine == 0 || region.StartColumn == 0 {
+			t.Errorf("Region must be set, got %+v", region)
+		}
+	}
+}
+
+func TestLintReportParseError(t *testing.T) {
+	out := runLintReport(t, "../../examples/linter/default02.vcl", lintFormatCheckstyle)
+
+	var report checkstyleReport
+	if err := xml.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("Failed to decode checkstyle output: %s", err)
+	}
+	if len(report.Files) != 1 || len(report.Files[0].Errors) != 1 {
+		t.Fatalf("Expected one parse error, got %s", out)
+	}
+	e := report.Files[0].Errors[0]
+	if e.Severity != "error" || e.Source != "falco."+parseErrorRule {
+		t.Errorf("Unexpected parse error report: %+v", e)
+	}
+}
+
+func TestLintReportGitHub(t *testing.T) {
+	out := runLintReport(t, "../../examples/linter/default03.vcl", lintFormatGitHub)
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 annotations, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "::notice file=") || !strings.Contains(line, "default03.vcl,line=") {
+			t.Errorf("Unexpected annotation: %s", line)
+		}
+	}
+}
+
+func TestGitHubAnnotationEscape(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeGitHubAnnotations(&buf, []*lintProblem{
+		{
+			File:     "a,b.vcl",
+			Line:     1,
+			Column:   2,
+			Severity: "Error",
+			Rule:     "foo:bar",
+			Message:  "100% broken\nnext",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expect := "::error file=a%2Cb.vcl,line=1,col=2,endColumn=0,title=falco foo%3Abar::100%25 broken%0Anext\n"
+	if buf.String() != expect {
+		t.Errorf("Annotation mismatch, expect=%q, got=%q", expect, buf.String())
+	}
+}
+
+func TestLintReportWithFix(t *testing.T) {
+	for _, c := range []*config.LinterConfig{
+		{OutputFormat: "sarif", Fix: true},
+		{OutputFormat: "github", FixDryRun: true},
+	} {
+		runner := NewRunner(&config.Config{Linter: c}, nil)
+		// Flag combination is rejected before linting so resolver is not used
+		if err := runLint(runner, nil); err != ErrExit {
+			t.Errorf("Expected error for --format with fix options, got %v", err)
+		}
+	}
+}
@@ -24,12 +24,11 @@ import (
 	"github.com/ysugimoto/falco/dap"
 	ife "github.com/ysugimoto/falco/interpreter/function/errors"
 	"github.com/ysugimoto/falco/lexer"
+	"github.com/ysugimoto/falco/lsp"
 	"github.com/ysugimoto/falco/resolver"
 	"github.com/ysugimoto/falco/snippet"
 	"github.com/ysugimoto/falco/snippet/remote"
 	"github.com/ysugimoto/falco/snippet/terraform"
-	"github.com/ysugimoto/falco/tester"
-	"github.com/ysugimoto/falco/tester/shared"
 	"github.com/ysugimoto/falco/token"
 )
 
@@ -58,6 +57,7 @@ const (
 	subcommandTerraform = "terraform"
 	subcommandSimulate  = "simulate"
 	subcommandDAP       = "dap"
+	subcommandLSP       = "lsp"
 	subcommandStats     = "stats"
 	subcommandTest      = "test"
 	subcommandConsole   = "console"
@@ -137,6 +137,11 @@ func main() {
 			os.Exit(Fail)
 		}
 		os.Exit(Success)
+	case subcommandLSP:
+		if err := lsp.New(c).Run(); err != nil {
+			os.Exit(Fail)
+		}
+		os.Exit(Success)
 	case subcommandFormat:
 		// "fmt" command accepts multiple target files
 		resolvers, err = resolver.NewGlobResolver(c.Commands[1:]...)
@@ -226,6 +231,17 @@ func main() {
 }
 
 func runLint(runner *Runner, rslv resolver.Resolver) error {
+	format := runner.config.Linter.OutputFormat
+	if format != "" && !isValidLintFormat(format) {
+		writeln(red, "Unsupported output format: %s, must be one of sarif, checkstyle and github", format)
+		return ErrExit
+	}
+	// Fix messages could not be output with the formatted report
+	if format != "" && (runner.config.Linter.Fix || runner.config.Linter.FixDryRun) {
+		writeln(red, "--format could not be used with --fix or --fix-dry-run")
+		return ErrExit
+	}
+
 	result, err := runner.Run(rslv)
 	if err != nil {
 		if err != ErrParser {
@@ -234,6 +250,18 @@ func runLint(runner *Runner, rslv resolver.Resolver) error {
 		return ErrExit
 	}
 
+	// Output lint results in specified format, other messages must not be output
+	if format != "" {
+		if err := runner.WriteLintReport(os.Stdout, format, result); err != nil {
+			writeln(red, err.Error())
+			return ErrExit
+		}
+		if result.Errors > 0 || len(result.ParseErrors) > 0 {
+			return ErrExit
+		}
+		return nil
+	}
+
 	if runner.config.Json {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
@@ -243,6 +271,19 @@ func runLint(runner *Runner, rslv resolver.Resolver) error {
 		}
 	}
 
+	if runner.config.Linter.Fix || runner.config.Linter.FixDryRun {
+		fixed, err := runner.Fix(runner.config.Linter.FixDryRun)
+		if err != nil {
+			writeln(red, err.Error())
+			return ErrExit
+		}
+		if runner.config.Linter.FixDryRun {
+			writeln(cyan, ":wrench:%d problems can be fixed automatically.", fixed)
+		} else {
+			writeln(cyan, ":wrench:%d problems are fixed.", fixed)
+		}
+	}
+
 	write(red, ":fire:%d errors, ", result.Errors)
 	write(yellow, ":exclamation:%d warnings, ", result.Warnings)
 	writeln(cyan, ":speaker:%d recommendations.", result.Infos)
@@ -424,24 +465,32 @@ func printCodeLine(lx *lexer.Lexer, tok token.Token) {
 }
 
 func runTest(runner *Runner, rslv resolver.Resolver) error {
+	reporter := runner.config.Testing.Reporter
+	if reporter != "" && !isValidTestReporter(reporter) {
+		writeln(red, "Unsupported reporter: %s, must be one of junit, tap and json", reporter)
+		return ErrExit
+	}
+	// JSON output is the same as json reporter
+	if reporter == "" && runner.config.Json {
+		reporter = testReporterJSON
+	}
+
 	factory, err := runner.Test(rslv)
 	if err != nil {
 		return ErrExit
 	}
 
-	if runner.config.Json {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		if err := enc.Encode(struct {
-			Tests   []*tester.TestResult `json:"tests"`
-			Summary *shared.Counter      `json:"summary"`
-		}{
-			Tests:   factory.Results,
-			Summary: factory.Statistics,
-		}); err != nil {
+	if reporter != "" {
+		if err := writeTestReport(os.Stdout, reporter, factory); err != nil {
 			writeln(red, err.Error())
 			return ErrExit
 		}
+		if factory.Coverage != nil && runner.config.Testing.CoverageOut != "" {
+			if err := writeCoverageOut(runner.config.Testing.CoverageOut, factory.Coverage); err != nil {
+				writeln(red, err.Error())
+				return ErrExit
+			}
+		}
 		if factory.Statistics.Fails > 0 {
 			return ErrExit
 		}
@@ -534,6 +583,13 @@ func runTest(runner *Runner, rslv resolver.Resolver) error {
 			writeln(red, err.Error())
 			return ErrExit
 		}
+		if out := runner.config.Testing.CoverageOut; out != "" {
+			if err := writeCoverageOut(out, factory.Coverage); err != nil {
+				writeln(red, err.Error())
+				return ErrExit
+			}
+			writeln(white, "Coverage data is written to %s", out)
+		}
 	}
 
 	if factory.Statistics.Fails > 0 {
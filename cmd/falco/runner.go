@@ -6,6 +6,7 @@ import (
 	"maps"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -81,6 +82,7 @@ type Runner struct {
 	level       Level
 	lintErrors  map[string][]*linter.LintError
 	parseErrors map[string]*parser.ParseError
+	fixes       []*linter.Fix
 
 	// runner result fields
 	infos    int
@@ -93,12 +95,23 @@ func (r *Runner) message(c *color.Color, format string, args ...any) {
 	// Suppress output when JSON mode turns on
 	// This is because JSON only should display JSON string
 	// so any other messages we must not output
-	if r.config.Json {
+	if r.isMachineReadable() {
 		return
 	}
 	write(c, format, args...)
 }
 
+// Returns true when lint results are output as machine readable format like JSON, SARIF and so on
+func (r *Runner) isMachineReadable() bool {
+	if r.config.Json {
+		return true
+	}
+	if r.config.Linter != nil && r.config.Linter.OutputFormat != "" {
+		return true
+	}
+	return r.config.Testing != nil && r.config.Testing.Reporter != ""
+}
+
 func NewRunner(c *config.Config, fetcher snippet.Fetcher) *Runner {
 	r := &Runner{
 		level:       LevelError,
@@ -174,7 +187,7 @@ func (r *Runner) Run(rslv resolver.Resolver) (*RunnerResult, error) {
 	// Note: this context is not Go context, our linter context :)
 	ctx := lcontext.New(options...)
 	vcl, err := r.run(ctx, main, RunModeLint)
-	if err != nil && !r.config.Json {
+	if err != nil && !r.isMachineReadable() {
 		return nil, err
 	}
 
@@ -227,7 +240,7 @@ func (r *Runner) run(ctx *lcontext.Context, main *resolver.VCL, mode RunMode) (*
 				file = "in " + pe.Token.File + " "
 			}
 			// Nothing to print to stdout if JSON mode is enabled, exit early.
-			if r.config.Json {
+			if r.isMachineReadable() {
 				r.parseErrors[pe.Token.File] = pe
 			} else {
 				r.printParseError(lt.FatalError.Lexer, file, pe)
@@ -245,9 +258,13 @@ func (r *Runner) run(ctx *lcontext.Context, main *resolver.VCL, mode RunMode) (*
 			}
 
 			// Store all but ignored linter errors
-			if r.config.Json && severity != linter.IGNORE {
+			if r.isMachineReadable() && severity != linter.IGNORE {
 				r.lintErrors[le.Token.File] = append(r.lintErrors[le.Token.File], le)
 			}
+			// Collect suggested fixes for the fix mode
+			if le.Fix != nil && severity != linter.IGNORE && !le.Token.Snippet {
+				r.fixes = append(r.fixes, le.Fix)
+			}
 			r.printLinterError(r.lexers[main.Name], severity, le)
 		}
 	}
@@ -271,7 +288,7 @@ func (r *Runner) parseVCL(name, code string) (*ast.VCL, error) {
 				file = "in " + pe.Token.File + " "
 			}
 			// Nothing to print to stdout if JSON mode is enabled, exit early.
-			if r.config.Json {
+			if r.isMachineReadable() {
 				r.parseErrors[pe.Token.File] = pe
 			}
 			r.printParseError(lx, file, pe)
@@ -369,6 +386,56 @@ func (r *Runner) printLinterError(lx *lexer.Lexer, severity linter.Severity, err
 	r.message(white, "\n")
 }
 
+// Apply collected lint fixes to the files, and then format the fixed files.
+// If dryRun is true, output fixed results instead of overwriting files
+func (r *Runner) Fix(dryRun bool) (int, error) {
+	edits := make(map[string][]*linter.TextEdit)
+	for _, fix := range r.fixes {
+		for _, edit := range fix.Edits {
+			edits[edit.File] = append(edits[edit.File], edit)
+		}
+	}
+
+	files := slices.Sorted(maps.Keys(edits))
+	var fixed int
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			// File is not on the disk like terraform planned VCL, skip it
+			r.message(yellow, "Could not fix %s: %s\n", file, err.Error())
+			continue
+		}
+		result, applied, err := linter.ApplyFixes(string(src), edits[file])
+		if err != nil {
+			return fixed, errors.WithStack(err)
+		}
+
+		// Re-format fixed VCL because fix may break formatting.
+		// Note that fixed result must be parsed successfully, otherwise we must not overwrite the file
+		vcl, err := parser.New(lexer.NewFromString(result, lexer.WithFile(file))).ParseVCLOrSnippet()
+		if err != nil {
+			return fixed, errors.Wrapf(err, "Fixed result of %s could not be parsed", file)
+		}
+		formatted, err := io.ReadAll(formatter.New(r.config.Format).Format(vcl))
+		if err != nil {
+			return fixed, errors.WithStack(err)
+		}
+		fixed += applied
+
+		if dryRun {
+			writeln(cyan, "Fixed result of %s (%d fixes):", file, applied)
+			// Output VCL as it is, emoji replacement should not be applied
+			fmt.Fprintln(output, string(formatted))
+			continue
+		}
+		if err := os.WriteFile(file, formatted, 0o644); err != nil {
+			return fixed, errors.WithStack(err)
+		}
+		r.message(cyan, "Fixed %d problems in %s\n", applied, file)
+	}
+	return fixed, nil
+}
+
 func (r *Runner) Stats(rslv resolver.Resolver) (*StatsResult, error) {
 	options := []lcontext.Option{lcontext.WithResolver(rslv)}
 	// If remote snippets exists, prepare parse and prepend to main VCL
@@ -414,6 +481,7 @@ func (r *Runner) Simulate(rslv resolver.Resolver) error {
 		icontext.WithMaxAcls(r.config.OverrideMaxAcls),
 		icontext.WithActualResponse(sc.IsProxyResponse),
 		icontext.WithTLServer(isTLS),
+		icontext.WithBackendProbe(true),
 	}
 
 	if r.snippets != nil {
@@ -425,12 +493,23 @@ func (r *Runner) Simulate(rslv resolver.Resolver) error {
 	if r.config.OverrideBackends != nil {
 		options = append(options, icontext.WithOverrideBackends(r.config.OverrideBackends))
 	}
+	if r.config.MockBackends != nil {
+		options = append(options, icontext.WithMockBackends(r.config.MockBackends))
+	}
 	// If simulator configuration has edge dictionaries, inject them
 	if sc.OverrideEdgeDictionaries != nil {
 		options = append(options, icontext.WithInjectEdgeDictionaries(sc.OverrideEdgeDictionaries))
 	}
+	if sc.Shielding != nil {
+		if sc.Shielding.Edge == "" || sc.Shielding.Shield == "" || sc.Shielding.Edge == sc.Shielding.Shield {
+			return errors.New("simulator.shielding must have different edge and shield POP names")
+		}
+		options = append(options, icontext.WithShielding(sc.Shielding))
+	}
 
 	i := interpreter.New(options...)
+	// Stop backend probes when the simulator is shut down
+	defer i.Close()
 
 	if sc.IsDebug {
 		// If debugger flag is on, run debugger mode
@@ -478,6 +557,9 @@ func (r *Runner) Test(rslv resolver.Resolver) (*tester.TestFactory, error) {
 	if tc.OverrideEdgeDictionaries != nil {
 		options = append(options, icontext.WithInjectEdgeDictionaries(tc.OverrideEdgeDictionaries))
 	}
+	if r.config.MockBackends != nil {
+		options = append(options, icontext.WithMockBackends(r.config.MockBackends))
+	}
 
 	// Factory override variables.
 	// The order is imporotant, should do yaml -> cli order because cli could override yaml configuration
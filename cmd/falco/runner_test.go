@@ -494,6 +494,56 @@ func TestTester(t *testing.T) {
 	}
 }
 
+func TestParallelTester(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "regex.vcl")
+	src, err := os.ReadFile("../../examples/testing/regex/regex.vcl")
+	if err != nil {
+		t.Fatalf("Unexpected read file error: %s", err)
+	}
+	if err := os.WriteFile(main, src, 0o644); err != nil {
+		t.Fatalf("Unexpected write file error: %s", err)
+	}
+	test, err := os.ReadFile("../../examples/testing/regex/regex.test.vcl")
+	if err != nil {
+		t.Fatalf("Unexpected read file error: %s", err)
+	}
+	files := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name+".test.vcl"), test, 0o644); err != nil {
+			t.Fatalf("Unexpected write file error: %s", err)
+		}
+	}
+
+	c := &config.Config{
+		Linter: &config.LinterConfig{},
+		Testing: &config.TestConfig{
+			Filter:   "*.test.vcl",
+			Parallel: 4,
+		},
+		Commands: config.Commands{"test", main},
+	}
+	resolvers, err := resolver.NewFileResolvers(main, c.IncludePaths)
+	if err != nil {
+		t.Fatalf("Unexpected runner creation error: %s", err)
+	}
+	ret, err := NewRunner(c, nil).Test(resolvers[0])
+	if err != nil {
+		t.Fatalf("Unexpected test error: %s", err)
+	}
+	if ret.Statistics.Passes != 72*len(files) {
+		t.Errorf("Testing passes should be %d, got: %d", 72*len(files), ret.Statistics.Passes)
+	}
+	if len(ret.Results) != len(files) {
+		t.Fatalf("Expected %d results, got: %d", len(files), len(ret.Results))
+	}
+	for i, name := range files {
+		if filepath.Base(ret.Results[i].Filename) != name+".test.vcl" {
+			t.Errorf("Results must be ordered by file, expected %s.test.vcl, got: %s", name, ret.Results[i].Filename)
+		}
+	}
+}
+
 func TestFastlyGeneratedVCLLinting(t *testing.T) {
 	c, err := config.New([]string{"--generated"})
 	if err != nil {
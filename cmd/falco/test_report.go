@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	ife "github.com/ysugimoto/falco/interpreter/function/errors"
+	"github.com/ysugimoto/falco/tester"
+	"github.com/ysugimoto/falco/tester/shared"
+	"github.com/ysugimoto/falco/token"
+)
+
+// Test result reporters
+const (
+	testReporterJUnit = "junit"
+	testReporterTAP   = "tap"
+	testReporterJSON  = "json"
+)
+
+func isValidTestReporter(reporter string) bool {
+	switch reporter {
+	case testReporterJUnit, testReporterTAP, testReporterJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// Write test results in the specified reporter format
+func writeTestReport(w io.Writer, reporter string, factory *tester.TestFactory) error {
+	switch reporter {
+	case testReporterJUnit:
+		return writeJUnit(w, factory)
+	case testReporterTAP:
+		return writeTAP(w, factory)
+	case testReporterJSON:
+		return writeTestJSON(w, factory)
+	default:
+		return fmt.Errorf("unsupported test reporter: %s", reporter)
+	}
+}
+
+// Display name of test case which is the same as console output
+func testCaseName(c *tester.TestCase) string {
+	var prefix string
+	if c.Group != "" {
+		prefix = c.Group + " › "
+	}
+	return fmt.Sprintf("[VCL_%s] %s%s", c.Scope, prefix, c.Name)
+}
+
+// Failure detail of test case, returns error type name, message and token which the error occurred
+func testCaseFailure(c *tester.TestCase) (string, string, *token.Token) {
+	switch e := c.Error.(type) {
+	case *ife.AssertionError:
+		return "AssertionError", e.Message, &e.Token
+	case *ife.TestingError:
+		return "TestingError", e.Message, &e.Token
+	default:
+		return "Error", e.Error(), nil
+	}
+}
+
+// Failure body includes the message, actual value, error position and captured logs
+func testCaseFailureBody(c *tester.TestCase) string {
+	_, message, tok := testCaseFailure(c)
+
+	var body strings.Builder
+	body.WriteString(message + "\n")
+	if e, ok := c.Error.(*ife.AssertionError); ok && e.Actual != nil {
+		body.WriteString("Actual Value: " + e.Actual.String() + "\n")
+	}
+	if tok != nil && tok.Line > 0 {
+		fmt.Fprintf(&body, "at %s:%d:%d\n", tok.File, tok.Line, tok.Position)
+	}
+	if len(c.Logs) > 0 {
+		body.WriteString("\n[Logs]\n")
+		for i := range c.Logs {
+			body.WriteString(c.Logs[i] + "\n")
+		}
+	}
+	return body.String()
+}
+
+func writeTestJSON(w io.Writer, factory *tester.TestFactory) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(struct {
+		Tests   []*tester.TestResult `json:"tests"`
+		Summary *shared.Counter      `json:"summary"`
+	}{
+		Tests:   factory.Results,
+		Summary: factory.Statistics,
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// JUnit XML structures.
+// See https://github.com/testmoapp/junitxml for the de-facto format
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string           `xml:"name,attr"`
+	ClassName  string           `xml:"classname,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties []*junitProperty `xml:"properties>property,omitempty"`
+	Skipped    *junitSkipped    `xml:"skipped,omitempty"`
+	Failure    *junitFailure    `xml:"failure,omitempty"`
+	Error      *junitFailure    `xml:"error,omitempty"`
+	SystemOut  string           `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// JUnit time attribute is seconds
+func junitTime(msec int64) string {
+	return fmt.Sprintf("%.3f", float64(msec)/1000)
+}
+
+func writeJUnit(w io.Writer, factory *tester.TestFactory) error {
+	report := &junitTestSuites{
+		Name: "falco",
+	}
+
+	var total int64
+	for _, r := range factory.Results {
+		suite := &junitTestSuite{
+			Name:  r.Filename,
+			Cases: []*junitTestCase{},
+		}
+		var elapsed int64
+		for _, c := range r.Cases {
+			className := strings.TrimSuffix(filepath.Base(r.Filename), ".vcl")
+			if c.Group != "" {
+				className += "." + c.Group
+			}
+			tc := &junitTestCase{
+				Name:      testCaseName(c),
+				ClassName: className,
+				Time:      junitTime(c.Time),
+				Properties: []*junitProperty{
+					{Name: "scope", Value: c.Scope},
+				},
+			}
+			if c.Group != "" {
+				tc.Properties = append(tc.Properties, &junitProperty{Name: "group", Value: c.Group})
+			}
+
+			switch {
+			case c.Skip:
+				tc.Skipped = &junitSkipped{}
+				suite.Skipped++
+			case c.Error != nil:
+				kind, message, _ := testCaseFailure(c)
+				failure := &junitFailure{
+					Message: message,
+					Type:    kind,
+					Body:    testCaseFailureBody(c),
+				}
+				// Assertion failure is reported as failure, otherwise it's an unexpected error
+				if kind == "AssertionError" {
+					tc.Failure = failure
+					suite.Failures++
+				} else {
+					tc.Error = failure
+					suite.Errors++
+				}
+			}
+			if len(c.Logs) > 0 {
+				tc.SystemOut = strings.Join(c.Logs, "\n")
+			}
+
+			suite.Tests++
+			elapsed += c.Time
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Time = junitTime(elapsed)
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		total += elapsed
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = junitTime(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.WithStack(err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Write results in TAP version 13.
+// See https://testanything.org/tap-version-13-specification.html
+func writeTAP(w io.Writer, factory *tester.TestFactory) error {
+	var out strings.Builder
+
+	var total int
+	for _, r := range factory.Results {
+		total += len(r.Cases)
+	}
+	out.WriteString("TAP version 13\n")
+	fmt.Fprintf(&out, "1..%d\n", total)
+
+	var index int
+	for _, r := range factory.Results {
+		fmt.Fprintf(&out, "# %s\n", r.Filename)
+		for _, c := range r.Cases {
+			index++
+			// Hash sign is directive separator in TAP so it must be escaped in the description
+			name := strings.ReplaceAll(testCaseName(c), "#", `\#`)
+
+			switch {
+			case c.Skip:
+				fmt.Fprintf(&out, "ok %d - %s # SKIP\n", index, name)
+			case c.Error != nil:
+				fmt.Fprintf(&out, "not ok %d - %s\n", index, name)
+				writeTAPDiagnostics(&out, c)
+			default:
+				fmt.Fprintf(&out, "ok %d - %s\n", index, name)
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, out.String()); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Write YAML diagnostic block of failed test case
+func writeTAPDiagnostics(out *strings.Builder, c *tester.TestCase) {
+	kind, message, tok := testCaseFailure(c)
+
+	out.WriteString("  ---\n")
+	fmt.Fprintf(out, "  message: %s\n", tapQuote(message))
+	fmt.Fprintf(out, "  severity: fail\n")
+	fmt.Fprintf(out, "  type: %s\n", kind)
+	if e, ok := c.Error.(*ife.AssertionError); ok && e.Actual != nil {
+		fmt.Fprintf(out, "  actual: %s\n", tapQuote(e.Actual.String()))
+	}
+	if tok != nil && tok.Line > 0 {
+		out.WriteString("  at:\n")
+		fmt.Fprintf(out, "    file: %s\n", tapQuote(tok.File))
+		fmt.Fprintf(out, "    line: %d\n", tok.Line)
+		fmt.Fprintf(out, "    column: %d\n", tok.Position)
+	}
+	fmt.Fprintf(out, "  scope: %s\n", c.Scope)
+	if c.Group != "" {
+		fmt.Fprintf(out, "  group: %s\n", tapQuote(c.Group))
+	}
+	fmt.Fprintf(out, "  duration_ms: %d\n", c.Time)
+	if len(c.Logs) > 0 {
+		out.WriteString("  logs:\n")
+		for i := range c.Logs {
+			fmt.Fprintf(out, "    - %s\n", tapQuote(c.Logs[i]))
+		}
+	}
+	out.WriteString("  ...\n")
+}
+
+// Quote string as YAML double-quoted scalar, JSON string is compatible with it
+func tapQuote(s string) string {
+	b, _ := json.Marshal(s) // nolint:errcheck
+	return string(b)
+}
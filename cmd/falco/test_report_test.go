@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ife "github.com/ysugimoto/falco/interpreter/function/errors"
+	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/tester"
+	"github.com/ysugimoto/falco/tester/shared"
+	"github.com/ysugimoto/falco/token"
+)
+
+func testReportFactory() *tester.TestFactory {
+	assertion := ife.NewAssertionError(&value.String{Value: "bar"}, "Assertion error: expect=baz, actual=bar")
+	assertion.Token = token.Token{File: "main.test.vcl", Line: 10, Position: 3}
+
+	return &tester.TestFactory{
+		Results: []*tester.TestResult{
+			{
+				Filename: "main.test.vcl",
+				Cases: []*tester.TestCase{
+					{Name: "test_recv", Group: "group", Scope: "RECV", Time: 12},
+					{
+						Name:  "test_fail",
+						Scope: "FETCH",
+						Time:  3,
+						Error: assertion,
+						Logs:  []string{"bar (main.test.vcl 9:3)"},
+					},
+					{Name: "test_skip", Scope: "RECV", Skip: true},
+				},
+			},
+			{
+				Filename: "other.test.vcl",
+				Cases: []*tester.TestCase{
+					{Name: "test_error", Scope: "DELIVER", Error: ife.NewTestingError("Variable not found")},
+				},
+			},
+		},
+		Statistics: shared.NewCounter(),
+	}
+}
+
+func TestJUnitReporter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTestReport(&buf, testReporterJUnit, testReportFactory()); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode JUnit XML: %s", err)
+	}
+	if report.Tests != 4 || report.Failures != 1 || report.Errors != 1 || report.Skipped != 1 {
+		t.Errorf("Unexpected summary: %+v", report)
+	}
+	if len(report.Suites) != 2 {
+		t.Fatalf("Expected 2 testsuites, got %d", len(report.Suites))
+	}
+
+	cases := report.Suites[0].Cases
+	if diff := cmp.Diff("[VCL_RECV] group › test_recv", cases[0].Name); diff != "" {
+		t.Errorf("Testcase name mismatch, diff=%s", diff)
+	}
+	if cases[0].ClassName != "main.test.group" || cases[0].Time != "0.012" {
+		t.Errorf("Unexpected testcase: %+v", cases[0])
+	}
+	if cases[1].Failure == nil {
+		t.Fatal("Expected failure is reported")
+	}
+	for _, s := range []string{"Actual Value: bar", "at main.test.vcl:10:3", "bar (main.test.vcl 9:3)"} {
+		if !strings.Contains(cases[1].Failure.Body, s) {
+			t.Errorf("Failure body should contain %q, got %q", s, cases[1].Failure.Body)
+		}
+	}
+	if cases[2].Skipped == nil {
+		t.Errorf("Expected skipped is reported")
+	}
+	if report.Suites[1].Cases[0].Error == nil {
+		t.Errorf("Expected testing error is reported as error")
+	}
+}
+
+func TestTAPReporter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTestReport(&buf, testReporterTAP, testReportFactory()); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expect := `TAP version 13
+1..4
+# main.test.vcl
+ok 1 - [VCL_RECV] group › test_recv
+not ok 2 - [VCL_FETCH] test_fail
+  ---
+  message: "Assertion error: expect=baz, actual=bar"
+  severity: fail
+  type: AssertionError
+  actual: "bar"
+  at:
+    file: "main.test.vcl"
+    line: 10
+    column: 3
+  scope: FETCH
+  duration_ms: 3
+  logs:
+    - "bar (main.test.vcl 9:3)"
+  ...
+ok 3 - [VCL_RECV] test_skip # SKIP
+# other.test.vcl
+not ok 4 - [VCL_DELIVER] test_error
+  ---
+  message: "Variable not found"
+  severity: fail
+  type: TestingError
+  scope: DELIVER
+  duration_ms: 0
+  ...
+`
+	if diff := cmp.Diff(expect, buf.String()); diff != "" {
+		t.Errorf("TAP output mismatch, diff=%s", diff)
+	}
+}
@@ -21,6 +21,10 @@ var needValueOptions = map[string]struct{}{
 	"-f":             {},
 	"--filter":       {},
 	"--generated":    {},
+	"--format":       {},
+	"--reporter":     {},
+	"--coverage-out": {},
+	"--parallel":     {},
 }
 
 func parseCommands(args []string) Commands {
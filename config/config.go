@@ -27,6 +27,13 @@ type OverrideBackend struct {
 	Unhealthy bool   `yaml:"unhealthy" default:"false"`
 }
 
+// Mocked backend response, the request is never sent to the actual origin
+type MockBackend struct {
+	Status  int               `yaml:"status" default:"200"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+}
+
 type EdgeDictionary map[string]string
 
 // Linter configuration
@@ -38,6 +45,9 @@ type LinterConfig struct {
 	EnforceSubroutineScopes map[string][]string `yaml:"enforce_subroutine_scopes"`
 	IgnoreSubroutines       []string            `yaml:"ignore_subroutines"`
 	IsGenerated             bool                `cli:"generated"`
+	OutputFormat            string              `cli:"format"`      // Enable only in CLI option
+	Fix                     bool                `cli:"fix"`         // Enable only in CLI option
+	FixDryRun               bool                `cli:"fix-dry-run"` // Enable only in CLI option
 }
 
 // Simulator configuration
@@ -57,13 +67,24 @@ type SimulatorConfig struct {
 	// Override Request configuration
 	OverrideRequest *RequestConfig
 
+	// Simulate origin shielding with the pair of edge and shield POP
+	Shielding *ShieldingConfig `yaml:"shielding"`
+
 	// Inject values that the simulator returns tentative value
 	// InjectValues map[string]any `yaml:"values"`
 }
 
+// Origin shielding configuration. The request which selects a shield director on the edge POP
+// is processed again with the same VCL on the shield POP
+type ShieldingConfig struct {
+	Edge   string `yaml:"edge"`
+	Shield string `yaml:"shield"`
+}
+
 // Testing configuration
 type TestConfig struct {
 	Timeout      int      `cli:"timeout" yaml:"timeout"`
+	Parallel     int      `cli:"parallel" yaml:"parallel"`
 	Filter       string   `cli:"f,filter" default:"*.test.vcl"`
 	Tags         []string `cli:"t,tag"`
 	IncludePaths []string // Copy from root field
@@ -71,6 +92,8 @@ type TestConfig struct {
 	Watch        bool     `cli:"w,watch"`      // Enable only in CLI option
 	Coverage     bool     `cli:"coverage"`     // Enable only in CLI option
 	CoverageOut  string   `cli:"coverage-out"` // Enable only in CLI option
+	Reporter     string   `cli:"reporter"`     // Enable only in CLI option
+	Suite        string   // Run only the suite of the name, enable only in DAP launch
 
 	// Override Request configuration
 	OverrideRequest *RequestConfig
@@ -136,6 +159,9 @@ type Config struct {
 	// Override Origin fetching URL
 	OverrideBackends map[string]*OverrideBackend `yaml:"override_backends"`
 
+	// Mock origin responses of backends
+	MockBackends map[string]*MockBackend `yaml:"mock_backends"`
+
 	// Override resource limits
 	OverrideMaxBackends int `cli:"max_backends" yaml:"max_backends"`
 	OverrideMaxAcls     int `cli:"mac_acls" yaml:"max_acls"`
@@ -165,6 +191,7 @@ func New(args []string) (*Config, error) {
 
 	c := &Config{
 		OverrideBackends: make(map[string]*OverrideBackend),
+		MockBackends:     make(map[string]*MockBackend),
 	}
 	if err := twist.Mix(c, options...); err != nil {
 		return nil, errors.WithStack(err)
@@ -187,6 +214,16 @@ func New(args []string) (*Config, error) {
 		}
 	}
 
+	// Coverage output requires to collect code coverage
+	if c.Testing.CoverageOut != "" {
+		c.Testing.Coverage = true
+	}
+
+	// Origin shielding is disabled unless POP names are configured
+	if s := c.Simulator.Shielding; s != nil && s.Edge == "" && s.Shield == "" {
+		c.Simulator.Shielding = nil
+	}
+
 	// Copy common fields
 	c.Simulator.IncludePaths = c.IncludePaths
 	c.Testing.IncludePaths = c.IncludePaths
@@ -90,6 +90,7 @@ func TestConfigFromCLI(t *testing.T) {
 			BreakCompoundConditions:    false,
 		},
 		OverrideBackends: make(map[string]*OverrideBackend),
+		MockBackends:     make(map[string]*MockBackend),
 	}
 
 	if diff := cmp.Diff(c, expect, cmpopts.IgnoreFields(Config{}, "FastlyServiceID", "FastlyApiKey")); diff != "" {
@@ -1,9 +1,18 @@
 package dap
 
-import "sync"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/lexer"
+	"github.com/ysugimoto/falco/parser"
+)
 
 type breakpointColl struct {
-	breakpoints map[string][]breakpoint // map[path][]breakpoint
+	breakpoints map[string][]*breakpoint // map[path][]breakpoint
 	counter     int
 	mu          sync.Mutex
 }
@@ -12,6 +21,61 @@ type breakpoint struct {
 	path string
 	line int
 	id   int
+
+	// Stop only when the VCL expression is evaluated as truthy
+	condition ast.Expression
+	// Stop only when the hit count satisfies the condition like ">= 5"
+	hitCondition func(hits int) bool
+	// Logpoint prints the message instead of stopping
+	logMessage string
+	hits       int
+}
+
+// Hit condition operators, longer operators must be placed before the shorter one
+var hitConditionOperators = []string{"==", "!=", ">=", "<=", ">", "<", "%"}
+
+// Parse hit condition of the breakpoint. The condition is an integer with optional operator,
+// a bare integer means "==" and "%" stops every N hits
+func parseHitCondition(cond string) (func(hits int) bool, error) {
+	cond = strings.TrimSpace(cond)
+	operator := "=="
+	for _, op := range hitConditionOperators {
+		if strings.HasPrefix(cond, op) {
+			operator = op
+			cond = strings.TrimSpace(strings.TrimPrefix(cond, op))
+			break
+		}
+	}
+
+	n, err := strconv.Atoi(cond)
+	if err != nil {
+		return nil, fmt.Errorf("hit condition must be an integer: %s", cond)
+	}
+
+	switch operator {
+	case "!=":
+		return func(hits int) bool { return hits != n }, nil
+	case ">=":
+		return func(hits int) bool { return hits >= n }, nil
+	case "<=":
+		return func(hits int) bool { return hits <= n }, nil
+	case ">":
+		return func(hits int) bool { return hits > n }, nil
+	case "<":
+		return func(hits int) bool { return hits < n }, nil
+	case "%":
+		if n <= 0 {
+			return nil, fmt.Errorf("hit condition modulo must be positive: %d", n)
+		}
+		return func(hits int) bool { return hits%n == 0 }, nil
+	default:
+		return func(hits int) bool { return hits == n }, nil
+	}
+}
+
+// Parse string as VCL expression like console does
+func parseExpression(expr string) (ast.Expression, error) {
+	return parser.New(lexer.NewFromString("(" + expr + ")")).ParseExpression(parser.LOWEST)
 }
 
 func (bpc *breakpointColl) newID() int {
@@ -19,17 +83,12 @@ func (bpc *breakpointColl) newID() int {
 	return bpc.counter
 }
 
-func (bpc *breakpointColl) add(path string, line int) breakpoint {
+func (bpc *breakpointColl) add(bp *breakpoint) *breakpoint {
 	bpc.mu.Lock()
 	defer bpc.mu.Unlock()
 
-	bp := breakpoint{
-		path: path,
-		line: line,
-		id:   bpc.newID(),
-	}
-
-	bpc.breakpoints[path] = append(bpc.breakpoints[path], bp)
+	bp.id = bpc.newID()
+	bpc.breakpoints[bp.path] = append(bpc.breakpoints[bp.path], bp)
 
 	return bp
 }
@@ -41,13 +100,13 @@ func (bpc *breakpointColl) clear(path string) {
 	delete(bpc.breakpoints, path)
 }
 
-func (bpc *breakpointColl) list(path string) []breakpoint {
+func (bpc *breakpointColl) list(path string) []*breakpoint {
 	bpc.mu.Lock()
 	defer bpc.mu.Unlock()
 
 	bps, ok := bpc.breakpoints[path]
 	if !ok {
-		return []breakpoint{}
+		return []*breakpoint{}
 	}
 
 	return bps
@@ -64,9 +123,18 @@ func (bpc *breakpointColl) getBreakpoint(path string, line int) *breakpoint {
 
 	for _, bp := range bps {
 		if bp.line == line {
-			return &bp
+			return bp
 		}
 	}
 
 	return nil
 }
+
+// Increment the hit count of the breakpoint and return the count
+func (bpc *breakpointColl) hit(bp *breakpoint) int {
+	bpc.mu.Lock()
+	defer bpc.mu.Unlock()
+
+	bp.hits++
+	return bp.hits
+}
@@ -0,0 +1,85 @@
+package dap
+
+import (
+	"testing"
+)
+
+func TestParseHitCondition(t *testing.T) {
+	tests := []struct {
+		condition string
+		stops     []int
+		isError   bool
+	}{
+		{condition: "3", stops: []int{3}},
+		{condition: "== 3", stops: []int{3}},
+		{condition: "!=3", stops: []int{1, 2, 4, 5, 6}},
+		{condition: ">= 5", stops: []int{5, 6}},
+		{condition: "> 5", stops: []int{6}},
+		{condition: "<= 2", stops: []int{1, 2}},
+		{condition: "< 2", stops: []int{1}},
+		{condition: "% 2", stops: []int{2, 4, 6}},
+		{condition: "% 0", isError: true},
+		{condition: "foo", isError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			cond, err := parseHitCondition(tt.condition)
+			if tt.isError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+				return
+			}
+			var stops []int
+			for hits := 1; hits <= 6; hits++ {
+				if cond(hits) {
+					stops = append(stops, hits)
+				}
+			}
+			if len(stops) != len(tt.stops) {
+				t.Errorf("Unexpected stops, want=%v, got=%v", tt.stops, stops)
+				return
+			}
+			for i := range stops {
+				if stops[i] != tt.stops[i] {
+					t.Errorf("Unexpected stops, want=%v, got=%v", tt.stops, stops)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestBreakpointShouldStop(t *testing.T) {
+	d := newDebugger(nil)
+	var messages []string
+	d.printFunc = func(msg string) {
+		messages = append(messages, msg)
+	}
+
+	hitCondition, err := parseHitCondition(">= 2")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	bp := d.setBreakpoint(&breakpoint{path: "main.vcl", line: 1, hitCondition: hitCondition})
+	if d.shouldStop(bp) {
+		t.Errorf("Breakpoint should not stop on the first hit")
+	}
+	if !d.shouldStop(bp) {
+		t.Errorf("Breakpoint should stop on the second hit")
+	}
+
+	lp := d.setBreakpoint(&breakpoint{path: "main.vcl", line: 2, logMessage: "reached"})
+	if d.shouldStop(lp) {
+		t.Errorf("Logpoint should not stop")
+	}
+	if len(messages) != 1 || messages[0] != "reached" {
+		t.Errorf("Unexpected log messages: %v", messages)
+	}
+}
@@ -1,10 +1,16 @@
 package dap
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 
+	godap "github.com/google/go-dap"
+	"github.com/pkg/errors"
 	"github.com/ysugimoto/falco/ast"
 	"github.com/ysugimoto/falco/interpreter"
+	"github.com/ysugimoto/falco/interpreter/exception"
+	"github.com/ysugimoto/falco/interpreter/value"
 )
 
 type Debugger struct {
@@ -16,13 +22,51 @@ type Debugger struct {
 
 	breakpoints *breakpointColl
 	stacks      *stackColl
+
+	// Interpreter which evaluates breakpoint conditions and log messages in the current scope.
+	// It is replaced on each request or test suite, so access via currentInterpreter
+	interpreter   *interpreter.Interpreter
+	interpreterMu sync.Mutex
+
+	// Enabled exception breakpoint filters
+	exceptionFilters map[string]bool
+	filterMu         sync.Mutex
+
+	// Recorded statements of the request to travel back in time
+	timeline *interpreter.Timeline
+}
+
+// Exception breakpoint filters
+const (
+	exceptionFilterRuntime = "runtime"
+	exceptionFilterError   = "error"
+	exceptionFilterRestart = "restart"
+)
+
+var exceptionBreakpointFilters = []godap.ExceptionBreakpointsFilter{
+	{
+		Filter:      exceptionFilterRuntime,
+		Label:       "Runtime Error",
+		Description: "Break when a runtime exception is raised",
+		Default:     true,
+	},
+	{
+		Filter:      exceptionFilterError,
+		Label:       "Error Statement",
+		Description: "Break when error statement is executed",
+	},
+	{
+		Filter:      exceptionFilterRestart,
+		Label:       "Restart",
+		Description: "Break when restart statement is executed",
+	},
 }
 
 func newDebugger(stateCh <-chan interpreter.DebugState) *Debugger {
 	return &Debugger{
 		stateCh: stateCh,
 		breakpoints: &breakpointColl{
-			breakpoints: map[string][]breakpoint{},
+			breakpoints: map[string][]*breakpoint{},
 			counter:     0,
 			mu:          sync.Mutex{},
 		},
@@ -31,6 +75,8 @@ func newDebugger(stateCh <-chan interpreter.DebugState) *Debugger {
 			counter: 0,
 			mu:      sync.Mutex{},
 		},
+		exceptionFilters: defaultExceptionFilters(),
+		timeline:         interpreter.NewTimeline(),
 	}
 }
 
@@ -51,7 +97,7 @@ func (d *Debugger) Run(node ast.Node) interpreter.DebugState {
 
 		return d.waitForNewState()
 	default:
-		if bp := d.getBreakpoint(node); bp != nil {
+		if bp := d.getBreakpoint(node); bp != nil && d.shouldStop(bp) {
 			d.mode = interpreter.DebugStepOver
 			d.appendStack(node)
 			d.notifyStoppedFunc(&notifyStoppedEventParams{
@@ -64,6 +110,36 @@ func (d *Debugger) Run(node ast.Node) interpreter.DebugState {
 	}
 }
 
+// Implements interpreter.ExceptionDebugger, stops when the filter of the exception is enabled
+func (d *Debugger) Exception(node ast.Node, e *exception.Exception) {
+	var filter string
+	switch e.Type {
+	case exception.ErrorType:
+		filter = exceptionFilterError
+	case exception.RestartType:
+		filter = exceptionFilterRestart
+	default:
+		filter = exceptionFilterRuntime
+	}
+	if !d.isExceptionFilterEnabled(filter) {
+		return
+	}
+
+	// Runtime exception has the token where the exception is raised
+	t := node.GetMeta().Token
+	if e.Token != nil {
+		t = *e.Token
+	}
+	d.mode = interpreter.DebugStepOver
+	d.stacks.append(t.Literal, t.File, t.Line)
+	d.notifyStoppedFunc(&notifyStoppedEventParams{
+		reason:      "exception",
+		description: string(e.Type),
+		text:        e.Message,
+	})
+	d.waitForNewState()
+}
+
 func (d *Debugger) Message(msg string) {
 	d.printFunc(msg)
 }
@@ -75,21 +151,187 @@ func (d *Debugger) Log(stmt *ast.LogStatement, value string) {
 func (d *Debugger) waitForNewState() interpreter.DebugState {
 	d.mode = <-d.stateCh
 
+	// Resuming the process brings the debugger back to the present
+	d.timeline.Present()
+
 	return d.mode
 }
 
+// Attach the debugger to the interpreter which processes the new request or test suite.
+// Timeline of the previous request is discarded
+func (d *Debugger) attach(i *interpreter.Interpreter) {
+	d.interpreterMu.Lock()
+	d.interpreter = i
+	d.interpreterMu.Unlock()
+
+	d.timeline.Reset()
+}
+
+// Get the attached interpreter, returns nil if not launched
+func (d *Debugger) currentInterpreter() *interpreter.Interpreter {
+	d.interpreterMu.Lock()
+	defer d.interpreterMu.Unlock()
+
+	return d.interpreter
+}
+
+// Implements interpreter.SnapshotDebugger to record the timeline
+func (d *Debugger) Snapshot(s *interpreter.Snapshot) {
+	d.timeline.Append(s)
+}
+
+func (d *Debugger) hasBreakpointAt(s *interpreter.Snapshot) bool {
+	return d.breakpoints.getBreakpoint(s.Token.File, s.Token.Line) != nil
+}
+
 func (d *Debugger) getBreakpoint(node ast.Node) *breakpoint {
 	meta := node.GetMeta()
 
 	return d.breakpoints.getBreakpoint(meta.Token.File, meta.Token.Line)
 }
 
+// Determine the breakpoint stops the process by its condition, hit condition and log message.
+// The hit count is incremented only when the condition is satisfied
+func (d *Debugger) shouldStop(bp *breakpoint) bool {
+	if bp.condition != nil {
+		ok, err := d.evaluateCondition(bp.condition)
+		if err != nil {
+			// Stop on the breakpoint to let user know the condition is wrong
+			d.printFunc(fmt.Sprintf("Failed to evaluate breakpoint condition: %s", err))
+			return true
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	hits := d.breakpoints.hit(bp)
+	if bp.hitCondition != nil && !bp.hitCondition(hits) {
+		return false
+	}
+
+	if bp.logMessage != "" {
+		d.printFunc(d.interpolate(bp.logMessage))
+		return false
+	}
+	return true
+}
+
+// Evaluate the expression in the current scope of the interpreter.
+// Condition expression allows to use undefined variables as null like if statement
+func (d *Debugger) evaluate(expr ast.Expression, isCondition bool) (value.Value, error) {
+	ip := d.currentInterpreter()
+	if ip == nil {
+		return value.Null, errors.New("interpreter is not launched")
+	}
+
+	var v value.Value
+	var err error
+	if isCondition {
+		v, err = ip.ProcessExpression(expr, interpreter.ConditionExpression())
+	} else {
+		v, err = ip.ProcessExpression(expr)
+	}
+	if err != nil {
+		return value.Null, unwrapException(err)
+	}
+	return v, nil
+}
+
+// Unwrap the exception message, DO NOT display line and position info
+func unwrapException(err error) error {
+	if re, ok := errors.Cause(err).(*exception.Exception); ok {
+		return errors.New(re.Message)
+	}
+	return err
+}
+
+// Condition is truthy like if statement, true boolean or set string
+func (d *Debugger) evaluateCondition(expr ast.Expression) (bool, error) {
+	v, err := d.evaluate(expr, true)
+	if err != nil {
+		return false, err
+	}
+
+	switch t := v.(type) {
+	case *value.Boolean:
+		return t.Value, nil
+	case *value.String:
+		return !t.IsNotSet, nil
+	default:
+		return false, fmt.Errorf("condition must be BOOL or STRING, got %s", v.Type())
+	}
+}
+
+// Interpolate expressions enclosed in braces of the log message
+func (d *Debugger) interpolate(msg string) string {
+	var b strings.Builder
+
+	for {
+		start := strings.Index(msg, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(msg[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(msg[:start])
+		b.WriteString(d.evaluateString(msg[start+1 : end]))
+		msg = msg[end+1:]
+	}
+	b.WriteString(msg)
+
+	return b.String()
+}
+
+func (d *Debugger) evaluateString(input string) string {
+	expr, err := parseExpression(input)
+	if err != nil {
+		return fmt.Sprintf("<%s>", err)
+	}
+	v, err := d.evaluate(expr, false)
+	if err != nil {
+		return fmt.Sprintf("<%s>", err)
+	}
+	return v.String()
+}
+
+func defaultExceptionFilters() map[string]bool {
+	filters := map[string]bool{}
+	for _, f := range exceptionBreakpointFilters {
+		if f.Default {
+			filters[f.Filter] = true
+		}
+	}
+	return filters
+}
+
+func (d *Debugger) setExceptionFilters(filters []string) {
+	d.filterMu.Lock()
+	defer d.filterMu.Unlock()
+
+	d.exceptionFilters = map[string]bool{}
+	for _, f := range filters {
+		d.exceptionFilters[f] = true
+	}
+}
+
+func (d *Debugger) isExceptionFilterEnabled(filter string) bool {
+	d.filterMu.Lock()
+	defer d.filterMu.Unlock()
+
+	return d.exceptionFilters[filter]
+}
+
 func (d *Debugger) clearBreakpoints(path string) {
 	d.breakpoints.clear(path)
 }
 
-func (d *Debugger) setBreakpoint(path string, line int) breakpoint {
-	return d.breakpoints.add(path, line)
+func (d *Debugger) setBreakpoint(bp *breakpoint) *breakpoint {
+	return d.breakpoints.add(bp)
 }
 
 func (d *Debugger) listBreakpoints(path string) []int {
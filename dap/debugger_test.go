@@ -0,0 +1,84 @@
+package dap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ysugimoto/falco/interpreter"
+	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/token"
+)
+
+func TestDebuggerTimeTravel(t *testing.T) {
+	d := newDebugger(nil)
+	for line := 1; line <= 4; line++ {
+		s := &interpreter.Snapshot{
+			Token: token.Token{File: "main.vcl", Line: line},
+		}
+		if line == 2 {
+			s.Writes = append(s.Writes, interpreter.VariableWrite{
+				Name:   "req.http.Foo",
+				Before: &value.String{IsNotSet: true},
+				After:  &value.String{Value: "bar"},
+			})
+		}
+		d.Snapshot(s)
+	}
+	d.setBreakpoint(&breakpoint{path: "main.vcl", line: 2})
+	current := &value.String{Value: "bar"}
+
+	tl := d.timeline
+	if tl.Traveling() != nil {
+		t.Errorf("Debugger should be at the present")
+	}
+	if tl.Travel(-1) == nil || tl.Traveling().Token.Line != 3 {
+		t.Errorf("Debugger should step back to line 3")
+	}
+	if v, _ := tl.Value("req.http.foo", current); v != current {
+		t.Errorf("Value should not be changed at line 3, got=%s", v)
+	}
+	if !tl.ReverseUntil(d.hasBreakpointAt) || tl.Traveling().Token.Line != 2 {
+		t.Errorf("Debugger should reverse continue to the breakpoint at line 2")
+	}
+	if v, _ := tl.Value("req.http.foo", current); !value.IsNotSet(v) {
+		t.Errorf("Value should be reverted at line 2, got=%s", v)
+	}
+	if tl.ReverseUntil(d.hasBreakpointAt) || tl.Traveling().Token.Line != 1 {
+		t.Errorf("Debugger should reverse continue to the beginning")
+	}
+	if tl.Travel(-1) != nil {
+		t.Errorf("Debugger could not step back from the beginning")
+	}
+	if !tl.ForwardUntil(d.hasBreakpointAt) || tl.Traveling().Token.Line != 2 {
+		t.Errorf("Debugger should continue to the breakpoint at line 2")
+	}
+	if tl.ForwardUntil(d.hasBreakpointAt) || tl.Traveling() != nil {
+		t.Errorf("Debugger should continue to the present")
+	}
+
+	// Timeline of the previous request is discarded when the debugger is attached
+	tl.Travel(-1)
+	d.attach(nil)
+	if tl.Len() != 0 || tl.Traveling() != nil {
+		t.Errorf("Timeline should be reset on attaching the debugger")
+	}
+}
+
+func TestDebuggerAttachConcurrently(t *testing.T) {
+	d := newDebugger(nil)
+	ip := interpreter.New()
+
+	// Request handler attaches the interpreter while DAP handlers read it
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.attach(ip)
+	}()
+	d.listVariables(localVariablesReference)
+	wg.Wait()
+
+	if d.currentInterpreter() != ip {
+		t.Errorf("Attached interpreter should be returned")
+	}
+}
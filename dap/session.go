@@ -15,9 +15,13 @@ import (
 	"github.com/ysugimoto/falco/interpreter"
 	icontext "github.com/ysugimoto/falco/interpreter/context"
 	"github.com/ysugimoto/falco/resolver"
+	"github.com/ysugimoto/falco/tester"
 	"golang.org/x/sync/errgroup"
 )
 
+// Timeout minutes of testing on debugging
+const testTimeout = 24 * 60
+
 type session struct {
 	conn   *bufio.ReadWriter
 	config *config.SimulatorConfig
@@ -31,10 +35,15 @@ type session struct {
 	debugger    *Debugger
 
 	stateCh chan<- interpreter.DebugState
+
+	// Closed when the client finishes configurations like breakpoints
+	configured     chan struct{}
+	configuredOnce sync.Once
 }
 
 func (s *session) start(ctx context.Context) error {
 	s.sendQueue = make(chan godap.Message)
+	s.configured = make(chan struct{})
 
 	stateCh := make(chan interpreter.DebugState)
 	s.stateCh = stateCh
@@ -78,6 +87,10 @@ func (s *session) start(ctx context.Context) error {
 func (s *session) close() error {
 	s.cancel()
 
+	// Server is not launched on testing
+	if s.server == nil {
+		return nil
+	}
 	return s.server.Shutdown(context.Background())
 }
 
@@ -118,10 +131,20 @@ func (s *session) dispatch(_ context.Context, msg godap.RequestMessage) {
 		err = s.onLaunchRequest(req)
 	case *godap.NextRequest:
 		s.onNextRequest(req)
+	case *godap.ReverseContinueRequest:
+		s.onReverseContinueRequest(req)
+	case *godap.ScopesRequest:
+		s.onScopesRequest(req)
 	case *godap.SetBreakpointsRequest:
 		err = s.onSetBreakpointsRequest(req)
+	case *godap.SetExceptionBreakpointsRequest:
+		s.onSetExceptionBreakpointsRequest(req)
+	case *godap.SetVariableRequest:
+		err = s.onSetVariableRequest(req)
 	case *godap.StackTraceRequest:
 		s.onStackTraceRequest(req)
+	case *godap.StepBackRequest:
+		s.onStepBackRequest(req)
 	case *godap.StepInRequest:
 		s.onStepInRequest(req)
 	case *godap.StepOutRequest:
@@ -204,6 +227,8 @@ func newErrorResponse(reqmsg godap.RequestMessage, err error) *godap.ErrorRespon
 
 type notifyStoppedEventParams struct {
 	reason        string
+	description   string
+	text          string
 	breakpointIDs []int
 }
 
@@ -211,8 +236,11 @@ func (s *session) notifyStoppedEvent(params *notifyStoppedEventParams) {
 	s.send(&godap.StoppedEvent{
 		Event: newEvent("stopped"),
 		Body: godap.StoppedEventBody{
-			Reason:   params.reason,
-			ThreadId: 1,
+			Reason:           params.reason,
+			Description:      params.description,
+			Text:             params.text,
+			ThreadId:         1,
+			HitBreakpointIds: params.breakpointIDs,
 		},
 	})
 }
@@ -250,12 +278,27 @@ func (s *session) onBreakpointLocationsRequest(req *godap.BreakpointLocationsReq
 }
 
 func (s *session) onConfigurationDoneRequest(req *godap.ConfigurationDoneRequest) {
+	s.configuredOnce.Do(func() {
+		close(s.configured)
+	})
+
 	s.send(&godap.ConfigurationDoneResponse{
 		Response: newResponse(req),
 	})
 }
 
 func (s *session) onContinueRequest(req *godap.ContinueRequest) {
+	// While traveling back in time, continue to the next breakpoint in the timeline
+	if s.debugger.timeline.Traveling() != nil && s.debugger.timeline.ForwardUntil(s.debugger.hasBreakpointAt) {
+		s.send(&godap.ContinueResponse{
+			Response: newResponse(req),
+		})
+		s.notifyStoppedEvent(&notifyStoppedEventParams{
+			reason: "breakpoint",
+		})
+		return
+	}
+
 	s.stateCh <- interpreter.DebugPass
 
 	s.send(&godap.ContinueResponse{
@@ -277,13 +320,30 @@ func (s *session) onInitializeRequest(req *godap.InitializeRequest) {
 			SupportsCancelRequest:              true,
 			SupportsConfigurationDoneRequest:   true,
 			SupportsTerminateRequest:           true,
+			SupportsConditionalBreakpoints:     true,
+			SupportsHitConditionalBreakpoints:  true,
+			SupportsLogPoints:                  true,
+			SupportsSetVariable:                true,
+			SupportsStepBack:                   true,
+			ExceptionBreakpointFilters:         exceptionBreakpointFilters,
 		},
 	})
 }
 
+// Launch modes
+const (
+	launchModeSimulator = "simulator"
+	launchModeTest      = "test"
+)
+
 type LaunchArguments struct {
+	Mode         string   `json:"mode"`
 	MainVCL      string   `json:"mainVCL"`
 	IncludePaths []string `json:"includePaths"`
+
+	// Testing options, run all test files which are found from mainVCL if testFile is not specified
+	TestFile string `json:"testFile"`
+	Suite    string `json:"suite"`
 }
 
 func (s *session) onLaunchRequest(req *godap.LaunchRequest) error {
@@ -303,12 +363,20 @@ func (s *session) onLaunchRequest(req *godap.LaunchRequest) error {
 		return fmt.Errorf("invalid number of resolvers")
 	}
 
-	s.interpreter = interpreter.New(
-		icontext.WithResolver(resolvers[0]),
-	)
-	s.interpreter.Debugger = s.debugger
-
-	s.launchServer()
+	switch args.Mode {
+	case "", launchModeSimulator:
+		s.interpreter = interpreter.New(
+			icontext.WithResolver(resolvers[0]),
+		)
+		s.interpreter.Debugger = s.debugger
+		s.debugger.attach(s.interpreter)
+
+		s.launchServer()
+	case launchModeTest:
+		s.launchTester(args, resolvers[0])
+	default:
+		return fmt.Errorf("unknown launch mode: %s", args.Mode)
+	}
 
 	log.Print("debugger launched")
 
@@ -337,6 +405,7 @@ func (s *session) launchServer() {
 			s.close()
 		}()
 
+		s.debugger.attach(s.interpreter)
 		s.interpreter.ServeHTTP(w, r)
 	})
 
@@ -369,7 +438,85 @@ func (s *session) launchServer() {
 	)
 }
 
+// Run tests with attaching the debugger to each test interpreter.
+// Tests start after configurations are done in order to stop on breakpoints
+func (s *session) launchTester(args *LaunchArguments, rslv resolver.Resolver) {
+	tc := &config.TestConfig{
+		// Test process could be paused for long time while debugging
+		Timeout:      testTimeout,
+		Parallel:     1,
+		Filter:       "*.test.vcl",
+		IncludePaths: args.IncludePaths,
+		Suite:        args.Suite,
+	}
+	t := tester.New(tc, []icontext.Option{
+		icontext.WithResolver(rslv),
+	})
+	t.AttachDebugger(func(i *interpreter.Interpreter) interpreter.Debugger {
+		s.debugger.attach(i)
+		return s.debugger
+	})
+
+	go func() {
+		defer func() {
+			s.send(&godap.TerminatedEvent{
+				Event: newEvent("terminated"),
+			})
+
+			s.close()
+		}()
+
+		<-s.configured
+
+		var factory *tester.TestFactory
+		var err error
+		if args.TestFile != "" {
+			factory, err = t.RunFiles(args.TestFile)
+		} else {
+			factory, err = t.Run(args.MainVCL)
+		}
+		if err != nil {
+			s.printConsole(fmt.Sprintf("Failed to run test: %s", err))
+			return
+		}
+		s.printTestResults(factory)
+	}()
+
+	s.printConsole("Running tests...")
+}
+
+func (s *session) printTestResults(factory *tester.TestFactory) {
+	var passed, failed, skipped int
+	for _, result := range factory.Results {
+		for _, c := range result.Cases {
+			name := c.Name
+			if c.Group != "" {
+				name = c.Group + " " + name
+			}
+			switch {
+			case c.Skip:
+				skipped++
+				s.printConsole(fmt.Sprintf("SKIP %s (%s)", name, c.Scope))
+			case c.Error != nil:
+				failed++
+				s.printConsole(fmt.Sprintf("FAIL %s (%s): %s", name, c.Scope, c.Error))
+			default:
+				passed++
+				s.printConsole(fmt.Sprintf("PASS %s (%s)", name, c.Scope))
+			}
+		}
+	}
+
+	s.printConsole(fmt.Sprintf(
+		"%d passed, %d failed, %d skipped, %d assertions",
+		passed, failed, skipped, factory.Statistics.Asserts,
+	))
+}
+
 func (s *session) onNextRequest(req *godap.NextRequest) {
+	if s.replayStep(&godap.NextResponse{Response: newResponse(req)}) {
+		return
+	}
 	s.stateCh <- interpreter.DebugStepOver
 
 	s.send(&godap.NextResponse{
@@ -377,6 +524,35 @@ func (s *session) onNextRequest(req *godap.NextRequest) {
 	})
 }
 
+// Step forward in the timeline instead of processing the statement while traveling back in time.
+// Returns false if not traveling
+func (s *session) replayStep(resp godap.Message) bool {
+	if s.debugger.timeline.Traveling() == nil {
+		return false
+	}
+	s.debugger.timeline.Travel(1)
+
+	s.send(resp)
+	s.notifyStoppedEvent(&notifyStoppedEventParams{
+		reason: "step",
+	})
+	return true
+}
+
+func (s *session) onReverseContinueRequest(req *godap.ReverseContinueRequest) {
+	reason := "step"
+	if s.debugger.timeline.ReverseUntil(s.debugger.hasBreakpointAt) {
+		reason = "breakpoint"
+	}
+
+	s.send(&godap.ReverseContinueResponse{
+		Response: newResponse(req),
+	})
+	s.notifyStoppedEvent(&notifyStoppedEventParams{
+		reason: reason,
+	})
+}
+
 func (s *session) onSetBreakpointsRequest(req *godap.SetBreakpointsRequest) error {
 	if req.Arguments.Source.Path == "" {
 		return fmt.Errorf("unable to set breakpoints")
@@ -387,7 +563,18 @@ func (s *session) onSetBreakpointsRequest(req *godap.SetBreakpointsRequest) erro
 	breakpoints := make([]godap.Breakpoint, 0, len(req.Arguments.Breakpoints))
 
 	for _, bp := range req.Arguments.Breakpoints {
-		res := s.debugger.setBreakpoint(req.Arguments.Source.Path, bp.Line)
+		b, err := newBreakpoint(req.Arguments.Source.Path, bp)
+		if err != nil {
+			// Invalid breakpoint is not set, and the reason is displayed on the editor
+			breakpoints = append(breakpoints, godap.Breakpoint{
+				Source:   &godap.Source{Path: req.Arguments.Source.Path},
+				Line:     bp.Line,
+				Verified: false,
+				Message:  err.Error(),
+			})
+			continue
+		}
+		res := s.debugger.setBreakpoint(b)
 
 		breakpoints = append(breakpoints, godap.Breakpoint{
 			Id:       res.id,
@@ -407,6 +594,32 @@ func (s *session) onSetBreakpointsRequest(req *godap.SetBreakpointsRequest) erro
 	return nil
 }
 
+// Create breakpoint from the request, condition is parsed as VCL expression
+func newBreakpoint(path string, sbp godap.SourceBreakpoint) (*breakpoint, error) {
+	bp := &breakpoint{
+		path:       path,
+		line:       sbp.Line,
+		logMessage: sbp.LogMessage,
+	}
+
+	if sbp.Condition != "" {
+		expr, err := parseExpression(sbp.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition: %w", err)
+		}
+		bp.condition = expr
+	}
+	if sbp.HitCondition != "" {
+		cond, err := parseHitCondition(sbp.HitCondition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hit condition: %w", err)
+		}
+		bp.hitCondition = cond
+	}
+
+	return bp, nil
+}
+
 func (s *session) onStackTraceRequest(req *godap.StackTraceRequest) {
 	stacks := s.debugger.listStacks()
 
@@ -424,6 +637,18 @@ func (s *session) onStackTraceRequest(req *godap.StackTraceRequest) {
 		}
 	}
 
+	// While traveling back in time, the statement at that time is the top frame
+	if snapshot := s.debugger.timeline.Traveling(); snapshot != nil {
+		frames = append([]godap.StackFrame{{
+			Name: fmt.Sprintf("%s (%s)", snapshot.Token.Literal, snapshot.Scope),
+			Source: &godap.Source{
+				Path: snapshot.Token.File,
+			},
+			Line:   snapshot.Token.Line,
+			Column: 1,
+		}}, frames...)
+	}
+
 	s.send(&godap.StackTraceResponse{
 		Response: newResponse(req),
 		Body: godap.StackTraceResponseBody{
@@ -433,7 +658,21 @@ func (s *session) onStackTraceRequest(req *godap.StackTraceRequest) {
 	})
 }
 
+func (s *session) onStepBackRequest(req *godap.StepBackRequest) {
+	s.debugger.timeline.Travel(-1)
+
+	s.send(&godap.StepBackResponse{
+		Response: newResponse(req),
+	})
+	s.notifyStoppedEvent(&notifyStoppedEventParams{
+		reason: "step",
+	})
+}
+
 func (s *session) onStepInRequest(req *godap.StepInRequest) {
+	if s.replayStep(&godap.StepInResponse{Response: newResponse(req)}) {
+		return
+	}
 	s.stateCh <- interpreter.DebugStepIn
 
 	s.send(&godap.StepInResponse{
@@ -442,6 +681,9 @@ func (s *session) onStepInRequest(req *godap.StepInRequest) {
 }
 
 func (s *session) onStepOutRequest(req *godap.StepOutRequest) {
+	if s.replayStep(&godap.StepOutResponse{Response: newResponse(req)}) {
+		return
+	}
 	s.stateCh <- interpreter.DebugStepOut
 
 	s.send(&godap.StepOutResponse{
@@ -469,11 +711,67 @@ func (s *session) onThreadsRequest(req *godap.ThreadsRequest) {
 	})
 }
 
+func (s *session) onScopesRequest(req *godap.ScopesRequest) {
+	scopes := make([]godap.Scope, 0, len(variableScopes))
+	for _, scope := range variableScopes {
+		scopes = append(scopes, godap.Scope{
+			Name:               scope.name,
+			VariablesReference: scope.reference,
+		})
+	}
+
+	s.send(&godap.ScopesResponse{
+		Response: newResponse(req),
+		Body: godap.ScopesResponseBody{
+			Scopes: scopes,
+		},
+	})
+}
+
 func (s *session) onVariablesRequest(req *godap.VariablesRequest) {
 	s.send(&godap.VariablesResponse{
 		Response: newResponse(req),
 		Body: godap.VariablesResponseBody{
-			Variables: []godap.Variable{},
+			Variables: s.debugger.listVariables(req.Arguments.VariablesReference),
+		},
+	})
+}
+
+func (s *session) onSetExceptionBreakpointsRequest(req *godap.SetExceptionBreakpointsRequest) {
+	s.debugger.setExceptionFilters(req.Arguments.Filters)
+
+	breakpoints := make([]godap.Breakpoint, 0, len(req.Arguments.Filters))
+	for range req.Arguments.Filters {
+		breakpoints = append(breakpoints, godap.Breakpoint{
+			Verified: true,
+		})
+	}
+
+	s.send(&godap.SetExceptionBreakpointsResponse{
+		Response: newResponse(req),
+		Body: godap.SetExceptionBreakpointsResponseBody{
+			Breakpoints: breakpoints,
+		},
+	})
+}
+
+func (s *session) onSetVariableRequest(req *godap.SetVariableRequest) error {
+	v, err := s.debugger.setVariable(
+		req.Arguments.VariablesReference,
+		req.Arguments.Name,
+		req.Arguments.Value,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.send(&godap.SetVariableResponse{
+		Response: newResponse(req),
+		Body: godap.SetVariableResponseBody{
+			Value: formatValue(v),
+			Type:  string(v.Type()),
 		},
 	})
+
+	return nil
 }
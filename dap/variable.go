@@ -0,0 +1,171 @@
+package dap
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	godap "github.com/google/go-dap"
+	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/lexer"
+	"github.com/ysugimoto/falco/parser"
+)
+
+// Variables references of scopes which are shown in the variables pane
+const (
+	localVariablesReference = iota + 1
+	requestHeadersReference
+	backendResponseReference
+)
+
+type variableScope struct {
+	name      string
+	reference int
+	prefix    string
+}
+
+var variableScopes = []variableScope{
+	{name: "Local", reference: localVariablesReference, prefix: "var."},
+	{name: "Request Headers", reference: requestHeadersReference, prefix: "req.http."},
+	{name: "Backend Response", reference: backendResponseReference, prefix: "beresp."},
+}
+
+// Backend response variables which are shown in addition to beresp.http.* headers
+var backendResponseVariables = []string{
+	"beresp.status",
+	"beresp.response",
+	"beresp.ttl",
+	"beresp.grace",
+	"beresp.stale_while_revalidate",
+	"beresp.stale_if_error",
+	"beresp.cacheable",
+}
+
+func findVariableScope(reference int) (variableScope, bool) {
+	for _, s := range variableScopes {
+		if s.reference == reference {
+			return s, true
+		}
+	}
+	return variableScope{}, false
+}
+
+// List variables of the scope in the current state of the interpreter
+func (d *Debugger) listVariables(reference int) []godap.Variable {
+	ip := d.currentInterpreter()
+	if ip == nil {
+		return []godap.Variable{}
+	}
+	ctx := ip.Context()
+
+	var names []string
+	switch reference {
+	case localVariablesReference:
+		for name := range ip.LocalVariables() {
+			names = append(names, name)
+		}
+	case requestHeadersReference:
+		if ctx.Request != nil {
+			for key := range ctx.Request.Header {
+				names = append(names, "req.http."+key)
+			}
+		}
+	case backendResponseReference:
+		// Backend response only exists after fetching
+		if ctx.BackendResponse != nil {
+			for key := range ctx.BackendResponse.Header {
+				names = append(names, "beresp.http."+key)
+			}
+			names = append(names, backendResponseVariables...)
+		}
+	}
+	slices.Sort(names)
+
+	variables := make([]godap.Variable, 0, len(names))
+	for _, name := range names {
+		expr, err := parseExpression(name)
+		if err != nil {
+			continue
+		}
+		// Variables which could not be accessed in the current scope are not shown
+		v, err := d.evaluate(expr, false)
+		if err != nil {
+			continue
+		}
+		// Show the value at the time while traveling back in time
+		v, _ = d.timeline.Value(name, v)
+		variables = append(variables, godap.Variable{
+			Name:  name,
+			Value: formatValue(v),
+			Type:  string(v.Type()),
+		})
+	}
+	return variables
+}
+
+// Set the variable by evaluating input as VCL expression like set statement does
+func (d *Debugger) setVariable(reference int, name, input string) (value.Value, error) {
+	ip := d.currentInterpreter()
+	if ip == nil {
+		return value.Null, fmt.Errorf("interpreter is not launched")
+	}
+	if d.timeline.Traveling() != nil {
+		return value.Null, fmt.Errorf("variable could not be set while traveling back in time")
+	}
+	scope, ok := findVariableScope(reference)
+	if !ok || !strings.HasPrefix(name, scope.prefix) {
+		return value.Null, fmt.Errorf("variable %s could not be set", name)
+	}
+
+	expr, err := parser.New(lexer.NewFromString(input)).ParseExpression(parser.LOWEST)
+	if err != nil {
+		return value.Null, fmt.Errorf("invalid value: %w", err)
+	}
+	stmt := &ast.SetStatement{
+		Meta:     &ast.Meta{},
+		Ident:    &ast.Ident{Meta: &ast.Meta{}, Value: name},
+		Operator: &ast.Operator{Meta: &ast.Meta{}, Operator: "="},
+		Value:    expr,
+	}
+	if err := ip.ProcessSetStatement(stmt); err != nil {
+		return value.Null, unwrapException(err)
+	}
+
+	ident, err := parseExpression(name)
+	if err != nil {
+		return value.Null, err
+	}
+	return d.evaluate(ident, false)
+}
+
+// Format value for the variables pane, string is quoted to be edited as VCL expression.
+// VCL string could not escape double quote so long string syntax is used for it
+func formatValue(v value.Value) string {
+	switch t := v.(type) {
+	case *value.String:
+		if t.IsNotSet {
+			return "NULL"
+		}
+		if strings.Contains(t.Value, `"`) {
+			delimiter := longStringDelimiter(t.Value)
+			return "{" + delimiter + `"` + t.Value + `"` + delimiter + "}"
+		}
+		return `"` + t.Value + `"`
+	default:
+		if v.Type() == value.NullType {
+			return "NULL"
+		}
+		return v.String()
+	}
+}
+
+// Find the delimiter of long string which does not appear in the value as the closing sequence,
+// e.g. {"..."} could not contain "} so {V"..."V} is used instead
+func longStringDelimiter(v string) string {
+	var delimiter string
+	for n := 0; strings.Contains(v, `"`+delimiter+"}"); n++ {
+		delimiter = fmt.Sprintf("V%d", n)
+	}
+	return delimiter
+}
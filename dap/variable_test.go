@@ -0,0 +1,164 @@
+package dap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	godap "github.com/google/go-dap"
+	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/interpreter"
+	icontext "github.com/ysugimoto/falco/interpreter/context"
+	fhttp "github.com/ysugimoto/falco/interpreter/http"
+	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/lexer"
+	"github.com/ysugimoto/falco/parser"
+	"github.com/ysugimoto/falco/resolver"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value  value.Value
+		expect string
+	}{
+		{value: &value.String{Value: "foo"}, expect: `"foo"`},
+		{value: &value.String{Value: `say "hi"`}, expect: `{"say "hi""}`},
+		{value: &value.String{Value: `{"foo":"bar"}`}, expect: `{V0"{"foo":"bar"}"V0}`},
+		{value: &value.String{Value: `"} "V0}`}, expect: `{V1""} "V0}"V1}`},
+		{value: &value.String{IsNotSet: true}, expect: "NULL"},
+		{value: &value.Integer{Value: 200}, expect: "200"},
+		{value: value.Null, expect: "NULL"},
+	}
+
+	for _, tt := range tests {
+		actual := formatValue(tt.value)
+		if actual != tt.expect {
+			t.Errorf("Unexpected formatted value, want=%s, got=%s", tt.expect, actual)
+		}
+		// Formatted string must be parsed as the same value to be edited
+		if s, ok := tt.value.(*value.String); ok && !s.IsNotSet {
+			expr, err := parser.New(lexer.NewFromString(actual)).ParseExpression(parser.LOWEST)
+			if err != nil {
+				t.Errorf("Failed to parse formatted value %s: %s", actual, err)
+				continue
+			}
+			if v := expr.(*ast.String).Value; v != s.Value {
+				t.Errorf("Unexpected parsed value, want=%s, got=%s", s.Value, v)
+			}
+		}
+	}
+}
+
+func newVariableTestDebugger(t *testing.T) (*Debugger, *interpreter.Interpreter) {
+	ip := interpreter.New(
+		icontext.WithResolver(resolver.NewStaticResolver("main", "sub vcl_recv {}")),
+	)
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+	req.Header.Set("X-Foo", "foo")
+	if err := ip.ProcessInit(fhttp.WrapRequest(req)); err != nil {
+		t.Fatalf("Failed to initialize interpreter: %s", err)
+	}
+	err := ip.ProcessDeclareStatement(&ast.DeclareStatement{
+		Meta:      &ast.Meta{},
+		Name:      &ast.Ident{Meta: &ast.Meta{}, Value: "var.Foo"},
+		ValueType: &ast.Ident{Meta: &ast.Meta{}, Value: "STRING"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to declare local variable: %s", err)
+	}
+
+	d := newDebugger(nil)
+	d.attach(ip)
+	return d, ip
+}
+
+func TestSetVariable(t *testing.T) {
+	d, ip := newVariableTestDebugger(t)
+	ip.SetScope(icontext.RecvScope)
+
+	tests := []struct {
+		reference int
+		name      string
+		input     string
+		expect    string
+	}{
+		{reference: requestHeadersReference, name: "req.http.X-Foo", input: `"bar"`, expect: "bar"},
+		{reference: localVariablesReference, name: "var.Foo", input: `"baz" "qux"`, expect: "bazqux"},
+	}
+	for _, tt := range tests {
+		v, err := d.setVariable(tt.reference, tt.name, tt.input)
+		if err != nil {
+			t.Errorf("Unexpected error on setting %s: %s", tt.name, err)
+			continue
+		}
+		if v.String() != tt.expect {
+			t.Errorf("Unexpected value of %s, want=%s, got=%s", tt.name, tt.expect, v.String())
+		}
+	}
+
+	ip.SetScope(icontext.FetchScope)
+	ip.Context().BackendResponse = fhttp.WrapResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+	})
+	if v, err := d.setVariable(backendResponseReference, "beresp.status", "404"); err != nil {
+		t.Errorf("Unexpected error on setting beresp.status: %s", err)
+	} else if v.String() != "404" {
+		t.Errorf("Unexpected value of beresp.status, want=404, got=%s", v.String())
+	}
+	if _, err := d.setVariable(backendResponseReference, "beresp.http.X-Bar", `"bar"`); err != nil {
+		t.Errorf("Unexpected error on setting beresp.http.X-Bar: %s", err)
+	}
+
+	errorTests := []struct {
+		reference int
+		name      string
+		input     string
+	}{
+		// Read-only variable
+		{reference: backendResponseReference, name: "beresp.backend.ip", input: `"foo"`},
+		// Variable which does not belong to the scope
+		{reference: localVariablesReference, name: "req.http.X-Foo", input: `"foo"`},
+		// Unknown scope
+		{reference: 100, name: "req.http.X-Foo", input: `"foo"`},
+		// Undeclared local variable
+		{reference: localVariablesReference, name: "var.Undefined", input: `"foo"`},
+		// Invalid expression
+		{reference: localVariablesReference, name: "var.Foo", input: `)`},
+	}
+	for _, tt := range errorTests {
+		if _, err := d.setVariable(tt.reference, tt.name, tt.input); err == nil {
+			t.Errorf("Expected error on setting %s = %s", tt.name, tt.input)
+		}
+	}
+}
+
+func TestListVariables(t *testing.T) {
+	d, ip := newVariableTestDebugger(t)
+	ip.SetScope(icontext.RecvScope)
+
+	if _, err := d.setVariable(localVariablesReference, "var.Foo", `"foo"`); err != nil {
+		t.Fatalf("Unexpected error on setting var.Foo: %s", err)
+	}
+	expect := []godap.Variable{
+		{Name: "var.Foo", Value: `"foo"`, Type: "STRING"},
+	}
+	if diff := cmp.Diff(expect, d.listVariables(localVariablesReference)); diff != "" {
+		t.Errorf("Unexpected local variables, diff=%s", diff)
+	}
+
+	expect = []godap.Variable{
+		{Name: "req.http.Host", Value: `"localhost"`, Type: "STRING"},
+		{Name: "req.http.X-Foo", Value: `"foo"`, Type: "STRING"},
+	}
+	if diff := cmp.Diff(expect, d.listVariables(requestHeadersReference)); diff != "" {
+		t.Errorf("Unexpected request headers, diff=%s", diff)
+	}
+
+	// Backend response is not shown before fetching
+	if vars := d.listVariables(backendResponseReference); len(vars) != 0 {
+		t.Errorf("Expected no backend response variables, got %v", vars)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/ysugimoto/falco/debugger/helpview"
 	"github.com/ysugimoto/falco/debugger/messageview"
 	"github.com/ysugimoto/falco/debugger/shellview"
+	"github.com/ysugimoto/falco/debugger/watchview"
 	"github.com/ysugimoto/falco/interpreter"
 )
 
@@ -23,6 +24,7 @@ type Console struct {
 	shell       *shellview.ShellView
 	help        *helpview.HelpView
 	interpreter *interpreter.Interpreter
+	debugger    *Debugger
 	isDebugging atomic.Bool
 
 	stateChan chan interpreter.DebugState
@@ -33,39 +35,48 @@ func New(i *interpreter.Interpreter) *Console {
 	message := messageview.New()
 	shell := shellview.New()
 	help := helpview.New()
+	watch := watchview.New()
 
 	grid := tview.NewGrid().
 		SetRows(0, 10, 6, 1).
+		SetColumns(-2, -1).
 		SetBorders(false).
 		SetGap(0, 0).
 		SetOffset(0, 0)
 
 	grid.AddItem(code, 0, 0, 1, 1, 0, 0, false)
-	grid.AddItem(message, 1, 0, 1, 1, 0, 0, false)
-	grid.AddItem(shell, 2, 0, 1, 1, 0, 0, false)
-	grid.AddItem(help, 3, 0, 1, 1, 0, 0, false)
+	grid.AddItem(watch, 0, 1, 1, 1, 0, 0, false)
+	grid.AddItem(message, 1, 0, 1, 2, 0, 0, false)
+	grid.AddItem(shell, 2, 0, 1, 2, 0, 0, false)
+	grid.AddItem(help, 3, 0, 1, 2, 0, 0, false)
 	grid.SetBackgroundColor(colors.Background)
 
 	app := tview.NewApplication().SetRoot(grid, true)
+	stateChan := make(chan interpreter.DebugState)
+	d := &Debugger{
+		code:        code,
+		shell:       shell,
+		message:     message,
+		app:         app,
+		help:        help,
+		watch:       watch,
+		interpreter: i,
+		input:       stateChan,
+		timeline:    interpreter.NewTimeline(),
+	}
 	c := &Console{
 		code:        code,
 		shell:       shell,
 		message:     message,
 		help:        help,
 		app:         app,
-		stateChan:   make(chan interpreter.DebugState),
+		stateChan:   stateChan,
 		isDebugging: atomic.Bool{},
 		interpreter: i,
+		debugger:    d,
 	}
 	// Attach debugger
-	i.Debugger = &Debugger{
-		code:    code,
-		shell:   shell,
-		message: message,
-		app:     app,
-		help:    help,
-		input:   c.stateChan,
-	}
+	i.Debugger = d
 
 	return c
 }
@@ -81,6 +92,12 @@ func (c *Console) keyEventHandler(evt *tcell.EventKey) *tcell.EventKey {
 	}
 
 	switch evt.Key() {
+	case tcell.KeyF5:
+		c.debugger.StepBack()
+		c.help.Highlight(helpview.F5)
+	case tcell.KeyF6:
+		c.debugger.StepForward()
+		c.help.Highlight(helpview.F6)
 	case tcell.KeyF7:
 		c.stateChan <- interpreter.DebugPass
 	case tcell.KeyF8:
@@ -161,6 +178,7 @@ func (c *Console) Run(sc *config.SimulatorConfig) error {
 }
 
 func (c *Console) activate() {
+	c.debugger.ResetTimeline()
 	c.isDebugging.Store(true)
 	c.shell.IsActivated = true
 	c.message.Append(messageview.Debugger, "Request received, start debugger session.")
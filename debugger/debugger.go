@@ -1,16 +1,25 @@
 package debugger
 
 import (
+	"fmt"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rivo/tview"
 	"github.com/ysugimoto/falco/ast"
 	"github.com/ysugimoto/falco/debugger/codeview"
 	"github.com/ysugimoto/falco/debugger/helpview"
 	"github.com/ysugimoto/falco/debugger/messageview"
 	"github.com/ysugimoto/falco/debugger/shellview"
+	"github.com/ysugimoto/falco/debugger/watchview"
 	"github.com/ysugimoto/falco/interpreter"
+	"github.com/ysugimoto/falco/interpreter/exception"
+	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/lexer"
+	"github.com/ysugimoto/falco/parser"
 	"github.com/ysugimoto/falco/token"
 )
 
@@ -23,11 +32,26 @@ type Debugger struct {
 	message *messageview.MessageView
 	shell   *shellview.ShellView
 	help    *helpview.HelpView
+	watch   *watchview.WatchView
 
-	input <-chan interpreter.DebugState
-	mode  interpreter.DebugState
+	interpreter *interpreter.Interpreter
+	input       <-chan interpreter.DebugState
+	mode        interpreter.DebugState
+
+	// Recorded statements of the request to travel back in time
+	timeline *interpreter.Timeline
+
+	mu sync.Mutex
+	// Headers before the previous statement and the current statement are processed,
+	// to find headers which are changed by the previous statement, keyed by the variable prefix
+	prevHeaders map[string]http.Header
+	headers     map[string]http.Header
+	changes     []watchview.HeaderChange
 }
 
+// Header variables which are compared between statements
+var watchHeaderPrefixes = []string{"req.http.", "resp.http."}
+
 func (d *Debugger) Run(node ast.Node) interpreter.DebugState {
 	switch d.mode {
 	case interpreter.DebugStepIn, interpreter.DebugStepOver:
@@ -44,6 +68,116 @@ func (d *Debugger) Run(node ast.Node) interpreter.DebugState {
 	}
 }
 
+// Implements interpreter.SnapshotDebugger to record the timeline
+func (d *Debugger) Snapshot(s *interpreter.Snapshot) {
+	d.timeline.Append(s)
+
+	// Snapshot is taken before the statement is processed so headers are changed by the previous statement
+	d.mu.Lock()
+	d.prevHeaders = d.headers
+	d.headers = d.currentHeaders()
+	d.mu.Unlock()
+}
+
+// Travel back to the previous statement in the timeline.
+// The interpreter keeps pausing at the present statement while traveling
+func (d *Debugger) StepBack() {
+	d.travel(-1)
+}
+
+// Travel forward to the next statement in the timeline, up to the present
+func (d *Debugger) StepForward() {
+	d.travel(1)
+}
+
+func (d *Debugger) travel(delta int) {
+	s := d.timeline.Travel(delta)
+	if s == nil {
+		return
+	}
+	d.code.SetFile(s.Token.File, s.Token.Line)
+
+	d.mu.Lock()
+	if d.timeline.IsPresent() {
+		d.watch.SetChanges(d.changes)
+		d.message.Append(messageview.Debugger, "Back to the present statement")
+	} else {
+		cursor := d.timeline.Cursor()
+		d.watch.SetChanges(d.recordedChanges(cursor - 1))
+		d.message.Append(
+			messageview.Debugger,
+			"Traveled to #%d/%d in %s (%s:%d)",
+			cursor+1, d.timeline.Len(), s.Scope, s.Token.File, s.Token.Line,
+		)
+	}
+	d.mu.Unlock()
+
+	d.refreshWatches()
+}
+
+// Header changes by the recorded statement at the index, used while traveling back in time
+func (d *Debugger) recordedChanges(index int) []watchview.HeaderChange {
+	s := d.timeline.At(index)
+	if s == nil {
+		return nil
+	}
+	var changes []watchview.HeaderChange
+	for _, w := range s.Writes {
+		for _, prefix := range watchHeaderPrefixes {
+			if !strings.HasPrefix(strings.ToLower(w.Name), prefix) {
+				continue
+			}
+			// Writes which do not change the value like setting the same value are ignored
+			if isNullValue(w.Before) == isNullValue(w.After) && w.Before.String() == w.After.String() {
+				continue
+			}
+			c := watchview.HeaderChange{
+				Type:   watchview.Modified,
+				Name:   w.Name,
+				Before: w.Before.String(),
+				After:  w.After.String(),
+			}
+			switch {
+			case isNullValue(w.Before):
+				c.Type = watchview.Added
+			case isNullValue(w.After):
+				c.Type = watchview.Removed
+			}
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// Reset the timeline for the new request
+func (d *Debugger) ResetTimeline() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.timeline.Reset()
+	d.prevHeaders = nil
+	d.headers = nil
+	d.changes = nil
+	d.watch.SetChanges(nil)
+}
+
+// Get the value of the variable at the traveling position, returns false if not traveling
+func (d *Debugger) ValueAt(name string, current value.Value) (value.Value, bool) {
+	return d.timeline.Value(name, current)
+}
+
+// Format value history of the variable
+func (d *Debugger) History(name string) []string {
+	var lines []string
+	for _, h := range d.timeline.History(name) {
+		lines = append(lines, fmt.Sprintf(
+			"#%d %s (%s:%d) %s: %s -> %s",
+			h.Index+1, h.Scope, h.Token.File, h.Token.Line, h.Name, formatValue(h.Before), formatValue(h.After),
+		))
+	}
+	return lines
+}
+
 func (d *Debugger) Message(msg string) {
 	d.message.Append(messageview.Runtime, "%s", msg)
 }
@@ -52,13 +186,98 @@ func (d *Debugger) Log(stmt *ast.LogStatement, value string) {
 	d.message.Append(messageview.Runtime, "%s", value)
 }
 
+// Pin the expression to the watch pane and evaluate it immediately
+func (d *Debugger) Watch(expr string) bool {
+	if !d.watch.Add(expr) {
+		return false
+	}
+	result, err := d.evaluate(expr)
+	d.watch.Update(expr, result, err)
+	return true
+}
+
+func (d *Debugger) Unwatch(expr string) bool {
+	return d.watch.Remove(expr)
+}
+
+// Re-evaluate pinned expressions in the current state
+func (d *Debugger) refreshWatches() {
+	for _, expr := range d.watch.Expressions() {
+		result, err := d.evaluate(expr)
+		d.watch.Update(expr, result, err)
+	}
+}
+
+// Compare headers with the ones before the previous statement to highlight changed headers.
+// Nothing is compared on the first statement in the request
+func (d *Debugger) diffHeaders() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	headers := d.currentHeaders()
+	var changes []watchview.HeaderChange
+	if d.prevHeaders != nil {
+		for _, prefix := range watchHeaderPrefixes {
+			changes = append(changes, watchview.DiffHeaders(prefix, d.prevHeaders[prefix], headers[prefix])...)
+		}
+	}
+	d.changes = changes
+	d.watch.SetChanges(changes)
+}
+
+// Copy headers of the current state, keyed by the variable prefix
+func (d *Debugger) currentHeaders() map[string]http.Header {
+	headers := map[string]http.Header{}
+	ctx := d.interpreter.Context()
+	if ctx == nil {
+		return headers
+	}
+	if ctx.Request != nil {
+		headers["req.http."] = ctx.Request.Header.Clone()
+	}
+	if ctx.Response != nil {
+		headers["resp.http."] = ctx.Response.Header.Clone()
+	}
+	return headers
+}
+
+// Evaluate the input as VCL expression and format the result
+func (d *Debugger) evaluate(input string) (string, error) {
+	psr := parser.New(lexer.NewFromString("(" + input + ")"))
+	exp, err := psr.ParseExpression(parser.LOWEST)
+	if err != nil {
+		return "", err
+	}
+	val, err := d.interpreter.ProcessExpression(exp)
+	if err != nil {
+		if re, ok := errors.Cause(err).(*exception.Exception); ok {
+			return "", errors.New(re.Message) // DO NOT diplay line and position info
+		}
+		return "", err
+	}
+	// While traveling back in time, variable is displayed with the value at that time
+	if g, ok := exp.(*ast.GroupedExpression); ok {
+		if ident, ok := g.Right.(*ast.Ident); ok {
+			if past, traveling := d.ValueAt(ident.Value, val); traveling {
+				return formatValue(past) + " (at the traveling position)", nil
+			}
+		}
+	}
+	return formatValue(val), nil
+}
+
 func (d *Debugger) breakPoint(t token.Token) interpreter.DebugState {
 	d.code.SetFile(t.File, t.Line)
+	d.diffHeaders()
+	d.refreshWatches()
 	d.app.Draw()
 
 	// Wait for keyboard input
 	d.mode = <-d.input
 
+	// Resuming the process brings the debugger back to the present
+	d.timeline.Present()
+
 	time.AfterFunc(time.Duration(highlightDelay)*time.Millisecond, func() {
 		d.help.Highlight(helpview.Default)
 		d.app.Draw()
@@ -80,6 +299,13 @@ func (d *Debugger) breakPoint(t token.Token) interpreter.DebugState {
 	return interpreter.DebugPass
 }
 
+func isNullValue(v value.Value) bool {
+	if s, ok := v.(*value.String); ok {
+		return s.IsNotSet
+	}
+	return v.Type() == value.NullType
+}
+
 func hasDebuggerMark(cs ast.Comments) bool {
 	return strings.Contains(cs.String(), debuggerMark)
 }
@@ -13,6 +13,8 @@ type HighlightCommand string
 
 const (
 	Default HighlightCommand = "default"
+	F5      HighlightCommand = "F5"
+	F6      HighlightCommand = "F6"
 	F7      HighlightCommand = "F7"
 	F8      HighlightCommand = "F8"
 	F9      HighlightCommand = "F9"
@@ -20,6 +22,8 @@ const (
 )
 
 var commands = map[HighlightCommand]string{
+	F5:  "Step Back",
+	F6:  "Step Forward",
 	F7:  "Resume Execution",
 	F8:  "Step In",
 	F9:  "Step Over",
@@ -47,8 +51,8 @@ func (h *HelpView) drawCommands() {
 	defer w.Close()
 	w.Clear()
 
-	cmds := make([]string, 4)
-	for i, cmd := range []HighlightCommand{F7, F8, F9, F10} {
+	cmds := make([]string, 6)
+	for i, cmd := range []HighlightCommand{F5, F6, F7, F8, F9, F10} {
 		if h.active == cmd {
 			cmds[i] = " [black:silver:][" + string(cmd) + "] " + commands[cmd] + "[-:-:]"
 		} else {
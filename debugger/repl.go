@@ -2,12 +2,9 @@ package debugger
 
 import (
 	"fmt"
+	"strings"
 
-	"github.com/pkg/errors"
-	"github.com/ysugimoto/falco/interpreter/exception"
 	"github.com/ysugimoto/falco/interpreter/value"
-	"github.com/ysugimoto/falco/lexer"
-	"github.com/ysugimoto/falco/parser"
 )
 
 func (c *Console) repl(input string) {
@@ -19,8 +16,22 @@ func (c *Console) repl(input string) {
 		c.message.Clear()
 		return
 	}
+	// Display value history of the variable
+	if name, found := strings.CutPrefix(input, "history "); found {
+		c.history(strings.TrimSpace(name))
+		return
+	}
+	// Pin or unpin the expression in the watch pane
+	if expr, found := strings.CutPrefix(input, "watch "); found {
+		c.watch(strings.TrimSpace(expr))
+		return
+	}
+	if expr, found := strings.CutPrefix(input, "unwatch "); found {
+		c.unwatch(strings.TrimSpace(expr))
+		return
+	}
 	// Otherwise, run repl and display output
-	output, err := c.evaluate(input)
+	output, err := c.debugger.evaluate(input)
 	if err != nil {
 		c.shell.CommandError(err.Error())
 		return
@@ -28,26 +39,41 @@ func (c *Console) repl(input string) {
 	c.shell.CommandResult(output)
 }
 
-func (c *Console) evaluate(input string) (string, error) {
-	psr := parser.New(lexer.NewFromString("(" + input + ")"))
-	exp, err := psr.ParseExpression(parser.LOWEST)
-	if err != nil {
-		return "", err
+func (c *Console) history(name string) {
+	lines := c.debugger.History(name)
+	if len(lines) == 0 {
+		c.shell.CommandResult(fmt.Sprintf("No history for %s", name))
+		return
 	}
-	val, err := c.interpreter.ProcessExpression(exp)
-	if err != nil {
-		if re, ok := err.(*exception.Exception); ok {
-			return "", errors.New(re.Message) // DO NOT diplay line and position info
-		}
-		return "", err
+	for _, line := range lines {
+		c.shell.CommandResult(line)
 	}
+}
+
+func (c *Console) watch(expr string) {
+	if !c.debugger.Watch(expr) {
+		c.shell.CommandError(fmt.Sprintf("%s is already watched", expr))
+		return
+	}
+	c.shell.CommandResult(fmt.Sprintf("Watching %s", expr))
+}
+
+func (c *Console) unwatch(expr string) {
+	if !c.debugger.Unwatch(expr) {
+		c.shell.CommandError(fmt.Sprintf("%s is not watched", expr))
+		return
+	}
+	c.shell.CommandResult(fmt.Sprintf("Unwatched %s", expr))
+}
+
+func formatValue(val value.Value) string {
 	switch val.Type() {
 	case value.NullType:
-		return "NULL", nil
+		return "NULL"
 	case value.BooleanType:
 		b := value.Unwrap[*value.Boolean](val)
-		return fmt.Sprintf("(%s)%t", val.Type(), b.Value), nil
+		return fmt.Sprintf("(%s)%t", val.Type(), b.Value)
 	default:
-		return fmt.Sprintf("(%s)%s", val.Type(), val.String()), nil
+		return fmt.Sprintf("(%s)%s", val.Type(), val.String())
 	}
 }
@@ -0,0 +1,208 @@
+package watchview
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/ysugimoto/falco/debugger/colors"
+)
+
+// Pinned watch expression and the result of the latest evaluation
+type Watch struct {
+	Expression string
+	Result     string
+	Error      string
+	Changed    bool
+}
+
+type ChangeType = string
+
+const (
+	Added    ChangeType = "+"
+	Modified ChangeType = "~"
+	Removed  ChangeType = "-"
+)
+
+// Header change between the previous statement and the current one
+type HeaderChange struct {
+	Type   ChangeType
+	Name   string
+	Before string
+	After  string
+}
+
+type WatchView struct {
+	*tview.TextView
+	watches []*Watch
+	changes []HeaderChange
+	mu      sync.Mutex
+}
+
+func New() *WatchView {
+	tv := tview.NewTextView().SetDynamicColors(true)
+	tv.SetTitle(" Watch ")
+	tv.SetBackgroundColor(colors.Background)
+	tv.SetBorder(true)
+
+	return &WatchView{
+		TextView: tv,
+	}
+}
+
+// Pin the expression, returns false if the expression is already pinned
+func (w *WatchView) Add(expr string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, v := range w.watches {
+		if v.Expression == expr {
+			return false
+		}
+	}
+	w.watches = append(w.watches, &Watch{Expression: expr})
+	return true
+}
+
+// Unpin the expression. The expression could be specified by the number displayed in the pane
+func (w *WatchView) Remove(expr string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, v := range w.watches {
+		if v.Expression == expr || fmt.Sprint(i+1) == expr {
+			w.watches = append(w.watches[:i], w.watches[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Pinned expressions in order
+func (w *WatchView) Expressions() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	exprs := make([]string, len(w.watches))
+	for i, v := range w.watches {
+		exprs[i] = v.Expression
+	}
+	return exprs
+}
+
+// Update the result of the expression, the watch is marked as changed when the result differs from the previous one
+func (w *WatchView) Update(expr, result string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, v := range w.watches {
+		if v.Expression != expr {
+			continue
+		}
+		var message string
+		if err != nil {
+			message = err.Error()
+		}
+		v.Changed = (v.Result != "" || v.Error != "") && (v.Result != result || v.Error != message)
+		v.Result = result
+		v.Error = message
+		return
+	}
+}
+
+func (w *WatchView) SetChanges(changes []HeaderChange) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.changes = changes
+}
+
+func (w *WatchView) drawWatches() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	bw := w.BatchWriter()
+	defer bw.Close()
+	bw.Clear()
+
+	fmt.Fprintln(bw, colors.Bold("Expressions"))
+	if len(w.watches) == 0 {
+		fmt.Fprintln(bw, colors.Gray(" Type \"watch <expression>\" to pin"))
+	}
+	for i, v := range w.watches {
+		// Escape values to avoid being parsed as color tags
+		expr := tview.Escape(v.Expression)
+		switch {
+		case v.Error != "":
+			fmt.Fprintf(bw, " #%d %s: %s\n", i+1, expr, colors.Red(tview.Escape(v.Error)))
+		case v.Changed:
+			fmt.Fprintf(bw, " #%d %s: %s\n", i+1, expr, colors.Bold(colors.Yellow(tview.Escape(v.Result))))
+		default:
+			fmt.Fprintf(bw, " #%d %s: %s\n", i+1, expr, tview.Escape(v.Result))
+		}
+	}
+
+	fmt.Fprintln(bw, "")
+	fmt.Fprintln(bw, colors.Bold("Changed Headers"))
+	if len(w.changes) == 0 {
+		fmt.Fprintln(bw, colors.Gray(" No changes"))
+	}
+	for _, c := range w.changes {
+		switch c.Type {
+		case Added:
+			fmt.Fprintln(bw, colors.Green(tview.Escape(fmt.Sprintf(" %s %s: %q", c.Type, c.Name, c.After))))
+		case Removed:
+			fmt.Fprintln(bw, colors.Red(tview.Escape(fmt.Sprintf(" %s %s: %q", c.Type, c.Name, c.Before))))
+		default:
+			fmt.Fprintln(bw, colors.Yellow(tview.Escape(fmt.Sprintf(" %s %s: %q -> %q", c.Type, c.Name, c.Before, c.After))))
+		}
+	}
+}
+
+func (w *WatchView) Draw(screen tcell.Screen) {
+	w.drawWatches()
+	w.TextView.Draw(screen)
+}
+
+// Compare headers and list changes with the variable prefix like "req.http.", sorted by the name.
+// Multiple header values are compared as joined value like the header variable does
+func DiffHeaders(prefix string, before, after http.Header) []HeaderChange {
+	var changes []HeaderChange
+	for key, a := range after {
+		b := before[key]
+		switch {
+		case len(a) == 0:
+			continue
+		case len(b) == 0:
+			changes = append(changes, HeaderChange{
+				Type:  Added,
+				Name:  prefix + key,
+				After: strings.Join(a, ", "),
+			})
+		case !slices.Equal(b, a):
+			changes = append(changes, HeaderChange{
+				Type:   Modified,
+				Name:   prefix + key,
+				Before: strings.Join(b, ", "),
+				After:  strings.Join(a, ", "),
+			})
+		}
+	}
+	for key, b := range before {
+		if len(b) > 0 && len(after[key]) == 0 {
+			changes = append(changes, HeaderChange{
+				Type:   Removed,
+				Name:   prefix + key,
+				Before: strings.Join(b, ", "),
+			})
+		}
+	}
+	slices.SortFunc(changes, func(a, b HeaderChange) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return changes
+}
@@ -0,0 +1,70 @@
+package watchview
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffHeaders(t *testing.T) {
+	before := http.Header{
+		"X-Keep":    {"foo"},
+		"X-Modify":  {"foo"},
+		"X-Remove":  {"foo"},
+		"X-Append":  {"foo"},
+		"X-Removed": {},
+	}
+	after := http.Header{
+		"X-Keep":   {"foo"},
+		"X-Modify": {"bar"},
+		"X-Add":    {"bar"},
+		"X-Append": {"foo", "bar"},
+		"X-Empty":  {},
+	}
+
+	actual := DiffHeaders("req.http.", before, after)
+	expect := []HeaderChange{
+		{Type: Added, Name: "req.http.X-Add", After: "bar"},
+		{Type: Modified, Name: "req.http.X-Append", Before: "foo", After: "foo, bar"},
+		{Type: Modified, Name: "req.http.X-Modify", Before: "foo", After: "bar"},
+		{Type: Removed, Name: "req.http.X-Remove", Before: "foo"},
+	}
+	if diff := cmp.Diff(expect, actual); diff != "" {
+		t.Errorf("DiffHeaders result mismatch, diff=%s", diff)
+	}
+
+	if actual := DiffHeaders("resp.http.", nil, http.Header{}); len(actual) != 0 {
+		t.Errorf("Expected no changes, got %v", actual)
+	}
+}
+
+func TestWatchUpdate(t *testing.T) {
+	w := New()
+	if !w.Add("req.http.Foo") {
+		t.Fatalf("Expected expression to be added")
+	}
+	if w.Add("req.http.Foo") {
+		t.Errorf("Expected duplicated expression not to be added")
+	}
+
+	w.Update("req.http.Foo", `(STRING)foo`, nil)
+	if w.watches[0].Changed {
+		t.Errorf("Expected first evaluation not to be marked as changed")
+	}
+	w.Update("req.http.Foo", `(STRING)bar`, nil)
+	if !w.watches[0].Changed {
+		t.Errorf("Expected changed result to be marked as changed")
+	}
+	w.Update("req.http.Foo", `(STRING)bar`, nil)
+	if w.watches[0].Changed {
+		t.Errorf("Expected same result not to be marked as changed")
+	}
+
+	if !w.Remove("1") {
+		t.Errorf("Expected expression to be removed by the number")
+	}
+	if len(w.Expressions()) != 0 {
+		t.Errorf("Expected no expressions, got %v", w.Expressions())
+	}
+}
@@ -217,6 +217,9 @@ func Assign(left, right value.Value) error {
 		case value.BackendType: // BACKEND = BACKEND
 			rv := value.Unwrap[*value.Backend](right)
 			lv.Value = rv.Value
+			// Director is also treated as backend
+			lv.Director = rv.Director
+			lv.Healthy = rv.Healthy
 		default:
 			return errors.WithStack(fmt.Errorf("invalid assignment for BACKEND type, got %s", right.Type()))
 		}
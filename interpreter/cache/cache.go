@@ -2,17 +2,15 @@
 package cache
 
 import (
+	ghttp "net/http"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ysugimoto/falco/interpreter/http"
 )
 
-const (
-	LocalDatacenterString = "cache-localsimulator-FALCO"
-)
-
 type CacheItem struct {
 	Response  *http.Response
 	Expires   time.Time
@@ -20,40 +18,176 @@ type CacheItem struct {
 	Hits      int
 	LastUsed  time.Duration
 
+	// URL ("host + path?query") and Surrogate-Key values of the cached object,
+	// used for purging
+	URL           string
+	SurrogateKeys []string
+	// Stale is true when the object is soft purged, the object is treated as expired
+	Stale bool
+	// Grace periods which the object could be served as stale after expired
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+	// Header names of Vary response header and the variant key which is made from
+	// normalized request header values of them. Objects are stored per variant
+	Vary    []string
+	Variant string
+	// ESI is processed on delivering the object when esi statement is executed in vcl_fetch
+	ESI bool
+
 	// private
 	requestedTime time.Time
+	// Background revalidation is running for the stored item
+	revalidating bool
+	// Item in the cache storage. Get() returns snapshot of the stored item
+	// because the item is shared between concurrent requests
+	stored *CacheItem
+	cache  *Cache
 }
 
 func (i *CacheItem) Update(d time.Duration) {
 	i.Expires = i.EntryTime.Add(d)
+	if i.stored != nil {
+		i.cache.mu.Lock()
+		defer i.cache.mu.Unlock()
+		i.stored.Expires = i.Expires
+	}
+}
+
+// StaleAge returns the elapsed time since the object has been expired
+func (i *CacheItem) StaleAge() time.Duration {
+	return time.Since(i.Expires)
+}
+
+// Soft purged object is never fresh even if the TTL is updated, e.g by the request which hit it before purging,
+// and it could be served as stale until it is revalidated
+func (i *CacheItem) state(now time.Time) string {
+	switch {
+	case !i.Stale && !now.After(i.Expires):
+		return "fresh"
+	case !now.After(i.staleUntil()):
+		return "stale"
+	default:
+		return "expired"
+	}
+}
+
+// Object could not be served even as stale after this time
+func (i *CacheItem) staleUntil() time.Time {
+	return i.Expires.Add(max(i.StaleWhileRevalidate, i.StaleIfError))
+}
+
+// Identifier of the stored object
+type objectKey struct {
+	hash    string
+	variant string
 }
 
 type Cache struct {
-	storage sync.Map
+	mu sync.Mutex
+	// Objects are stored per request hash and variant
+	storage map[string]map[string]*CacheItem
+
+	// Indexes for purging, map value is the set of stored object
+	urls map[string]map[objectKey]struct{}
+	keys map[string]map[objectKey]struct{}
+
+	// Waiting list of request collapsing, the busy object is released when the object is fetched
+	busy map[string]*BusyObject
 }
 
 func New() *Cache {
-	return &Cache{}
+	return &Cache{
+		storage: make(map[string]map[string]*CacheItem),
+		urls:    make(map[string]map[objectKey]struct{}),
+		keys:    make(map[string]map[objectKey]struct{}),
+		busy:    make(map[string]*BusyObject),
+	}
 }
 
-func (c *Cache) Set(hash string, item *CacheItem) {
-	item.requestedTime = item.EntryTime
-	c.storage.Store(hash, item)
+// Busy object which is being fetched by another request on request collapsing
+type BusyObject struct {
+	done chan struct{}
+	pass bool
 }
 
-func (c *Cache) Get(hash string) *CacheItem {
-	// Load and cast to *CacheItem
-	v, ok := c.storage.Load(hash)
-	if !ok {
-		return nil
+// Done returns the channel which is closed when the busy object is released
+func (b *BusyObject) Done() <-chan struct{} {
+	return b.done
+}
+
+// Pass reports the fetched object is not cached like hit-for-pass,
+// then waiting requests should fetch on their own instead of waiting again.
+// The value is available after Done channel is closed
+func (b *BusyObject) Pass() bool {
+	return b.pass
+}
+
+// Acquire the busy object of the hash in order to collapse concurrent requests.
+// If no other request is fetching the object, returns release function which must be called
+// when the object has been fetched with whether the object is not cached.
+// Otherwise, returns the busy object to wait for the fetch.
+// see: https://developer.fastly.com/learning/concepts/request-collapsing/
+func (c *Cache) Acquire(hash string) (release func(pass bool), wait *BusyObject) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if busy, ok := c.busy[hash]; ok {
+		return nil, busy
 	}
-	item, ok := v.(*CacheItem)
-	if !ok {
+	busy := &BusyObject{done: make(chan struct{})}
+	c.busy[hash] = busy
+
+	var once sync.Once
+	return func(pass bool) {
+		once.Do(func() {
+			c.mu.Lock()
+			delete(c.busy, hash)
+			c.mu.Unlock()
+			busy.pass = pass
+			close(busy.done)
+		})
+	}, nil
+}
+
+// Set stores the object as the variant of the hash and returns true if stored.
+// The object which has "*" in Vary is never stored because it could not match any request
+func (c *Cache) Set(hash string, item *CacheItem) bool {
+	if slices.Contains(item.Vary, "*") {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := objectKey{hash: hash, variant: item.Variant}
+	// Drop indexes of the item which will be replaced
+	c.remove(key)
+
+	item.requestedTime = item.EntryTime
+	if _, ok := c.storage[hash]; !ok {
+		c.storage[hash] = make(map[string]*CacheItem)
+	}
+	c.storage[hash][item.Variant] = item
+	if item.URL != "" {
+		addIndex(c.urls, item.URL, key)
+	}
+	for _, sk := range item.SurrogateKeys {
+		addIndex(c.keys, sk, key)
+	}
+	return true
+}
+
+// Get returns the fresh object which matches to the request headers,
+// expired object is treated as cache miss
+func (c *Cache) Get(hash string, h ghttp.Header) *CacheItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := c.find(hash, h)
+	if item == nil {
 		return nil
 	}
-	// Check expiration
-	if time.Now().After(item.Expires) {
-		c.storage.Delete(hash)
+	if item.state(time.Now()) != "fresh" {
 		return nil
 	}
 
@@ -61,7 +195,214 @@ func (c *Cache) Get(hash string) *CacheItem {
 	item.Hits++
 	item.LastUsed = time.Since(item.requestedTime)
 	item.requestedTime = time.Now()
-	return item
+
+	return c.snapshot(item)
+}
+
+// GetStale returns the expired object which is still in the grace periods
+func (c *Cache) GetStale(hash string, h ghttp.Header) *CacheItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := c.find(hash, h)
+	if item == nil {
+		return nil
+	}
+	if item.state(time.Now()) != "stale" {
+		return nil
+	}
+	return c.snapshot(item)
+}
+
+// Find the variant which matches to the request headers.
+// Expired objects are removed while finding. Must be called with holding the lock
+func (c *Cache) find(hash string, h ghttp.Header) *CacheItem {
+	var found *CacheItem
+	now := time.Now()
+	for variant, item := range c.storage[hash] {
+		if item.state(now) == "expired" {
+			c.remove(objectKey{hash: hash, variant: variant})
+			continue
+		}
+		if VariantKey(item.Vary, h) != variant {
+			continue
+		}
+		// Variants which have different Vary could match, prefer the newest one
+		if found == nil || item.EntryTime.After(found.EntryTime) {
+			found = item
+		}
+	}
+	return found
+}
+
+// Trace returns how the object is looked up for the request headers.
+// Cache state is not changed by tracing
+func (c *Cache) Trace(hash string, h ghttp.Header) *LookupTrace {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	trace := &LookupTrace{
+		Hash:     hash,
+		Variants: []*VariantTrace{},
+	}
+	now := time.Now()
+	for variant, item := range c.storage[hash] {
+		request := VariantKey(item.Vary, h)
+		trace.Variants = append(trace.Variants, &VariantTrace{
+			Vary:    item.Vary,
+			Variant: variant,
+			Request: request,
+			Matched: request == variant,
+			State:   item.state(now),
+		})
+	}
+	slices.SortFunc(trace.Variants, func(a, b *VariantTrace) int {
+		return strings.Compare(a.Variant, b.Variant)
+	})
+	return trace
+}
+
+// StartRevalidation marks the object as revalidating.
+// Returns false if the object does not exist or revalidation has already started
+func (c *Cache) StartRevalidation(hash, variant string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.storage[hash][variant]
+	if !ok || item.revalidating {
+		return false
+	}
+	item.revalidating = true
+	return true
+}
+
+// FinishRevalidation unmarks revalidating state of the object.
+// Note that the object may have already been replaced by revalidated one
+func (c *Cache) FinishRevalidation(hash, variant string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item, ok := c.storage[hash][variant]; ok {
+		item.revalidating = false
+	}
+}
+
+// Return snapshot of the item, the response is also cloned
+// because reading response body is not goroutine safe.
+// Must be called with holding the lock
+func (c *Cache) snapshot(item *CacheItem) *CacheItem {
+	return &CacheItem{
+		Response:             item.Response.Clone(),
+		Expires:              item.Expires,
+		EntryTime:            item.EntryTime,
+		Hits:                 item.Hits,
+		LastUsed:             item.LastUsed,
+		URL:                  item.URL,
+		SurrogateKeys:        item.SurrogateKeys,
+		Stale:                item.Stale,
+		StaleWhileRevalidate: item.StaleWhileRevalidate,
+		StaleIfError:         item.StaleIfError,
+		Vary:                 item.Vary,
+		Variant:              item.Variant,
+		ESI:                  item.ESI,
+		stored:               item,
+		cache:                c,
+	}
+}
+
+// PurgeURL purges cached objects which are stored for the URL.
+// When soft is true, objects are marked as stale instead of being removed.
+// Returns the number of purged objects.
+func (c *Cache) PurgeURL(url string, soft bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.purge(c.urls[url], soft)
+}
+
+// PurgeKey purges cached objects which have the surrogate key.
+// When soft is true, objects are marked as stale instead of being removed.
+// Returns the number of purged objects.
+func (c *Cache) PurgeKey(key string, soft bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.purge(c.keys[key], soft)
+}
+
+// PurgeAll removes all cached objects and returns the number of purged objects.
+// Fastly does not support soft purge for purge all.
+func (c *Cache) PurgeAll() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	for _, variants := range c.storage {
+		n += len(variants)
+	}
+	c.storage = make(map[string]map[string]*CacheItem)
+	c.urls = make(map[string]map[objectKey]struct{})
+	c.keys = make(map[string]map[objectKey]struct{})
+	return n
+}
+
+// Must be called with holding the lock
+func (c *Cache) purge(objects map[objectKey]struct{}, soft bool) int {
+	// Collect keys first because removing item modifies the index
+	targets := make([]objectKey, 0, len(objects))
+	for key := range objects {
+		targets = append(targets, key)
+	}
+
+	now := time.Now()
+	for _, key := range targets {
+		if !soft {
+			c.remove(key)
+			continue
+		}
+		// Soft purge marks the object as stale, it is expired immediately
+		// but still stays in the storage
+		item := c.storage[key.hash][key.variant]
+		item.Stale = true
+		if item.Expires.After(now) {
+			item.Expires = now
+		}
+	}
+	return len(targets)
+}
+
+// Remove item and its indexes. Must be called with holding the lock
+func (c *Cache) remove(key objectKey) {
+	item, ok := c.storage[key.hash][key.variant]
+	if !ok {
+		return
+	}
+	delete(c.storage[key.hash], key.variant)
+	if len(c.storage[key.hash]) == 0 {
+		delete(c.storage, key.hash)
+	}
+	removeIndex(c.urls, item.URL, key)
+	for _, sk := range item.SurrogateKeys {
+		removeIndex(c.keys, sk, key)
+	}
+}
+
+func addIndex(index map[string]map[objectKey]struct{}, name string, key objectKey) {
+	if _, ok := index[name]; !ok {
+		index[name] = make(map[objectKey]struct{})
+	}
+	index[name][key] = struct{}{}
+}
+
+func removeIndex(index map[string]map[objectKey]struct{}, name string, key objectKey) {
+	objects, ok := index[name]
+	if !ok {
+		return
+	}
+	delete(objects, key)
+	if len(objects) == 0 {
+		delete(index, name)
+	}
 }
 
 // Fastly follows its own cache freshness rules
@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"io"
+	ghttp "net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ysugimoto/falco/interpreter/http"
+)
+
+func newItem(url string, keys ...string) *CacheItem {
+	now := time.Now()
+	return &CacheItem{
+		Response:      http.WrapResponse(&ghttp.Response{Header: ghttp.Header{}, Body: io.NopCloser(strings.NewReader("OK"))}),
+		Expires:       now.Add(time.Minute),
+		EntryTime:     now,
+		URL:           url,
+		SurrogateKeys: keys,
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	t.Run("purge by URL", func(t *testing.T) {
+		c := New()
+		c.Set("a", newItem("example.com/foo"))
+		c.Set("b", newItem("example.com/foo"))
+		c.Set("c", newItem("example.com/bar"))
+
+		if n := c.PurgeURL("example.com/foo", false); n != 2 {
+			t.Errorf("Expected 2 objects are purged, got %d", n)
+		}
+		if c.Get("a", nil) != nil || c.Get("b", nil) != nil {
+			t.Errorf("Purged objects still exist")
+		}
+		if c.Get("c", nil) == nil {
+			t.Errorf("Unrelated object is purged")
+		}
+		if n := c.PurgeURL("example.com/foo", false); n != 0 {
+			t.Errorf("Expected no objects are purged, got %d", n)
+		}
+	})
+
+	t.Run("purge by surrogate key", func(t *testing.T) {
+		c := New()
+		c.Set("a", newItem("example.com/a", "all", "a"))
+		c.Set("b", newItem("example.com/b", "all", "b"))
+
+		if n := c.PurgeKey("a", false); n != 1 {
+			t.Errorf("Expected 1 object is purged, got %d", n)
+		}
+		if n := c.PurgeKey("all", false); n != 1 {
+			t.Errorf("Expected 1 object is purged, got %d", n)
+		}
+		if len(c.storage) != 0 || len(c.urls) != 0 || len(c.keys) != 0 {
+			t.Errorf("Storage and indexes must be empty")
+		}
+	})
+
+	t.Run("replaced item is not indexed by old keys", func(t *testing.T) {
+		c := New()
+		c.Set("a", newItem("example.com/a", "old"))
+		c.Set("a", newItem("example.com/a", "new"))
+
+		if n := c.PurgeKey("old", false); n != 0 {
+			t.Errorf("Expected no objects are purged, got %d", n)
+		}
+		if n := c.PurgeKey("new", false); n != 1 {
+			t.Errorf("Expected 1 object is purged, got %d", n)
+		}
+	})
+
+	t.Run("soft purge", func(t *testing.T) {
+		c := New()
+		item := newItem("example.com/a", "a")
+		item.StaleWhileRevalidate = time.Minute
+		c.Set("a", item)
+
+		if n := c.PurgeKey("a", true); n != 1 {
+			t.Errorf("Expected 1 object is purged, got %d", n)
+		}
+		if item := c.storage["a"][""]; item == nil || !item.Stale {
+			t.Errorf("Soft purged object must be kept as stale")
+		}
+		if c.Get("a", nil) != nil {
+			t.Errorf("Stale object must not be hit")
+		}
+		stale := c.GetStale("a", nil)
+		if stale == nil {
+			t.Fatalf("Soft purged object must be served as stale")
+		}
+		// Updating TTL of the object must not make it fresh again
+		stale.Update(time.Hour)
+		if c.Get("a", nil) != nil || c.GetStale("a", nil) == nil {
+			t.Errorf("Soft purged object must be kept as stale until revalidated")
+		}
+		c.Set("a", newItem("example.com/a", "a"))
+		if c.Get("a", nil) == nil {
+			t.Errorf("Revalidated object must be hit")
+		}
+	})
+
+	t.Run("purge all", func(t *testing.T) {
+		c := New()
+		c.Set("a", newItem("example.com/a", "a"))
+		c.Set("b", newItem("example.com/b", "b"))
+
+		if n := c.PurgeAll(); n != 2 {
+			t.Errorf("Expected 2 objects are purged, got %d", n)
+		}
+		if c.Get("a", nil) != nil || c.Get("b", nil) != nil {
+			t.Errorf("Purged objects still exist")
+		}
+	})
+}
+
+func TestCacheVariants(t *testing.T) {
+	c := New()
+	set := func(encoding string) {
+		item := newItem("example.com/")
+		item.Response.Header.Set("Content-Encoding", encoding)
+		item.Vary = ParseVary(ghttp.Header{"Vary": {"accept-encoding"}})
+		item.Variant = VariantKey(item.Vary, ghttp.Header{"Accept-Encoding": {encoding}})
+		c.Set("hash", item)
+	}
+	get := func(encoding string) string {
+		item := c.Get("hash", ghttp.Header{"Accept-Encoding": {encoding}})
+		if item == nil {
+			return ""
+		}
+		return item.Response.Header.Get("Content-Encoding")
+	}
+
+	set("gzip, br")
+	set("identity")
+	if v := get("gzip,br"); v != "gzip, br" {
+		t.Errorf("Expected normalized gzip variant, got %q", v)
+	}
+	if v := get("identity"); v != "identity" {
+		t.Errorf("Expected identity variant, got %q", v)
+	}
+	if v := get("deflate"); v != "" {
+		t.Errorf("Expected cache miss for unknown variant, got %q", v)
+	}
+
+	trace := c.Trace("hash", ghttp.Header{"Accept-Encoding": {"identity"}})
+	if len(trace.Variants) != 2 {
+		t.Fatalf("Expected 2 variants are traced, got %d", len(trace.Variants))
+	}
+	if v := trace.Variants[1]; v.Variant != "accept-encoding=identity" || !v.Matched || v.State != "fresh" {
+		t.Errorf("Unexpected variant trace: %+v", v)
+	}
+
+	if n := c.PurgeURL("example.com/", false); n != 2 {
+		t.Errorf("Expected all variants are purged, got %d", n)
+	}
+
+	item := newItem("example.com/")
+	item.Vary = []string{"*"}
+	c.Set("hash", item)
+	if len(c.storage) != 0 {
+		t.Errorf("Object which varies by * must not be stored")
+	}
+}
+
+func TestCacheAcquire(t *testing.T) {
+	c := New()
+	release, wait := c.Acquire("hash")
+	if release == nil || wait != nil {
+		t.Fatalf("First request must acquire the busy object")
+	}
+	_, wait = c.Acquire("hash")
+	if wait == nil {
+		t.Fatalf("Second request must wait for the busy object")
+	}
+	if other, _ := c.Acquire("other"); other == nil {
+		t.Errorf("Different hash must not be collapsed")
+	}
+
+	release(true)
+	release(false) // could be called multiple times
+	select {
+	case <-wait.Done():
+		if !wait.Pass() {
+			t.Errorf("Waiting request must be notified the object is not cached")
+		}
+	default:
+		t.Errorf("Waiting request must be woken up on release")
+	}
+	if release, _ := c.Acquire("hash"); release == nil {
+		t.Errorf("Busy object must be released")
+	}
+}
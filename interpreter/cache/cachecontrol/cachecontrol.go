@@ -0,0 +1,192 @@
+// Package cachecontrol parses Cache-Control and Surrogate-Control header directives
+// and determines object freshness following Fastly's rules.
+// This package is shared between the interpreter and the linter.
+package cachecontrol
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fastly's default TTL when the response does not have any freshness information
+const DefaultTTL = 2 * time.Minute
+
+type Directive struct {
+	Name  string // lower-cased directive name
+	Value string // unquoted value, empty if the directive does not have value
+}
+
+// Directives keeps the order and duplication of the header value
+// in order to find conflicts on linting
+type Directives []Directive
+
+// Parse comma separated directives like `public, max-age=60, no-cache="Set-Cookie"`.
+// Comma in the quoted value is not treated as separator
+func Parse(header string) Directives {
+	var directives Directives
+	for _, token := range splitDirectives(header) {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(token, "=")
+		directives = append(directives, Directive{
+			Name:  strings.ToLower(strings.TrimSpace(name)),
+			Value: strings.Trim(strings.TrimSpace(value), `"`),
+		})
+	}
+	return directives
+}
+
+func splitDirectives(header string) []string {
+	var tokens []string
+	var quoted bool
+	var start int
+	for i, r := range header {
+		switch r {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				tokens = append(tokens, header[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(tokens, header[start:])
+}
+
+// Get returns the value of first found directive
+func (d Directives) Get(name string) (string, bool) {
+	for i := range d {
+		if d[i].Name == name {
+			return d[i].Value, true
+		}
+	}
+	return "", false
+}
+
+func (d Directives) Has(name string) bool {
+	_, ok := d.Get(name)
+	return ok
+}
+
+// Seconds returns delta-seconds value of the directive as duration.
+// Returns false if the directive does not exist or the value is invalid
+func (d Directives) Seconds(name string) (time.Duration, bool) {
+	v, ok := d.Get(name)
+	if !ok {
+		return 0, false
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec < 0 {
+		return 0, false
+	}
+	return time.Duration(sec) * time.Second, true
+}
+
+// Directives which prevent the response from being cached
+var uncacheableDirectives = []string{"private", "no-store", "no-cache"}
+
+// Directives which require delta-seconds value
+var secondsDirectives = []string{"max-age", "s-maxage", "stale-while-revalidate", "stale-if-error"}
+
+// Conflicts returns messages of conflicting or invalid directives
+func (d Directives) Conflicts() []string {
+	var conflicts []string
+
+	seen := make(map[string]struct{})
+	for i := range d {
+		if _, ok := seen[d[i].Name]; ok {
+			conflicts = append(conflicts, fmt.Sprintf(`"%s" directive is duplicated`, d[i].Name))
+		}
+		seen[d[i].Name] = struct{}{}
+	}
+	for _, name := range secondsDirectives {
+		if v, ok := d.Get(name); ok {
+			if _, valid := d.Seconds(name); !valid {
+				conflicts = append(conflicts, fmt.Sprintf(`"%s" directive has invalid seconds value "%s"`, name, v))
+			}
+		}
+	}
+	if d.Has("public") && d.Has("private") {
+		conflicts = append(conflicts, `"public" conflicts with "private"`)
+	}
+	for _, name := range uncacheableDirectives {
+		if !d.Has(name) {
+			continue
+		}
+		for _, age := range []string{"max-age", "s-maxage"} {
+			if v, ok := d.Seconds(age); ok && v > 0 {
+				conflicts = append(conflicts, fmt.Sprintf(`"%s" conflicts with "%s", the response will not be cached`, name, age))
+			}
+		}
+	}
+	return conflicts
+}
+
+// Freshness of the object which is determined from response headers
+type Freshness struct {
+	TTL                  time.Duration
+	Cacheable            bool
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+}
+
+// Determine freshness of the response following Fastly's precedence:
+// Surrogate-Control max-age, Cache-Control s-maxage, Cache-Control max-age, Expires and the default TTL.
+// Cache-Control private, no-store and no-cache make the response uncacheable
+// unless Surrogate-Control specifies freshness for Fastly.
+// see: https://developer.fastly.com/learning/concepts/cache-freshness/
+func Determine(h http.Header, now time.Time) Freshness {
+	sc := Parse(strings.Join(h.Values("Surrogate-Control"), ","))
+	cc := Parse(strings.Join(h.Values("Cache-Control"), ","))
+
+	f := Freshness{Cacheable: true, TTL: DefaultTTL}
+
+	// Stale periods in Surrogate-Control take precedence over Cache-Control
+	for _, d := range []Directives{cc, sc} {
+		if v, ok := d.Seconds("stale-while-revalidate"); ok {
+			f.StaleWhileRevalidate = v
+		}
+		if v, ok := d.Seconds("stale-if-error"); ok {
+			f.StaleIfError = v
+		}
+	}
+
+	if v, ok := sc.Seconds("max-age"); ok {
+		f.TTL = v
+		f.Cacheable = !sc.Has("no-store")
+		return f
+	}
+	if sc.Has("no-store") {
+		f.Cacheable = false
+		return f
+	}
+	for _, name := range uncacheableDirectives {
+		if cc.Has(name) {
+			f.Cacheable = false
+			return f
+		}
+	}
+	if v, ok := cc.Seconds("s-maxage"); ok {
+		f.TTL = v
+		return f
+	}
+	if v, ok := cc.Seconds("max-age"); ok {
+		f.TTL = v
+		return f
+	}
+	if v := h.Get("Expires"); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			f.TTL = max(t.Sub(now), 0)
+		} else {
+			// Invalid date value like "0" means already expired
+			f.TTL = 0
+		}
+	}
+	return f
+}
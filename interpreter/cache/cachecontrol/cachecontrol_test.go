@@ -0,0 +1,117 @@
+package cachecontrol
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	expect := Directives{
+		{Name: "public"},
+		{Name: "max-age", Value: "60"},
+		{Name: "no-cache", Value: "Set-Cookie, X-Foo"},
+	}
+	if diff := cmp.Diff(expect, Parse(` public,MAX-AGE=60 , no-cache="Set-Cookie, X-Foo",`)); diff != "" {
+		t.Errorf("Parse result mismatch, diff=%s", diff)
+	}
+}
+
+func TestDetermine(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		expect  Freshness
+	}{
+		{
+			name:   "default",
+			expect: Freshness{TTL: DefaultTTL, Cacheable: true},
+		},
+		{
+			name:    "max-age not at the beginning",
+			headers: map[string]string{"Cache-Control": "public, max-age=60"},
+			expect:  Freshness{TTL: 60 * time.Second, Cacheable: true},
+		},
+		{
+			name:    "s-maxage takes precedence over max-age",
+			headers: map[string]string{"Cache-Control": "max-age=60, s-maxage=300"},
+			expect:  Freshness{TTL: 300 * time.Second, Cacheable: true},
+		},
+		{
+			name: "Surrogate-Control takes precedence over Cache-Control",
+			headers: map[string]string{
+				"Cache-Control":     "private, s-maxage=300",
+				"Surrogate-Control": "max-age=3600, stale-while-revalidate=10",
+			},
+			expect: Freshness{TTL: time.Hour, Cacheable: true, StaleWhileRevalidate: 10 * time.Second},
+		},
+		{
+			name:    "private is not cacheable",
+			headers: map[string]string{"Cache-Control": "private, max-age=60"},
+			expect:  Freshness{TTL: DefaultTTL},
+		},
+		{
+			name:    "no-store in Surrogate-Control is not cacheable",
+			headers: map[string]string{"Surrogate-Control": "no-store"},
+			expect:  Freshness{TTL: DefaultTTL},
+		},
+		{
+			name:    "Expires",
+			headers: map[string]string{"Expires": "Mon, 01 Jan 2024 00:10:00 GMT"},
+			expect:  Freshness{TTL: 10 * time.Minute, Cacheable: true},
+		},
+		{
+			name:    "invalid Expires means already expired",
+			headers: map[string]string{"Expires": "0"},
+			expect:  Freshness{Cacheable: true},
+		},
+		{
+			name:    "stale directives",
+			headers: map[string]string{"Cache-Control": "max-age=60, stale-while-revalidate=30, stale-if-error=86400"},
+			expect: Freshness{
+				TTL:                  60 * time.Second,
+				Cacheable:            true,
+				StaleWhileRevalidate: 30 * time.Second,
+				StaleIfError:         24 * time.Hour,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			if diff := cmp.Diff(tt.expect, Determine(h, now)); diff != "" {
+				t.Errorf("Freshness mismatch, diff=%s", diff)
+			}
+		})
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	tests := []struct {
+		header string
+		expect []string
+	}{
+		{header: "public, max-age=60, stale-if-error=60"},
+		{header: "no-store, no-cache, private"},
+		{header: "public, private", expect: []string{`"public" conflicts with "private"`}},
+		{header: "max-age=60, max-age=120", expect: []string{`"max-age" directive is duplicated`}},
+		{header: "max-age=1m", expect: []string{`"max-age" directive has invalid seconds value "1m"`}},
+		{
+			header: "no-store, s-maxage=60",
+			expect: []string{`"no-store" conflicts with "s-maxage", the response will not be cached`},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.expect, Parse(tt.header).Conflicts()); diff != "" {
+			t.Errorf("Conflicts of %s mismatch, diff=%s", tt.header, diff)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package cache
+
+// LookupTrace describes how the cache is looked up on the request
+type LookupTrace struct {
+	Hash string `json:"hash"`
+	// Final state of the lookup like HIT, HIT-STALE or MISS
+	Result string `json:"result"`
+	// Variant key of the object which is used for the response
+	Variant string `json:"variant,omitempty"`
+	// Number of times the request waited for the busy object by request collapsing
+	Collapsed int             `json:"collapsed,omitempty"`
+	Variants  []*VariantTrace `json:"variants"`
+}
+
+// VariantTrace describes the stored variant of the hash
+type VariantTrace struct {
+	Vary []string `json:"vary"`
+	// Variant key of the stored object
+	Variant string `json:"variant"`
+	// Variant key which is made from the request headers
+	Request string `json:"request"`
+	Matched bool   `json:"matched"`
+	// fresh, stale or expired
+	State string `json:"state"`
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	ghttp "net/http"
+	"slices"
+	"strings"
+)
+
+// ParseVary returns canonical header names in Vary response header
+func ParseVary(h ghttp.Header) []string {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name != "*" {
+				name = ghttp.CanonicalHeaderKey(name)
+			}
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// VariantKey makes the key of the variant from the request header values which are named in Vary.
+// Values are normalized by trimming spaces around comma separated tokens and collapsing consecutive spaces,
+// so "gzip,deflate" and "gzip, deflate" are the same variant
+func VariantKey(vary []string, h ghttp.Header) string {
+	pairs := make([]string, len(vary))
+	for i, name := range vary {
+		pairs[i] = strings.ToLower(name) + "=" + normalizeHeaderValue(h.Values(name))
+	}
+	return strings.Join(pairs, "&")
+}
+
+func normalizeHeaderValue(values []string) string {
+	var tokens []string
+	for _, v := range values {
+		for _, token := range strings.Split(v, ",") {
+			if token = strings.Join(strings.Fields(token), " "); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return strings.Join(tokens, ",")
+}
@@ -17,6 +17,7 @@ const consoleBackendResponseBody = "falco_console_response"
 func (i *Interpreter) ConsoleProcessInit() error {
 	var err error
 	i.ctx = context.New(i.options...)
+	i.ctx.InjectedVariable = i.injectedVariable
 
 	// On console process, all request/response variables should be set initially
 	i.ctx.Request, err = http.NewRequest(ghttp.MethodGet, "http://localhost:3124", nil)
@@ -58,6 +58,8 @@ type Context struct {
 	SubroutineFunctions map[string]*ast.SubroutineDeclaration
 	OriginalHost        string
 	IsActualResponse    bool
+	// Run health check probes of backends in background
+	IsBackendProbe bool
 
 	OverrideMaxBackends    int
 	OverrideMaxAcls        int
@@ -65,10 +67,20 @@ type Context struct {
 	OverrideBackends       map[string]*config.OverrideBackend
 	InjectEdgeDictionaries map[string]config.EdgeDictionary
 
+	// Origin shielding configuration and the POP which processes the request.
+	// Datacenter is empty if origin shielding is not configured
+	Shielding  *config.ShieldingConfig
+	Datacenter string
+
 	// Mocking subroutines map
 	MockedSubroutines            map[string]*ast.SubroutineDeclaration
 	MockedFunctioncalSubroutines map[string]*ast.SubroutineDeclaration
 
+	// Mocking backends map, key accepts glob pattern of backend name
+	MockedBackends map[string]*config.MockBackend
+	// Backend requests which are sent to mocked backends in this request
+	MockedBackendRequests []*MockedBackendRequest
+
 	Request          *http.Request
 	BackendRequest   *http.Request
 	BackendResponse  *http.Response
@@ -78,6 +90,10 @@ type Context struct {
 	RequestEndTime   time.Time
 	RequestStartTime time.Time
 	CacheHitItem     *cache.CacheItem
+	// Expired object in the cache which could be served as stale
+	StaleItem *cache.CacheItem
+	// Backend which is determined by the director for the backend request
+	DirectorBackend *value.Backend
 
 	// Interpreter states, following variables could be set in each subroutine directives
 	Restarts                            int
@@ -90,7 +106,8 @@ type Context struct {
 	Stale                               *value.Boolean
 	StaleIsError                        *value.Boolean
 	StaleIsRevalidating                 *value.Boolean
-	StaleContents                       *value.String
+	StaleExists                         *value.Boolean
+	IsBackgroundFetch                   *value.Boolean
 	FastlyError                         *value.String
 	ClientIdentity                      *value.String
 	ClientGeoIpOverride                 *value.String
@@ -143,6 +160,7 @@ type Context struct {
 	BackendResponseStatus               *value.Integer
 	BackendResponseTTL                  *value.RTime
 	ObjectGrace                         *value.RTime
+	ObjectStaleWhileRevalidate          *value.RTime
 	ObjectTTL                           *value.RTime
 	ObjectStatus                        *value.Integer
 	ObjectResponse                      *value.String
@@ -186,7 +204,51 @@ type Context struct {
 	// Marker that return request is purge request.
 	IsPurgeRequest bool
 
+	// Marker that the request is passed to the backend.
+	// Fastly does not process Range request on pass unless req.enable_range_on_pass is set.
+	IsPassRequest bool
+
+	// Segmented caching result of the outer request, nil if segmented caching is not used
+	SegmentedCaching *SegmentedCaching
+
 	OverrideVariables map[string]value.Value
+
+	// Variable getter and setter which is injected from the interpreter, e.g testing variables
+	InjectedVariable InjectVariable
+}
+
+// Recorded backend request which would have been sent to the origin
+type MockedBackendRequest struct {
+	Backend string
+	Request *http.Request
+	Body    string
+}
+
+// Fastly's default block size of segmented caching is 1MB
+const DefaultSegmentedCachingBlockSize = 1024 * 1024
+
+// Segmented caching result which is exposed via segmented_caching.* variables
+type SegmentedCaching struct {
+	BlockNumber      int64
+	BlockSize        int64
+	TotalBlocks      int64
+	CompleteLength   int64
+	RoundedRangeLow  int64
+	RoundedRangeHigh int64
+	Completed        bool
+	Failed           bool
+	Autopurged       bool
+	Error            string
+}
+
+// IsShieldPOP returns true when the request is processed on the shield POP
+func (c *Context) IsShieldPOP() bool {
+	return c.Shielding != nil && c.Datacenter == c.Shielding.Shield
+}
+
+type InjectVariable interface {
+	Get(*Context, Scope, string) (value.Value, error)
+	Set(*Context, Scope, string, string, value.Value) error
 }
 
 func New(options ...Option) *Context {
@@ -204,6 +266,7 @@ func New(options ...Option) *Context {
 
 		MockedSubroutines:            make(map[string]*ast.SubroutineDeclaration),
 		MockedFunctioncalSubroutines: make(map[string]*ast.SubroutineDeclaration),
+		MockedBackends:               make(map[string]*config.MockBackend),
 
 		CacheHitItem:                    nil,
 		RequestStartTime:                time.Now(),
@@ -215,7 +278,8 @@ func New(options ...Option) *Context {
 		Stale:                           &value.Boolean{},
 		StaleIsError:                    &value.Boolean{},
 		StaleIsRevalidating:             &value.Boolean{},
-		StaleContents:                   &value.String{},
+		StaleExists:                     &value.Boolean{},
+		IsBackgroundFetch:               &value.Boolean{},
 		FastlyError:                     &value.String{},
 		ClientGeoIpOverride:             &value.String{},
 		ClientSocketCongestionAlgorithm: &value.String{Value: "cubic"},
@@ -228,7 +292,7 @@ func New(options ...Option) *Context {
 		EnableSSI:                       &value.Boolean{},
 		HashAlwaysMiss:                  &value.Boolean{},
 		HashIgnoreBusy:                  &value.Boolean{},
-		SegmentedCacheingBlockSize:      &value.Integer{},
+		SegmentedCacheingBlockSize:      &value.Integer{Value: DefaultSegmentedCachingBlockSize},
 		ESILevel:                        &value.Integer{},
 		RequestHash:                     &value.String{},
 
@@ -275,6 +339,7 @@ func New(options ...Option) *Context {
 		BackendResponseStatus:               &value.Integer{},
 		BackendResponseTTL:                  &value.RTime{},
 		ObjectGrace:                         &value.RTime{},
+		ObjectStaleWhileRevalidate:          &value.RTime{},
 		ObjectTTL:                           &value.RTime{},
 		ObjectStatus:                        &value.Integer{Value: 500},
 		ObjectResponse:                      &value.String{IsNotSet: true},
@@ -1,6 +1,7 @@
 package context
 
 import (
+	"maps"
 	"time"
 
 	"github.com/ysugimoto/falco/config"
@@ -48,6 +49,13 @@ func WithOverrideBackends(ov map[string]*config.OverrideBackend) Option {
 	}
 }
 
+func WithMockBackends(mb map[string]*config.MockBackend) Option {
+	return func(c *Context) {
+		// Copy mocks because they could be added via testing function in each test
+		maps.Copy(c.MockedBackends, mb)
+	}
+}
+
 func WithOverrideHost(host string) Option {
 	return func(c *Context) {
 		c.OriginalHost = host
@@ -60,6 +68,13 @@ func WithInjectEdgeDictionaries(ed map[string]config.EdgeDictionary) Option {
 	}
 }
 
+func WithShielding(s *config.ShieldingConfig) Option {
+	return func(c *Context) {
+		c.Shielding = s
+		c.Datacenter = s.Edge
+	}
+}
+
 func WithActualResponse(is bool) Option {
 	return func(c *Context) {
 		c.IsActualResponse = is
@@ -89,6 +104,12 @@ func WithCoverage(cv *shared.Coverage) Option {
 	}
 }
 
+func WithBackendProbe(is bool) Option {
+	return func(c *Context) {
+		c.IsBackendProbe = is
+	}
+}
+
 func WithTLServer(tls bool) Option {
 	return func(c *Context) {
 		c.TLSServer = tls
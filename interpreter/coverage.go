@@ -35,6 +35,13 @@ func (i *Interpreter) instrumentStatements(stmts []ast.Statement) []ast.Statemen
 	var statements []ast.Statement
 
 	for j := range stmts {
+		// Goto jumps to the destination statement directly so the marker which is put before it is never executed.
+		// Then the marker of goto destination is put after the statement
+		if _, ok := stmts[j].(*ast.GotoDestinationStatement); ok {
+			statements = append(statements, stmts[j])
+			statements = append(statements, i.instrumentStatement(stmts[j])...)
+			continue
+		}
 		statements = append(statements, i.instrumentStatement(stmts[j])...)
 		statements = append(statements, stmts[j])
 	}
@@ -95,6 +102,7 @@ func (i *Interpreter) instrumentStatement(stmt ast.Statement) []ast.Statement {
 		// *ast.BreakStatement
 		// *ast.FallthroughStatement
 		// *ast.GotoStatement
+		// *ast.GotoDestinationStatement
 		// *ast.IncludeStatement
 		statements = append(statements, i.createMarker(shared.CoverageTypeStatement, stmt))
 	}
@@ -339,6 +347,11 @@ func (i *Interpreter) createMarker(t shared.CoverageType, node ast.Node, suffix
 	if len(suffix) > 0 {
 		s = "_" + strings.Join(suffix, "_")
 	}
+	// Nodes in different included files could be placed at the same position,
+	// so id has the file name to be unique
+	if tok.File != "" {
+		s += "@" + tok.File
+	}
 
 	var id string
 	switch t {
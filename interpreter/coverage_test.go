@@ -39,7 +39,9 @@ func assertInstrument(t *testing.T, tests testTables) {
 			}
 			c := shared.NewCoverage()
 			ip := &Interpreter{
-				ctx: context.New(context.WithCoverage(c)),
+				requestState: &requestState{
+					ctx: context.New(context.WithCoverage(c)),
+				},
 			}
 			ip.instrument(vcl)
 
@@ -100,6 +102,7 @@ sub instrument2 {
 					"stmt_3_2": {Type: token.SET, Literal: "set", Line: 3, Position: 2},
 					"stmt_6_2": {Type: token.SET, Literal: "set", Line: 6, Position: 2},
 				},
+				NameMap: map[string]string{"sub_2_1": "instrument1", "sub_5_1": "instrument2"},
 			},
 		},
 	}
@@ -215,6 +218,7 @@ sub instrument {
 					"branch_11_3_1": {Type: token.IF, Literal: "if", Line: 11, Position: 3},
 					"branch_11_3_2": {Type: token.IF, Literal: "if", Line: 11, Position: 3},
 				},
+				NameMap: map[string]string{"sub_2_1": "instrument"},
 			},
 		},
 	}
@@ -329,6 +333,7 @@ sub instrument {
 					"branch_11_2":  {Type: token.CASE, Literal: "case", Line: 11, Position: 2},
 					"branch_14_2":  {Type: token.DEFAULT, Literal: "default", Line: 14, Position: 2},
 				},
+				NameMap: map[string]string{"sub_2_1": "instrument"},
 			},
 		},
 	}
@@ -378,8 +383,107 @@ sub instrument {
 					"branch_4_14_true":  {Type: token.IF, Literal: "if", Line: 4, Position: 14},
 					"branch_4_14_false": {Type: token.IF, Literal: "if", Line: 4, Position: 14},
 				},
+				NameMap: map[string]string{"sub_2_1": "instrument"},
 			},
 		},
 	}
 	assertInstrument(t, tests)
 }
+
+func TestInstrumentGotoStatement(t *testing.T) {
+	tests := testTables{
+		{
+			name: "goto destination marker is put after the destination",
+			input: `
+sub instrument {
+	goto skip;
+	set req.http.Foo = "bar";
+	skip:
+	set req.http.Bar = "baz";
+}
+`,
+			expect: `
+sub instrument {
+	coverage.subroutine("sub_2_1");
+	coverage.statement("stmt_3_2");
+	goto skip;
+	coverage.statement("stmt_4_2");
+	set req.http.Foo = "bar";
+	skip:
+	coverage.statement("stmt_5_2");
+	coverage.statement("stmt_6_2");
+	set req.http.Bar = "baz";
+}
+`,
+			coverage: &shared.CoverageFactory{
+				Subroutines: shared.CoverageFactoryItem{
+					"sub_2_1": 0,
+				},
+				Statements: shared.CoverageFactoryItem{
+					"stmt_3_2": 0,
+					"stmt_4_2": 0,
+					"stmt_5_2": 0,
+					"stmt_6_2": 0,
+				},
+				Branches: shared.CoverageFactoryItem{},
+				NodeMap: map[string]token.Token{
+					"sub_2_1":  {Type: token.SUBROUTINE, Literal: "sub", Line: 2, Position: 1},
+					"stmt_3_2": {Type: token.GOTO, Literal: "goto", Line: 3, Position: 2},
+					"stmt_4_2": {Type: token.SET, Literal: "set", Line: 4, Position: 2},
+					"stmt_5_2": {Type: token.IDENT, Literal: "skip:", Line: 5, Position: 2},
+					"stmt_6_2": {Type: token.SET, Literal: "set", Line: 6, Position: 2},
+				},
+				NameMap: map[string]string{"sub_2_1": "instrument"},
+			},
+		},
+	}
+	assertInstrument(t, tests)
+}
+
+func TestInstrumentIncludedFiles(t *testing.T) {
+	// Statements in both files are placed at the same position
+	parse := func(file, input string) []ast.Statement {
+		vcl, err := parser.New(lexer.NewFromString(input, lexer.WithFile(file))).ParseVCL()
+		if err != nil {
+			t.Fatalf("Unexpected input VCL parse error: %s", err)
+		}
+		return vcl.Statements
+	}
+	vcl := &ast.VCL{}
+	vcl.Statements = append(vcl.Statements, parse("a.vcl", `
+sub included_a {
+	set req.http.A = "a";
+}
+`)...)
+	vcl.Statements = append(vcl.Statements, parse("b.vcl", `
+sub included_b {
+	set req.http.B = "b";
+}
+`)...)
+
+	c := shared.NewCoverage()
+	ip := &Interpreter{
+		requestState: &requestState{
+			ctx: context.New(context.WithCoverage(c)),
+		},
+	}
+	ip.instrument(vcl)
+
+	factory := c.Factory()
+	expect := map[string]string{
+		"sub_2_1@a.vcl":  "a.vcl",
+		"stmt_3_2@a.vcl": "a.vcl",
+		"sub_2_1@b.vcl":  "b.vcl",
+		"stmt_3_2@b.vcl": "b.vcl",
+	}
+	files := make(map[string]string)
+	for id, tok := range factory.NodeMap {
+		files[id] = tok.File
+	}
+	if diff := cmp.Diff(expect, files); diff != "" {
+		t.Errorf("coverage node map mismatch, diff=%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"sub_2_1@a.vcl": "included_a", "sub_2_1@b.vcl": "included_b"}, factory.NameMap); diff != "" {
+		t.Errorf("coverage name map mismatch, diff=%s", diff)
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/pkg/errors"
 	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/exception"
+	"github.com/ysugimoto/falco/interpreter/variable"
 )
 
 type DebugState int
@@ -22,6 +26,53 @@ type Debugger interface {
 	Log(*ast.LogStatement, string)
 }
 
+// Debugger which breaks when the exception is raised, the runtime error, error statement or restart statement.
+// The exception is notified before it is propagated so the debugger could inspect the states
+type ExceptionDebugger interface {
+	Exception(ast.Node, *exception.Exception)
+}
+
+// Notify the exception to the debugger if supported.
+// The same exception is notified only once while it is propagated through subroutines
+func (i *Interpreter) notifyException(node ast.Node, err error) {
+	d, ok := i.Debugger.(ExceptionDebugger)
+	if !ok {
+		return
+	}
+	e, ok := errors.Cause(err).(*exception.Exception)
+	if !ok || e == i.raised {
+		return
+	}
+	i.raised = e
+	d.Exception(node, e)
+}
+
+// Current request context, used for the debugger to inspect states while paused
+func (i *Interpreter) Context() *context.Context {
+	return i.ctx
+}
+
+// Local variables of the current subroutine, used for the debugger to inspect states while paused
+func (i *Interpreter) LocalVariables() variable.LocalVariables {
+	return i.localVars
+}
+
+func restartException(stmt *ast.RestartStatement) *exception.Exception {
+	return &exception.Exception{
+		Type:    exception.RestartType,
+		Token:   &stmt.GetMeta().Token,
+		Message: "restart",
+	}
+}
+
+func (i *Interpreter) errorException(stmt *ast.ErrorStatement) *exception.Exception {
+	return &exception.Exception{
+		Type:    exception.ErrorType,
+		Token:   &stmt.GetMeta().Token,
+		Message: fmt.Sprintf("error %d %s", i.ctx.ObjectStatus.Value, i.ctx.ObjectResponse.Value),
+	}
+}
+
 // Default debugger, simply output message to stdout
 type DefaultDebugger struct{}
 
@@ -34,3 +85,12 @@ func (d DefaultDebugger) Message(msg string) {
 func (d DefaultDebugger) Log(stmt *ast.LogStatement, value string) {
 	fmt.Fprintln(os.Stderr, value)
 }
+
+// Debugger which ignores everything, used for background process
+type nopDebugger struct{}
+
+func (d nopDebugger) Run(node ast.Node) DebugState {
+	return DebugPass
+}
+func (d nopDebugger) Message(msg string)                       {}
+func (d nopDebugger) Log(stmt *ast.LogStatement, value string) {}
@@ -143,6 +143,32 @@ func (i *Interpreter) setDirectorConfigProperty(conf *value.DirectorConfig, prop
 			conf.VNodesPerNode = int(v.Value)
 		}
 		return nil
+	case "shield":
+		if conf.Type != value.DIRECTORTYPE_SHIELD {
+			return exception.Runtime(
+				&prop.GetMeta().Token,
+				".shield field must be present only in shield director type",
+			)
+		}
+		if v, ok := prop.Value.(*ast.String); !ok {
+			return exception.Runtime(&prop.GetMeta().Token, ".shield value must be string")
+		} else {
+			conf.Shield = v.Value
+		}
+		return nil
+	case "is_ssl":
+		if conf.Type != value.DIRECTORTYPE_SHIELD {
+			return exception.Runtime(
+				&prop.GetMeta().Token,
+				".is_ssl field must be present only in shield director type",
+			)
+		}
+		if v, ok := prop.Value.(*ast.Boolean); !ok {
+			return exception.Runtime(&prop.GetMeta().Token, ".is_ssl value must be boolean")
+		} else {
+			conf.IsSSL = v.Value
+		}
+		return nil
 	}
 	return exception.Runtime(&prop.GetMeta().Token, "Unexpected director property '%s' found", prop.Key.Value)
 }
@@ -236,6 +262,8 @@ func (i *Interpreter) createDirectorRequest(ctx *context.Context, dc *value.Dire
 		backend, err = i.directorBackendClient(dc)
 	case value.DIRECTORTYPE_CHASH:
 		backend, err = i.directorBackendConsistentHash(dc)
+	case value.DIRECTORTYPE_SHIELD:
+		return i.createShieldRequest(ctx, dc)
 	default:
 		return nil, exception.System("Unexpected director type '%s' provided", dc.Type)
 	}
@@ -243,6 +271,8 @@ func (i *Interpreter) createDirectorRequest(ctx *context.Context, dc *value.Dire
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	i.Debugger.Message(fmt.Sprintf("Director %s determines backend %s", dc.Name, backend))
+	ctx.DirectorBackend = backend
 	return i.createBackendRequest(ctx, backend)
 }
 
@@ -2,126 +2,142 @@ package interpreter
 
 import (
 	"bytes"
-	"context"
+	"fmt"
 	"io"
+	ghttp "net/http"
 	"net/url"
-	"path/filepath"
-	"regexp"
-	"strings"
-	"time"
 
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/interpreter/esi"
 	"github.com/ysugimoto/falco/interpreter/exception"
 	"github.com/ysugimoto/falco/interpreter/http"
+	"github.com/ysugimoto/falco/interpreter/limitations"
+	"github.com/ysugimoto/falco/interpreter/value"
 )
 
-var (
-	esiIncludeRegex = regexp.MustCompile(`<esi:include\s*src=['"]([^'"]+)['"]\s*/?\s*>`)
-	esiRemoveStart  = []byte("<esi:remove>")
-	esiRemoveEnd    = []byte("</esi:remove>")
-)
-
+// Process ESI on the client response. The response body is tokenized as a stream
+// and each include is processed through the VCL lifecycle as a new request with incremented req.esi_level,
+// so that the included fragment could be also processed by ESI
 func (i *Interpreter) executeESI() error {
 	resp := i.ctx.Response
 	if resp == nil {
 		return exception.System("Client Response is nil")
 	}
 
-	var respBody bytes.Buffer
-	if _, err := respBody.ReadFrom(resp.Body); err != nil {
-		return err
-	}
-
-	body := respBody.Bytes()
-	req := i.ctx.Request
-	ctx := i.ctx.Request.Context()
-	var parsed []byte
+	tokenizer := esi.NewTokenizer(resp.Body, esi.WithAllowInsideCData(i.ctx.EsiAllowInsideCData.Value))
+	var body bytes.Buffer
+	var includes int
 	for {
-		match := esiIncludeRegex.FindSubmatchIndex(body)
-		if len(match) == 0 {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
 			break
+		} else if err != nil {
+			return exception.Runtime(nil, "Failed to process ESI: %s", err)
 		}
-		previous := body[0:match[0]]
-		src := body[match[2]:match[3]]
-		body = body[match[1]:]
-		parsed = append(parsed, previous...)
-
-		// resolve inclusion
-		cloned := req.Clone(ctx)
-		partial, err := executeEsiInclude(ctx, cloned, src)
-		if err != nil {
-			// If ESI inclusion failed, find <esi:remove> tag and use its nodeText
-			index := bytes.Index(body, esiRemoveStart)
-			if index == -1 {
-				break
-			}
-			body = body[index+len(esiRemoveStart):]
 
-			// Find </esi:remove> tag
-			index = bytes.Index(body, esiRemoveEnd)
-			if index == -1 {
-				return exception.Runtime(nil, "Syntax error: does not seem to close </esi:remove>")
+		switch token.Type {
+		case esi.TEXT:
+			body.Write(token.Text)
+		case esi.INCLUDE:
+			if includes++; includes > limitations.MaxESIIncludes {
+				return exception.Runtime(nil, "Max ESI includes of %d exceeded", limitations.MaxESIIncludes)
 			}
-			parsed = append(parsed, body[0:index]...)
-			body = body[index+len(esiRemoveEnd):]
-		} else {
-			parsed = append(parsed, partial...)
-			// If ESI inclusion succeeded, find <esi:remove>...</esi:remove> tag and remove it
-			index := bytes.Index(body, esiRemoveStart)
-			if index == -1 {
-				continue
+			fragment, err := i.esiInclude(token)
+			if err != nil {
+				// Response is truncated at the failed include because it is already streamed
+				resp.Body = io.NopCloser(bytes.NewReader(body.Bytes()))
+				resp.ContentLength = int64(body.Len())
+				i.Debugger.Message(fmt.Sprintf("Response is truncated: %s", err))
+				return nil
 			}
-			body = body[index+len(esiRemoveStart):]
-			index = bytes.Index(body, esiRemoveEnd)
-			if index == -1 {
-				return exception.Runtime(nil, "Syntax error: does not seem to close </esi:remove>")
-			}
-			body = body[index+len(esiRemoveEnd):]
+			body.Write(fragment)
 		}
 	}
 
-	// remaining bytes
-	if len(body) > 0 {
-		parsed = append(parsed, body...)
+	resp.Body = io.NopCloser(bytes.NewReader(body.Bytes()))
+	resp.ContentLength = int64(body.Len())
+	// ESI processed response is streamed so the length is unknown on Fastly,
+	// but the simulator fixes it because the response is buffered
+	if resp.Header.Get("Content-Length") != "" {
+		resp.Header.Set("Content-Length", fmt.Sprint(body.Len()))
 	}
-	resp.Body = io.NopCloser(bytes.NewReader(parsed))
 	return nil
 }
 
-func executeEsiInclude(ctx context.Context, req *http.Request, includeUrl []byte) ([]byte, error) {
-	ctx, timeout := context.WithTimeout(ctx, 10*time.Second)
-	defer timeout()
+// Include the fragment of src, or alt if src is failed.
+// Failed include is removed from the document when onerror="continue" is specified
+func (i *Interpreter) esiInclude(token *esi.Token) ([]byte, error) {
+	fragment, err := i.esiFragment(token.Src)
+	if err != nil && token.Alt != "" {
+		i.Debugger.Message(fmt.Sprintf("ESI include %s failed: %s, try alt %s", token.Src, err, token.Alt))
+		fragment, err = i.esiFragment(token.Alt)
+	}
+	if err != nil {
+		if token.ContinueOnError {
+			i.Debugger.Message(fmt.Sprintf("ESI include %s failed: %s, continue", token.Src, err))
+			return nil, nil
+		}
+		return nil, exception.Runtime(nil, "ESI include %s failed: %s", token.Src, err)
+	}
+	return fragment, nil
+}
 
-	if err := resolveIncludeURL(req, string(includeUrl)); err != nil {
-		return nil, err
+// Process fragment request through the VCL lifecycle and returns its response body.
+// Fragment response which has error status code is treated as failure
+func (i *Interpreter) esiFragment(src string) ([]byte, error) {
+	level := i.ctx.ESILevel.Value + 1
+	if level > limitations.MaxESIDepth {
+		return nil, errors.Errorf("max ESI depth of %d exceeded", limitations.MaxESIDepth)
 	}
+	req, err := esiRequest(i.ctx.Request, src)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Fragment shares the declarations of the parsed VCL with the parent
+	fragment := i.clone()
+	fragment.inheritContext(i.ctx, req)
+	fragment.ctx.ESILevel = &value.Integer{Value: level}
 
-	resp, err := http.SendRequest(req.WithContext(ctx))
+	i.Debugger.Message(fmt.Sprintf("Start ESI include %s (level %d)", req.URL.String(), level))
+	err = fragment.ProcessRecv()
+	// Flows of the fragment are recorded in the parent process
+	i.process.Merge(fragment.process)
 	if err != nil {
-		return nil, err
+		return nil, errors.WithStack(err)
+	}
+
+	resp := fragment.ctx.Response
+	if resp == nil {
+		return nil, errors.New("fragment is not responded")
+	}
+	if resp.StatusCode >= ghttp.StatusBadRequest {
+		return nil, errors.Errorf("fragment responded status code %d", resp.StatusCode)
 	}
-	defer resp.Body.Close()
 	var buf bytes.Buffer
 	if _, err := buf.ReadFrom(resp.Body); err != nil {
-		return nil, err
+		return nil, errors.WithStack(err)
 	}
 	return buf.Bytes(), nil
 }
 
-func resolveIncludeURL(req *http.Request, path string) error {
-	switch {
-	case strings.HasPrefix(path, "http://"), strings.HasPrefix(path, "https://"):
-		parsed, err := url.Parse(path)
-		if err != nil {
-			return err
-		}
-		req.URL = parsed
-		req.Header.Set("Host", parsed.Host)
-		return nil
-	case strings.HasPrefix(path, "/"):
-		req.URL.Path = path
-		return nil
-	default:
-		req.URL.Path = filepath.Join(req.URL.Path, path)
-		return nil
+// Create fragment request from the current client request.
+// Relative src is resolved from the request URL, and Host header is changed for the absolute src
+func esiRequest(parent *http.Request, src string) (*http.Request, error) {
+	ref, err := url.Parse(src)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resolved := parent.URL.ResolveReference(ref)
+
+	req := parent.Clone(parent.Context())
+	req.Method = ghttp.MethodGet
+	req.Body = ghttp.NoBody
+	req.ContentLength = 0
+	req.URL = &url.URL{Path: resolved.Path, RawQuery: resolved.RawQuery}
+	req.RequestURI = req.URL.RequestURI()
+	if ref.IsAbs() {
+		req.Host = ref.Host
 	}
+	return req, nil
 }
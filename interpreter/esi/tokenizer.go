@@ -0,0 +1,285 @@
+// esi package implements streaming tokenizer for the ESI (Edge Side Includes) subset which Fastly supports.
+// see: https://developer.fastly.com/reference/vcl/statements/esi/
+//
+// Supported syntaxes are:
+// - <esi:include src="..." alt="..." onerror="continue" />
+// - <esi:remove>...</esi:remove>
+// - <esi:comment text="..." />
+// - <!--esi ... -->
+package esi
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type TokenType int
+
+const (
+	TEXT TokenType = iota
+	INCLUDE
+)
+
+type Token struct {
+	Type TokenType
+	// Raw bytes for TEXT token
+	Text []byte
+	// Attributes for INCLUDE token
+	Src             string
+	Alt             string
+	ContinueOnError bool
+}
+
+var (
+	includeTag      = []byte("<esi:include")
+	includeCloseTag = []byte("</esi:include>")
+	removeTag       = []byte("<esi:remove>")
+	removeCloseTag  = []byte("</esi:remove>")
+	commentTag      = []byte("<esi:comment")
+	esiCommentStart = []byte("<!--esi")
+	esiCommentEnd   = []byte("-->")
+	cdataStart      = []byte("<![CDATA[")
+	cdataEnd        = []byte("]]>")
+
+	delimiters = [][]byte{includeTag, removeTag, commentTag, esiCommentStart, cdataStart}
+)
+
+type Option func(t *Tokenizer)
+
+// Process ESI tags inside CDATA section, corresponds to esi.allow_inside_cdata variable
+func WithAllowInsideCData(v bool) Option {
+	return func(t *Tokenizer) {
+		t.allowInsideCData = v
+	}
+}
+
+// Tokenizer reads the document from the reader and returns tokens one by one
+// so that whole document does not need to be loaded on memory
+type Tokenizer struct {
+	r                *bufio.Reader
+	allowInsideCData bool
+	inESIComment     bool
+}
+
+func NewTokenizer(r io.Reader, opts ...Option) *Tokenizer {
+	t := &Tokenizer{
+		r: bufio.NewReader(r),
+	}
+	for i := range opts {
+		opts[i](t)
+	}
+	return t
+}
+
+// Next returns the next token, returns io.EOF when the document is read completely
+func (t *Tokenizer) Next() (*Token, error) {
+	for {
+		text, err := t.readText()
+		if err != nil && err != io.EOF {
+			return nil, errors.WithStack(err)
+		}
+		if len(text) > 0 {
+			return &Token{Type: TEXT, Text: text}, nil
+		}
+		if err == io.EOF {
+			if t.inESIComment {
+				return nil, errors.New("ESI comment is not closed")
+			}
+			return nil, io.EOF
+		}
+
+		token, err := t.readTag()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if token != nil {
+			return token, nil
+		}
+	}
+}
+
+// Read bytes until the delimiter of ESI syntax appears
+func (t *Tokenizer) readText() ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return buf.Bytes(), err
+		}
+		if b == '<' || (t.inESIComment && b == '-') {
+			t.r.UnreadByte() // nolint:errcheck
+			if t.isDelimiter() {
+				return buf.Bytes(), nil
+			}
+			b, _ = t.r.ReadByte() // nolint:errcheck
+		}
+		buf.WriteByte(b)
+	}
+}
+
+func (t *Tokenizer) isDelimiter() bool {
+	if t.inESIComment && t.hasPrefix(esiCommentEnd) {
+		return true
+	}
+	for _, d := range delimiters {
+		if t.hasPrefix(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Read ESI tag, returns nil token if the tag is consumed without output
+func (t *Tokenizer) readTag() (*Token, error) {
+	switch {
+	case t.hasPrefix(esiCommentEnd) && t.inESIComment:
+		t.discard(len(esiCommentEnd))
+		t.inESIComment = false
+		return nil, nil
+	case t.hasPrefix(esiCommentStart):
+		if t.inESIComment {
+			return nil, errors.New("ESI comment could not be nested")
+		}
+		t.discard(len(esiCommentStart))
+		t.inESIComment = true
+		return nil, nil
+	case t.hasPrefix(cdataStart):
+		if t.allowInsideCData {
+			t.discard(len(cdataStart))
+			return &Token{Type: TEXT, Text: cdataStart}, nil
+		}
+		// Whole CDATA section is treated as text
+		text, err := t.readUntil(cdataEnd)
+		if err != nil {
+			return nil, errors.New("CDATA section is not closed")
+		}
+		return &Token{Type: TEXT, Text: text}, nil
+	case t.hasPrefix(removeTag):
+		if _, err := t.readUntil(removeCloseTag); err != nil {
+			return nil, errors.New("<esi:remove> tag is not closed")
+		}
+		return nil, nil
+	case t.hasPrefix(commentTag):
+		if _, err := t.readElement(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return nil, nil
+	case t.hasPrefix(includeTag):
+		return t.readInclude()
+	default:
+		return nil, errors.New("unexpected delimiter")
+	}
+}
+
+func (t *Tokenizer) readInclude() (*Token, error) {
+	element, err := t.readElement()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	attrs, err := parseAttributes(element[len(includeTag) : len(element)-1])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// <esi:include> tag may be closed by </esi:include>
+	if !bytes.HasSuffix(element, []byte("/>")) && t.hasPrefix(includeCloseTag) {
+		t.discard(len(includeCloseTag))
+	}
+
+	token := &Token{
+		Type:            INCLUDE,
+		Src:             attrs["src"],
+		Alt:             attrs["alt"],
+		ContinueOnError: attrs["onerror"] == "continue",
+	}
+	if token.Src == "" {
+		return nil, errors.New("<esi:include> tag must have src attribute")
+	}
+	return token, nil
+}
+
+// Read element until the tag is closed. Quoted ">" is not treated as the end of the element
+func (t *Tokenizer) readElement() ([]byte, error) {
+	var buf bytes.Buffer
+	var quote byte
+	for {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return nil, errors.Errorf("tag is not closed: %s", buf.String())
+		}
+		buf.WriteByte(b)
+		switch {
+		case quote != 0:
+			if b == quote {
+				quote = 0
+			}
+		case b == '"' || b == '\'':
+			quote = b
+		case b == '>':
+			return buf.Bytes(), nil
+		}
+	}
+}
+
+// Read bytes including the delimiter
+func (t *Tokenizer) readUntil(delimiter []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	for !bytes.HasSuffix(buf.Bytes(), delimiter) {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(b)
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *Tokenizer) hasPrefix(prefix []byte) bool {
+	peek, _ := t.r.Peek(len(prefix)) // nolint:errcheck
+	return bytes.Equal(peek, prefix)
+}
+
+func (t *Tokenizer) discard(n int) {
+	t.r.Discard(n) // nolint:errcheck
+}
+
+// Parse attributes like `src="/path" alt='/alt' onerror=continue`
+func parseAttributes(s []byte) (map[string]string, error) {
+	attrs := make(map[string]string)
+	rest := strings.TrimSuffix(strings.TrimSpace(string(s)), "/")
+	for {
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			return attrs, nil
+		}
+		name, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			return nil, errors.Errorf("invalid attribute: %s", rest)
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimLeft(value, " \t\r\n")
+		if value == "" {
+			return nil, errors.Errorf("attribute %s has no value", name)
+		}
+
+		switch value[0] {
+		case '"', '\'':
+			end := strings.IndexByte(value[1:], value[0])
+			if end < 0 {
+				return nil, errors.Errorf("attribute %s is not quoted correctly", name)
+			}
+			attrs[name] = value[1 : end+1]
+			rest = value[end+2:]
+		default:
+			end := strings.IndexAny(value, " \t\r\n")
+			if end < 0 {
+				end = len(value)
+			}
+			attrs[name] = value[:end]
+			rest = value[end:]
+		}
+	}
+}
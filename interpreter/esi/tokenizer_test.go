@@ -0,0 +1,117 @@
+package esi
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func tokenize(t *testing.T, input string, opts ...Option) ([]*Token, error) {
+	t.Helper()
+	tokenizer := NewTokenizer(strings.NewReader(input), opts...)
+	var tokens []*Token
+	for {
+		token, err := tokenizer.Next()
+		if err == io.EOF {
+			return tokens, nil
+		} else if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+func text(s string) *Token {
+	return &Token{Type: TEXT, Text: []byte(s)}
+}
+
+func TestTokenizer(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		opts   []Option
+		expect []*Token
+	}{
+		{
+			name:   "plain text",
+			input:  "<p>Hello <b>ESI</b></p>",
+			expect: []*Token{text("<p>Hello <b>ESI</b></p>")},
+		},
+		{
+			name:  "include with attributes",
+			input: `A<esi:include src="/a" alt='/b' onerror="continue"/>B`,
+			expect: []*Token{
+				text("A"),
+				{Type: INCLUDE, Src: "/a", Alt: "/b", ContinueOnError: true},
+				text("B"),
+			},
+		},
+		{
+			name:  "include closed by end tag",
+			input: `<esi:include src="/a?x=>"></esi:include>B`,
+			expect: []*Token{
+				{Type: INCLUDE, Src: "/a?x=>"},
+				text("B"),
+			},
+		},
+		{
+			name:   "remove and comment",
+			input:  `A<esi:remove><a href="/">fallback</a></esi:remove>B<esi:comment text="note"/>C`,
+			expect: []*Token{text("A"), text("B"), text("C")},
+		},
+		{
+			name:  "esi comment",
+			input: `A<!--esi <p>-</p><esi:include src="/a"/>-->B`,
+			expect: []*Token{
+				text("A"),
+				text(" <p>-</p>"),
+				{Type: INCLUDE, Src: "/a"},
+				text("B"),
+			},
+		},
+		{
+			name:   "cdata is not processed",
+			input:  `<![CDATA[<esi:include src="/a"/>]]>`,
+			expect: []*Token{text(`<![CDATA[<esi:include src="/a"/>]]>`)},
+		},
+		{
+			name:  "cdata is processed if allowed",
+			input: `<![CDATA[<esi:include src="/a"/>]]>`,
+			opts:  []Option{WithAllowInsideCData(true)},
+			expect: []*Token{
+				text("<![CDATA["),
+				{Type: INCLUDE, Src: "/a"},
+				text("]]>"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(t, tt.input, tt.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(tt.expect, tokens); diff != "" {
+				t.Errorf("Tokens mismatch, diff=%s", diff)
+			}
+		})
+	}
+}
+
+func TestTokenizerError(t *testing.T) {
+	tests := []string{
+		`<esi:remove>not closed`,
+		`<!--esi not closed`,
+		`<esi:include src="/a"`,
+		`<esi:include alt="/a"/>`,
+	}
+
+	for _, input := range tests {
+		if _, err := tokenize(t, input); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
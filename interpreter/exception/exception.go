@@ -13,6 +13,11 @@ type Type string
 const (
 	RuntimeType Type = "RuntimeException"
 	SystemType  Type = "SystemException"
+
+	// Raised by error and restart statement only for the debugger to break on them,
+	// the process continues as the statement does
+	ErrorType   Type = "ErrorStatement"
+	RestartType Type = "RestartStatement"
 )
 
 type Exception struct {
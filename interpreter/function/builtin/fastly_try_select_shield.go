@@ -47,6 +47,10 @@ func Fastly_try_select_shield(ctx *context.Context, args ...value.Value) (value.
 	if shield.Healthy == nil || !shield.Healthy.Load() {
 		return fallback, nil
 	}
+	// Request is already on the shield POP, should be sent to the origin
+	if ctx.IsShieldPOP() {
+		return fallback, nil
+	}
 
 	return shield, nil
 }
@@ -2,7 +2,6 @@ package function
 
 import (
 	"fmt"
-	"maps"
 
 	"github.com/pkg/errors"
 	"github.com/ysugimoto/falco/interpreter/context"
@@ -16,8 +15,19 @@ type Function struct {
 	IsIdentArgument  func(i int) bool
 }
 
+// Functions is a set of functions which are injected to the interpreter like testing functions.
+// Injected functions are looked up before builtin functions so they could override builtin one
+type Functions map[string]*Function
+
 func Exists(scope context.Scope, name string) (*Function, error) {
-	fn, ok := builtinFunctions[name]
+	return Functions(nil).Exists(scope, name)
+}
+
+func (f Functions) Exists(scope context.Scope, name string) (*Function, error) {
+	fn, ok := f[name]
+	if !ok {
+		fn, ok = builtinFunctions[name]
+	}
 	if !ok {
 		return nil, errors.WithStack(
 			fmt.Errorf("Function %s is not defined", name),
@@ -29,8 +39,3 @@ func Exists(scope context.Scope, name string) (*Function, error) {
 	}
 	return fn, nil
 }
-
-func Inject(fns map[string]*Function) {
-	// Always override existing functions
-	maps.Copy(builtinFunctions, fns)
-}
@@ -21,9 +21,24 @@ func (i *Interpreter) ServeHTTP(w ghttp.ResponseWriter, r *ghttp.Request) {
 		ghttp.Error(w, "loop detected", ghttp.StatusServiceUnavailable)
 		return
 	}
+	// Purge API request is handled without processing VCL
+	if i.handlePurgeAPI(w, r) {
+		return
+	}
+
+	// Debugger inspects states of this interpreter while processing the request,
+	// so requests are processed on this interpreter one by one.
+	// Otherwise, process the request concurrently with the request scoped interpreter
+	if _, ok := i.Debugger.(DefaultDebugger); ok {
+		i.clone().serveHTTP(w, r)
+		return
+	}
 	i.lock.Lock()
 	defer i.lock.Unlock()
+	i.serveHTTP(w, r)
+}
 
+func (i *Interpreter) serveHTTP(w ghttp.ResponseWriter, r *ghttp.Request) {
 	if err := i.ProcessInit(http.WrapRequest(r)); err != nil {
 		ghttp.Error(w, err.Error(), ghttp.StatusInternalServerError)
 		return
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const rangeUnit = "bytes"
+
+// RangeSpec represents raw byte-range-spec in the Range header.
+// First is -1 for the suffix range like "-500", and Last is -1 for the open-ended range like "500-"
+type RangeSpec struct {
+	First int64
+	Last  int64
+}
+
+// Resolve byte range against the complete length of the object.
+// Returns false if the range is not satisfiable
+func (s RangeSpec) Resolve(size int64) (ByteRange, bool) {
+	// Suffix range, Last is the suffix length
+	if s.First < 0 {
+		if s.Last == 0 || size == 0 {
+			return ByteRange{}, false
+		}
+		return ByteRange{Start: max(size-s.Last, 0), End: size - 1}, true
+	}
+	if s.First >= size {
+		return ByteRange{}, false
+	}
+	end := size - 1
+	if s.Last >= 0 && s.Last < end {
+		end = s.Last
+	}
+	return ByteRange{Start: s.First, End: end}, true
+}
+
+// IsOpenEnded returns true when the range does not specify the last byte position
+func (s RangeSpec) IsOpenEnded() bool {
+	return s.First >= 0 && s.Last < 0
+}
+
+// ByteRange represents inclusive byte positions of the object
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+func (r ByteRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+// ContentRange returns Content-Range header value for the complete length
+func (r ByteRange) ContentRange(size int64) string {
+	return fmt.Sprintf("%s %d-%d/%d", rangeUnit, r.Start, r.End, size)
+}
+
+// ParseRange parses Range header value like "bytes=0-499, 1000-".
+// Returns false if the value is invalid, the Range header should be ignored in that case
+func ParseRange(v string) ([]RangeSpec, bool) {
+	unit, set, ok := strings.Cut(v, "=")
+	if !ok || strings.TrimSpace(unit) != rangeUnit {
+		return nil, false
+	}
+
+	var specs []RangeSpec
+	for _, s := range strings.Split(set, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(s, "-")
+		if !ok {
+			return nil, false
+		}
+		spec := RangeSpec{First: -1, Last: -1}
+		if first != "" {
+			n, err := parseBytePosition(first)
+			if err != nil {
+				return nil, false
+			}
+			spec.First = n
+		}
+		if last != "" {
+			n, err := parseBytePosition(last)
+			if err != nil {
+				return nil, false
+			}
+			spec.Last = n
+		}
+		if spec.First < 0 && spec.Last < 0 {
+			return nil, false
+		}
+		if spec.First >= 0 && spec.Last >= 0 && spec.Last < spec.First {
+			return nil, false
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, false
+	}
+	return specs, true
+}
+
+// ResolveRanges returns satisfiable byte ranges for the complete length
+func ResolveRanges(specs []RangeSpec, size int64) []ByteRange {
+	var ranges []ByteRange
+	for _, s := range specs {
+		if r, ok := s.Resolve(size); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// ParseContentRange parses Content-Range header value like "bytes 0-499/1234" or "bytes */1234".
+// Start and End are -1 for the unsatisfied range, returns false if the value is invalid
+// or the complete length is unknown
+func ParseContentRange(v string) (r ByteRange, size int64, ok bool) {
+	unit, rest, ok := strings.Cut(strings.TrimSpace(v), " ")
+	if !ok || unit != rangeUnit {
+		return r, 0, false
+	}
+	positions, length, ok := strings.Cut(rest, "/")
+	if !ok {
+		return r, 0, false
+	}
+	size, err := parseBytePosition(length)
+	if err != nil {
+		return r, 0, false
+	}
+	if positions == "*" {
+		return ByteRange{Start: -1, End: -1}, size, true
+	}
+	first, last, ok := strings.Cut(positions, "-")
+	if !ok {
+		return r, 0, false
+	}
+	if r.Start, err = parseBytePosition(first); err != nil {
+		return r, 0, false
+	}
+	if r.End, err = parseBytePosition(last); err != nil {
+		return r, 0, false
+	}
+	if r.End < r.Start || r.End >= size {
+		return r, 0, false
+	}
+	return r, size, true
+}
+
+func parseBytePosition(v string) (int64, error) {
+	for _, c := range v {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid byte position: %s", v)
+		}
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect []ByteRange
+		valid  bool
+	}{
+		{input: "bytes=0-4", expect: []ByteRange{{Start: 0, End: 4}}, valid: true},
+		{input: "bytes=20-", expect: []ByteRange{{Start: 20, End: 25}}, valid: true},
+		{input: "bytes=-3", expect: []ByteRange{{Start: 23, End: 25}}, valid: true},
+		{input: "bytes=-100", expect: []ByteRange{{Start: 0, End: 25}}, valid: true},
+		{input: "bytes=0-1, 24-100", expect: []ByteRange{{Start: 0, End: 1}, {Start: 24, End: 25}}, valid: true},
+		{input: "bytes=30-", expect: nil, valid: true},
+		{input: "bytes=5-1"},
+		{input: "bytes=-"},
+		{input: "bytes=a-b"},
+		{input: "items=0-4"},
+		{input: ""},
+	}
+
+	for _, tt := range tests {
+		specs, ok := ParseRange(tt.input)
+		if ok != tt.valid {
+			t.Errorf("ParseRange(%q) validity expects %t, got %t", tt.input, tt.valid, ok)
+			continue
+		}
+		if diff := cmp.Diff(tt.expect, ResolveRanges(specs, 26)); diff != "" {
+			t.Errorf("ParseRange(%q) resolved ranges mismatch, diff=%s", tt.input, diff)
+		}
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect ByteRange
+		size   int64
+		valid  bool
+	}{
+		{input: "bytes 0-4/26", expect: ByteRange{Start: 0, End: 4}, size: 26, valid: true},
+		{input: "bytes */26", expect: ByteRange{Start: -1, End: -1}, size: 26, valid: true},
+		{input: "bytes 0-4/*"},
+		{input: "bytes 0-30/26"},
+		{input: "bytes 0-4"},
+	}
+
+	for _, tt := range tests {
+		r, size, ok := ParseContentRange(tt.input)
+		if ok != tt.valid {
+			t.Errorf("ParseContentRange(%q) validity expects %t, got %t", tt.input, tt.valid, ok)
+			continue
+		}
+		if ok && (r != tt.expect || size != tt.size) {
+			t.Errorf("ParseContentRange(%q) expects %v/%d, got %v/%d", tt.input, tt.expect, tt.size, r, size)
+		}
+	}
+}
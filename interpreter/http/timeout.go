@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/interpreter/exception"
+)
+
+// Backend timeout errors. Error messages are the same as obj.response value
+// which Fastly responds with 503 status code
+var (
+	ErrConnectTimeout      = errors.New("connection timed out")
+	ErrFirstByteTimeout    = errors.New("first byte timeout")
+	ErrBetweenBytesTimeout = errors.New("between bytes timeout")
+)
+
+// Root certificates to verify the backend, nil means the system pool is used.
+// Replaced in testing to trust the test server certificate
+var rootCAs *x509.CertPool
+
+// Timeouts for the backend request, zero value means no timeout
+type Timeouts struct {
+	// Timeout for establishing the connection including TLS handshake
+	Connect time.Duration
+	// Timeout for waiting the first byte of the response after the request is sent
+	FirstByte time.Duration
+	// Timeout for waiting the next bytes of the response after the first byte is received
+	BetweenBytes time.Duration
+}
+
+// SendRequestWithTimeouts sends HTTP request with applying backend timeouts.
+// Returned error is one of timeout errors when the request is timed out,
+// and reading the response body also returns ErrBetweenBytesTimeout
+func SendRequestWithTimeouts(req *Request, t Timeouts) (*Response, error) {
+	dialer := &net.Dialer{Timeout: t.Connect}
+	// Keep the dialed connection to arm the between bytes timeout after response headers are read
+	var dialed atomic.Pointer[betweenBytesConn]
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSHandshakeTimeout:   t.Connect,
+		ResponseHeaderTimeout: t.FirstByte,
+		// Connection is not reused because the read deadline is managed for each request
+		DisableKeepAlives: true,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			bc := &betweenBytesConn{Conn: conn, timeout: t.BetweenBytes}
+			dialed.Store(bc)
+			return bc, nil
+		},
+	}
+	if req.URL.Scheme == "https" {
+		transport.TLSClientConfig = &tls.Config{
+			ServerName: req.URL.Hostname(),
+			RootCAs:    rootCAs,
+		}
+	}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Do(req.Request)
+	if err != nil {
+		if terr := classifyTimeout(err, false); terr != nil {
+			return nil, terr
+		}
+		return nil, exception.Runtime(nil, "Failed to retrieve backend response: %s", err)
+	}
+	resp.Body = &timeoutBody{ReadCloser: resp.Body, conn: dialed.Load()}
+	return WrapResponse(resp), nil
+}
+
+// Connection which limits the time between reads after the response headers are received.
+// Note that bytes of TLS handshake must not be counted as the first byte,
+// so the timeout is armed by the response body rather than the bytes read from the connection
+type betweenBytesConn struct {
+	net.Conn
+	timeout time.Duration
+	armed   atomic.Bool
+}
+
+func (c *betweenBytesConn) Read(b []byte) (int, error) {
+	if c.timeout > 0 && c.armed.Load() {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout)) // nolint:errcheck
+	}
+	return c.Conn.Read(b)
+}
+
+// Response body which converts the timeout error on reading
+type timeoutBody struct {
+	io.ReadCloser
+	conn *betweenBytesConn
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	if b.conn != nil {
+		b.conn.armed.Store(true)
+	}
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		if terr := classifyTimeout(err, true); terr != nil {
+			err = terr
+		}
+	}
+	return n, err
+}
+
+// Classify the error into backend timeout errors, returns nil if the error is not caused by timeout.
+// Context deadline by bereq.fetch_timeout is also treated as first byte or between bytes timeout
+func classifyTimeout(err error, received bool) error {
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		if !errors.Is(err, os.ErrDeadlineExceeded) && !strings.Contains(err.Error(), "context deadline exceeded") {
+			return nil
+		}
+	}
+
+	var oe *net.OpError
+	switch {
+	case errors.As(err, &oe) && oe.Op == "dial":
+		return ErrConnectTimeout
+	case strings.Contains(err.Error(), "TLS handshake timeout"):
+		return ErrConnectTimeout
+	case strings.Contains(err.Error(), "timeout awaiting response headers"):
+		return ErrFirstByteTimeout
+	case received || errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrBetweenBytesTimeout
+	default:
+		return ErrFirstByteTimeout
+	}
+}
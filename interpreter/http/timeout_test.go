@@ -0,0 +1,89 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendRequestWithTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow-first-byte":
+			time.Sleep(200 * time.Millisecond)
+		case "/slow-between-bytes":
+			w.Write([]byte("first")) // nolint:errcheck
+			w.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte("OK")) // nolint:errcheck
+	}))
+	defer server.Close()
+
+	timeouts := Timeouts{
+		Connect:      time.Second,
+		FirstByte:    50 * time.Millisecond,
+		BetweenBytes: 50 * time.Millisecond,
+	}
+	tests := []struct {
+		path   string
+		expect error
+	}{
+		{path: "/", expect: nil},
+		{path: "/slow-first-byte", expect: ErrFirstByteTimeout},
+		{path: "/slow-between-bytes", expect: ErrBetweenBytesTimeout},
+	}
+
+	for _, tt := range tests {
+		req, err := NewRequest(http.MethodGet, server.URL+tt.path, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %s", err)
+		}
+		resp, err := SendRequestWithTimeouts(req, timeouts)
+		if err == nil {
+			var buf bytes.Buffer
+			_, err = buf.ReadFrom(resp.Body)
+			resp.Body.Close()
+		}
+		if !errors.Is(err, tt.expect) {
+			t.Errorf("%s: expects error %v, got %v", tt.path, tt.expect, err)
+		}
+	}
+
+	t.Run("first byte is not counted from TLS handshake", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Response is sent after the between bytes timeout but within the first byte timeout
+			time.Sleep(300 * time.Millisecond)
+			w.Write([]byte("OK")) // nolint:errcheck
+		}))
+		defer server.Close()
+
+		rootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+		defer func() { rootCAs = nil }()
+
+		req, err := NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %s", err)
+		}
+		resp, err := SendRequestWithTimeouts(req, Timeouts{
+			Connect:      time.Second,
+			FirstByte:    time.Second,
+			BetweenBytes: 100 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		defer resp.Body.Close()
+
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(resp.Body); err != nil {
+			t.Fatalf("Failed to read response body: %s", err)
+		}
+		if buf.String() != "OK" {
+			t.Errorf("Expects response body OK, got %s", buf.String())
+		}
+	})
+}
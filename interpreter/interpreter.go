@@ -3,6 +3,7 @@ package interpreter
 import (
 	"fmt"
 	"io"
+	"maps"
 	ghttp "net/http"
 	"strings"
 	"sync"
@@ -12,8 +13,10 @@ import (
 	"github.com/pkg/errors"
 	"github.com/ysugimoto/falco/ast"
 	"github.com/ysugimoto/falco/interpreter/cache"
+	"github.com/ysugimoto/falco/interpreter/cache/cachecontrol"
 	"github.com/ysugimoto/falco/interpreter/context"
 	"github.com/ysugimoto/falco/interpreter/exception"
+	"github.com/ysugimoto/falco/interpreter/function"
 	"github.com/ysugimoto/falco/interpreter/http"
 	"github.com/ysugimoto/falco/interpreter/limitations"
 	"github.com/ysugimoto/falco/interpreter/process"
@@ -21,37 +24,244 @@ import (
 	"github.com/ysugimoto/falco/interpreter/variable"
 	"github.com/ysugimoto/falco/lexer"
 	"github.com/ysugimoto/falco/parser"
+	"github.com/ysugimoto/falco/token"
 )
 
 type Interpreter struct {
-	vars      variable.Variable
-	localVars variable.LocalVariables
-	lock      sync.Mutex
+	// Request scoped states, each request has its own states
+	*requestState
+	// States which are shared between requests
+	*sharedState
+
+	// Lock for processing requests one by one on debugging
+	lock *sync.Mutex
 
 	options []context.Option
 
-	ctx           *context.Context
-	process       *process.Process
-	cache         *cache.Cache
-	callStack     []*ast.SubroutineDeclaration
 	Debugger      Debugger
 	IdentResolver func(v string) value.Value
 
+	// Injected functions and variable for this interpreter, e.g testing functions
+	functions        function.Functions
+	injectedVariable variable.InjectVariable
+
+	// Mocked origin and default backend which are used on testing request lifecycle
+	originMock     func(req *http.Request) (*http.Response, error)
+	defaultBackend *value.Backend
+
 	TestingState State
 }
 
+// Create an interpreter for the incoming request which has its own request scoped states
+// and shares other fields with this interpreter
+func (i *Interpreter) clone() *Interpreter {
+	c := *i
+	c.requestState = newRequestState()
+	c.lock = &sync.Mutex{}
+	c.TestingState = NONE
+	return &c
+}
+
+type requestState struct {
+	ctx           *context.Context
+	vars          variable.Variable
+	localVars     variable.LocalVariables
+	process       *process.Process
+	callStack     []*ast.SubroutineDeclaration
+	gotoStatement *ast.GotoStatement
+	// Release the busy object which is acquired on cache miss for request collapsing
+	releaseBusy func(pass bool)
+	// The last exception which is notified to the debugger
+	raised *exception.Exception
+}
+
+func newRequestState() *requestState {
+	return &requestState{
+		callStack: []*ast.SubroutineDeclaration{},
+		localVars: variable.LocalVariables{},
+		process:   process.New(),
+	}
+}
+
+// Stateful objects which should be kept between requests even VCL is parsed for each request.
+// These objects are accessed from concurrent requests so they must be goroutine safe.
+type sharedState struct {
+	cache *cache.Cache
+
+	mu           sync.Mutex
+	ratecounters map[string]*value.Ratecounter
+	penaltyboxes map[string]*value.Penaltybox
+	probes       map[string]*backendProbe
+
+	// Running background revalidations
+	revalidations sync.WaitGroup
+
+	// States of the shield POP on origin shielding, created when the shield POP is used at first
+	shieldState *sharedState
+	// States of the edge POP, set on the shield POP states to share backend probes
+	edgeState *sharedState
+}
+
+func newSharedState() *sharedState {
+	return &sharedState{
+		cache:        cache.New(),
+		ratecounters: make(map[string]*value.Ratecounter),
+		penaltyboxes: make(map[string]*value.Penaltybox),
+		probes:       make(map[string]*backendProbe),
+	}
+}
+
 func New(options ...context.Option) *Interpreter {
 	return &Interpreter{
+		requestState: newRequestState(),
+		sharedState:  newSharedState(),
+		lock:         &sync.Mutex{},
 		options:      options,
-		cache:        cache.New(),
-		callStack:    []*ast.SubroutineDeclaration{},
-		localVars:    variable.LocalVariables{},
 		Debugger:     DefaultDebugger{},
 		TestingState: NONE,
-		process:      process.New(),
 	}
 }
 
+// Get the states of the shield POP which has its own cache, ratecounters and penaltyboxes
+func (s *sharedState) shield() *sharedState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shieldState == nil {
+		s.shieldState = newSharedState()
+		s.shieldState.edgeState = s
+	}
+	return s.shieldState
+}
+
+// Get all caches of virtual POPs, purging is propagated to all POPs like Fastly does
+func (s *sharedState) caches() []*cache.Cache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	caches := []*cache.Cache{s.cache}
+	if s.shieldState != nil {
+		caches = append(caches, s.shieldState.cache)
+	}
+	return caches
+}
+
+// Get the ratecounter which is shared between requests, create new one if not exists.
+// The ratecounter is recreated when the declaration is changed
+func (s *sharedState) ratecounter(decl *ast.RatecounterDeclaration) *value.Ratecounter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rc, ok := s.ratecounters[decl.Name.Value]
+	if !ok || declarationSignature(rc.Decl.Name, rc.Decl.Block) != declarationSignature(decl.Name, decl.Block) {
+		rc = value.NewRatecounter(decl)
+		s.ratecounters[decl.Name.Value] = rc
+	}
+	return rc
+}
+
+// Get the penaltybox which is shared between requests, create new one if not exists.
+// The penaltybox is recreated when the declaration is changed
+func (s *sharedState) penaltybox(decl *ast.PenaltyboxDeclaration) *value.Penaltybox {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pb, ok := s.penaltyboxes[decl.Name.Value]
+	if !ok || declarationSignature(pb.Decl.Name, pb.Decl.Block) != declarationSignature(decl.Name, decl.Block) {
+		pb = value.NewPenaltybox(decl)
+		s.penaltyboxes[decl.Name.Value] = pb
+	}
+	return pb
+}
+
+// Make the signature of the declaration from its name and properties.
+// Comments are ignored in order not to reset the state by comment-only changes
+func declarationSignature(name *ast.Ident, block *ast.BlockStatement) string {
+	var buf strings.Builder
+	buf.WriteString(name.Value)
+
+	l := lexer.NewFromString(block.String())
+	for {
+		tok := l.NextToken()
+		switch tok.Type {
+		case token.EOF:
+			return buf.String()
+		case token.COMMENT, token.LF:
+			continue
+		}
+		buf.WriteString(" " + string(tok.Type) + ":" + tok.Literal)
+	}
+}
+
+// Get the running probe of the backend, start the probe if not running.
+// The probe is restarted when the backend declaration is changed.
+// Backends are probed once even if they are declared on both edge and shield POPs
+func (s *sharedState) backendProbe(name string, p *backendProbe) *backendProbe {
+	if s.edgeState != nil {
+		return s.edgeState.backendProbe(name, p)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if running, ok := s.probes[name]; ok {
+		if running.definition == p.definition {
+			return running
+		}
+		running.stop()
+	}
+	s.probes[name] = p
+	p.start()
+	return p
+}
+
+// Stop probes of the backends which are not declared anymore
+func (s *sharedState) pruneProbes(backends map[string]*value.Backend) {
+	if s.edgeState != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, p := range s.probes {
+		if _, ok := backends[name]; !ok {
+			p.stop()
+			delete(s.probes, name)
+		}
+	}
+}
+
+// Stop all running probes
+func (s *sharedState) stopProbes() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, p := range s.probes {
+		p.stop()
+		delete(s.probes, name)
+	}
+}
+
+// Stop background tasks of the interpreter like backend probes.
+// The interpreter should be closed when the simulator is shut down
+func (i *Interpreter) Close() {
+	i.stopProbes()
+}
+
+// Inject functions to this interpreter. Injected functions override builtin functions
+func (i *Interpreter) InjectFunctions(fns map[string]*function.Function) {
+	if i.functions == nil {
+		i.functions = make(function.Functions)
+	}
+	maps.Copy(i.functions, fns)
+}
+
+// Inject variable getter and setter to this interpreter
+func (i *Interpreter) InjectVariable(v variable.InjectVariable) {
+	i.injectedVariable = v
+}
+
 func (i *Interpreter) SetScope(scope context.Scope) {
 	i.ctx.Scope = scope
 	switch scope {
@@ -77,12 +287,15 @@ func (i *Interpreter) SetScope(scope context.Scope) {
 }
 
 func (i *Interpreter) restart() error {
+	i.release()
 	i.ctx.Restarts++
 	i.Debugger.Message(fmt.Sprintf("Restarted (%d) time", i.ctx.Restarts))
 	i.ctx.BackendRequest = nil
 	i.ctx.BackendResponse = nil
 	i.ctx.Object = nil
 	i.ctx.Response = nil
+	i.ctx.IsPassRequest = false
+	i.ctx.SegmentedCaching = nil
 
 	if err := i.ProcessRecv(); err != nil {
 		return errors.WithStack(err)
@@ -127,8 +340,33 @@ func (i *Interpreter) ProcessInit(r *http.Request) error {
 			vcl.Statements = append(s.Statements, vcl.Statements...)
 		}
 	}
-	ctx.RequestStartTime = time.Now()
+	ctx.InjectedVariable = i.injectedVariable
 	i.ctx = ctx
+	i.setupRequest(r)
+
+	vcl.Statements, err = i.resolveIncludeStatement(vcl.Statements, true)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// instrumenting if coverage measurement is enabled
+	if i.ctx.Coverage != nil {
+		i.instrument(vcl)
+	}
+	if err := i.ProcessDeclarations(vcl.Statements); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := limitations.CheckFastlyResourceLimit(i.ctx); err != nil {
+		return errors.WithStack(err)
+	}
+	// Keep the default backend to set up the context for the inherited requests like ESI include
+	i.defaultBackend = i.ctx.Backend
+
+	return nil
+}
+
+// Set up request related states for the current context
+func (i *Interpreter) setupRequest(r *http.Request) {
+	i.ctx.RequestStartTime = time.Now()
 	i.ctx.Request = r
 	r.Header.Set("Host", r.Host)
 
@@ -149,23 +387,34 @@ func (i *Interpreter) ProcessInit(r *http.Request) error {
 	if i.ctx.IsPurgeRequest {
 		i.ctx.OriginalHost = "api.fastly.com"
 	}
+}
 
-	vcl.Statements, err = i.resolveIncludeStatement(vcl.Statements, true)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-	// instrumenting if coverage measurement is enabled
-	if i.ctx.Coverage != nil {
-		i.instrument(vcl)
-	}
-	if err := i.ProcessDeclarations(vcl.Statements); err != nil {
-		return errors.WithStack(err)
-	}
-	if err := limitations.CheckFastlyResourceLimit(i.ctx); err != nil {
-		return errors.WithStack(err)
-	}
+// Set up a new context for the request which shares declarations, mocks and settings
+// with the parent context, so that the request could be processed without parsing VCL again
+func (i *Interpreter) inheritContext(prev *context.Context, r *http.Request) {
+	ctx := context.New(i.options...)
+	ctx.Acls = prev.Acls
+	ctx.Backends = prev.Backends
+	ctx.Tables = prev.Tables
+	ctx.Subroutines = prev.Subroutines
+	ctx.Penaltyboxes = prev.Penaltyboxes
+	ctx.Ratecounters = prev.Ratecounters
+	ctx.Gotos = prev.Gotos
+	ctx.SubroutineFunctions = prev.SubroutineFunctions
+	ctx.OverrideBackends = prev.OverrideBackends
+	ctx.InjectEdgeDictionaries = prev.InjectEdgeDictionaries
+	ctx.MockedSubroutines = prev.MockedSubroutines
+	ctx.MockedFunctioncalSubroutines = prev.MockedFunctioncalSubroutines
+	ctx.MockedBackends = prev.MockedBackends
+	ctx.OverrideVariables = prev.OverrideVariables
+	ctx.FixedTime = prev.FixedTime
+	ctx.FixedAccessRate = prev.FixedAccessRate
+	ctx.Coverage = prev.Coverage
+	ctx.InjectedVariable = prev.InjectedVariable
+	ctx.Backend = i.defaultBackend
 
-	return nil
+	i.ctx = ctx
+	i.setupRequest(r)
 }
 
 func (i *Interpreter) ProcessDeclarations(statements []ast.Statement) error {
@@ -194,8 +443,10 @@ func (i *Interpreter) ProcessDeclarations(statements []ast.Statement) error {
 			if err != nil {
 				return errors.WithStack(err)
 			}
+			// Director is healthy when it could determine the backend from healthy backends.
+			// Shield director is always healthy because health of the shield POP is not simulated
 			h := &atomic.Bool{}
-			h.Store(true)
+			h.Store(dc.Type == value.DIRECTORTYPE_SHIELD || i.canDetermineBackend(dc) == nil)
 			i.ctx.Backends[t.Name.Value] = &value.Backend{Director: dc, Literal: true, Healthy: h}
 		case *ast.TableDeclaration:
 			i.Debugger.Run(stmt)
@@ -233,13 +484,13 @@ func (i *Interpreter) ProcessDeclarations(statements []ast.Statement) error {
 			if _, ok := i.ctx.Penaltyboxes[t.Name.Value]; ok {
 				return exception.Runtime(&t.Token, "Penaltybox %s is duplicated", t.Name.Value)
 			}
-			i.ctx.Penaltyboxes[t.Name.Value] = value.NewPenaltybox(t)
+			i.ctx.Penaltyboxes[t.Name.Value] = i.penaltybox(t)
 		case *ast.RatecounterDeclaration:
 			i.Debugger.Run(stmt)
 			if _, ok := i.ctx.Ratecounters[t.Name.Value]; ok {
 				return exception.Runtime(&t.Token, "Ratecounter %s is duplicated", t.Name.Value)
 			}
-			i.ctx.Ratecounters[t.Name.Value] = value.NewRatecounter(t)
+			i.ctx.Ratecounters[t.Name.Value] = i.ratecounter(t)
 		}
 	}
 
@@ -270,6 +521,15 @@ func (i *Interpreter) ProcessBackends(statements []ast.Statement) error {
 		i.Debugger.Run(stmt)
 		h := &atomic.Bool{}
 		h.Store(true)
+		// Health of the backend is shared with the running probe
+		if i.ctx.IsBackendProbe {
+			p, err := i.newBackendProbe(t)
+			if err != nil {
+				return errors.WithStack(err)
+			} else if p != nil {
+				h = i.backendProbe(t.Name.Value, p).healthy
+			}
+		}
 		// Determine default backend
 		if i.ctx.Backend == nil {
 			i.ctx.Backend = &value.Backend{Value: t, Literal: true, Healthy: h}
@@ -279,11 +539,17 @@ func (i *Interpreter) ProcessBackends(statements []ast.Statement) error {
 		}
 		i.ctx.Backends[t.Name.Value] = &value.Backend{Value: t, Literal: true, Healthy: h}
 	}
+	// Backends could be removed from VCL which is parsed for each request
+	if i.ctx.IsBackendProbe {
+		i.pruneProbes(i.ctx.Backends)
+	}
 	return nil
 }
 
 func (i *Interpreter) ProcessRecv() error {
 	i.SetScope(context.RecvScope)
+	// Guard for waiting requests if the process ends without fetching
+	defer i.release()
 
 	// Simulate Fastly statement lifecycle
 	// see: https://developer.fastly.com/learning/vcl/using/#the-vcl-request-lifecycle
@@ -315,7 +581,9 @@ func (i *Interpreter) ProcessRecv() error {
 				`Failed to accept purge request. The vcl_recv subroutine MUST return "lookup" or "pass" state with return statement`,
 			)
 		}
-		// We don't call following state machine subroutines.
+		// Purge cached objects of the URL and
+		// we don't call following state machine subroutines.
+		i.purgeURL()
 		return nil
 	}
 
@@ -338,18 +606,7 @@ func (i *Interpreter) ProcessRecv() error {
 		if err = i.ProcessHash(); err != nil {
 			return errors.WithStack(err)
 		}
-		if v := i.cache.Get(i.ctx.RequestHash.Value); v != nil {
-			i.process.Cached = true
-			i.ctx.State = "HIT"
-			i.ctx.CacheHitItem = v
-			i.ctx.Object = v.Response.Clone()
-			i.Debugger.Message(fmt.Sprintf("Move state: %s -> HIT", i.ctx.Scope))
-			err = i.ProcessHit()
-		} else {
-			i.ctx.State = "MISS"
-			i.Debugger.Message(fmt.Sprintf("Move state: %s -> MISS", i.ctx.Scope))
-			err = i.ProcessMiss()
-		}
+		err = i.lookup()
 	default:
 		return exception.Runtime(
 			&sub.GetMeta().Token,
@@ -408,6 +665,8 @@ func (i *Interpreter) ProcessMiss() error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	// Fastly fetches the whole object on cache miss and processes Range request by itself
+	stripRangeHeaders(i.ctx.BackendRequest)
 
 	// Simulate Fastly statement lifecycle
 	// see: https://developer.fastly.com/learning/vcl/using/#the-vcl-request-lifecycle
@@ -425,7 +684,16 @@ func (i *Interpreter) ProcessMiss() error {
 
 	switch state {
 	case DELIVER_STALE:
-		i.Debugger.Message(fmt.Sprintf("Move state: %s -> DELIVER", i.ctx.Scope))
+		if i.ctx.StaleItem == nil {
+			return exception.Runtime(
+				&sub.GetMeta().Token,
+				"Subroutine %s returned %s but stale object does not exist in MISS",
+				sub.Name.Value,
+				state,
+			)
+		}
+		i.useStaleObject(i.ctx.StaleItem, false)
+		i.Debugger.Message(fmt.Sprintf("Move state: %s -> DELIVER (stale)", i.ctx.Scope))
 		err = i.ProcessDeliver()
 	case PASS:
 		i.Debugger.Message(fmt.Sprintf("Move state: %s -> PASS", i.ctx.Scope))
@@ -516,6 +784,10 @@ func (i *Interpreter) ProcessPass() error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	i.ctx.IsPassRequest = true
+	if i.ctx.EnableRangeOnPass.Value {
+		stripRangeHeaders(i.ctx.BackendRequest)
+	}
 
 	// Simulate Fastly statement lifecycle
 	// see: https://developer.fastly.com/learning/vcl/using/#the-vcl-request-lifecycle
@@ -562,23 +834,43 @@ func (i *Interpreter) ProcessFetch() error {
 
 	// Send request to backend
 	var err error
-	i.ctx.BackendResponse, err = i.sendBackendRequest(i.ctx.Backend)
+	if i.ctx.EnableSegmentedCaching.Value && !i.ctx.IsPassRequest {
+		i.ctx.BackendResponse, err = i.segmentedFetch(i.ctx.Backend)
+	} else {
+		i.ctx.BackendResponse, err = i.sendBackendRequest(i.ctx.Backend)
+	}
 	if err != nil {
+		// Serve stale object instead if the origin fails in the stale-if-error period
+		if item := i.ctx.StaleItem; item != nil && i.canServeIfError(item) {
+			i.Debugger.Message(fmt.Sprintf("Backend request failed: %s, serve stale object", err))
+			i.useStaleObject(item, true)
+			i.Debugger.Message(fmt.Sprintf("Move state: %s -> DELIVER (stale)", i.ctx.Scope))
+			return errors.WithStack(i.ProcessDeliver())
+		}
+		// Backend timeout is responded as 503 error through vcl_error
+		if response, code := backendTimeoutError(err); response != "" {
+			i.Debugger.Message(fmt.Sprintf("Backend request failed: %s", response))
+			i.ctx.ObjectStatus = &value.Integer{Value: ghttp.StatusServiceUnavailable}
+			i.ctx.ObjectResponse = &value.String{Value: response}
+			i.ctx.FastlyError = &value.String{Value: code}
+			i.Debugger.Message(fmt.Sprintf("Move state: %s -> ERROR", i.ctx.Scope))
+			return errors.WithStack(i.ProcessError())
+		}
 		return errors.WithStack(err)
 	}
 
 	// Mark request process has ended
 	i.ctx.RequestEndTime = time.Now()
 
-	// Set cacheable strategy
-	isCacheable := cache.IsCacheableStatusCode(i.ctx.BackendResponse.StatusCode)
+	// Set cacheable strategy from the status code and cache related response headers
+	freshness := cachecontrol.Determine(i.ctx.BackendResponse.Header, i.ctx.RequestEndTime)
+	isCacheable := cache.IsCacheableStatusCode(i.ctx.BackendResponse.StatusCode) && freshness.Cacheable
 	i.ctx.BackendResponseCacheable = &value.Boolean{Value: isCacheable}
 	if isCacheable {
-		i.ctx.BackendResponseTTL = &value.RTime{
-			Value: i.determineCacheTTL(i.ctx.BackendResponse),
-		}
+		i.ctx.BackendResponseTTL = &value.RTime{Value: freshness.TTL}
 	}
-	// TODO: consider stale-white-revalidate and stale-if-error TTL
+	i.ctx.BackendResponseStaleWhileRevalidate = &value.RTime{Value: freshness.StaleWhileRevalidate}
+	i.ctx.BackendResponseStaleIfError = &value.RTime{Value: freshness.StaleIfError}
 
 	// Simulate Fastly statement lifecycle
 	// see: https://developer.fastly.com/learning/vcl/using/#the-vcl-request-lifecycle
@@ -594,7 +886,25 @@ func (i *Interpreter) ProcessFetch() error {
 		}
 	}
 
-	i.updateCache()
+	// Deliver stale object instead of the backend response, the response is not cached
+	if state == DELIVER_STALE && i.ctx.StaleItem != nil {
+		i.useStaleObject(i.ctx.StaleItem, true)
+		i.Debugger.Message(fmt.Sprintf("Move state: %s -> DELIVER (stale)", i.ctx.Scope))
+		return errors.WithStack(i.ProcessDeliver())
+	}
+
+	// Segmented blocks are already cached so the assembled object is not cached,
+	// but waiting requests could look up the cached blocks
+	cached := true
+	if i.ctx.SegmentedCaching == nil {
+		cached = i.updateCache()
+	}
+	// Waiting requests could look up the fetched object
+	i.releaseObject(cached)
+	// Background fetch only updates the cache
+	if i.ctx.IsBackgroundFetch.Value {
+		return nil
+	}
 	switch state {
 	case DELIVER, DELIVER_STALE, PASS, HIT_FOR_PASS:
 		i.Debugger.Message(fmt.Sprintf("Move state: %s -> DELIVER", i.ctx.Scope))
@@ -620,6 +930,8 @@ func (i *Interpreter) ProcessFetch() error {
 
 func (i *Interpreter) ProcessError() error {
 	i.SetScope(context.ErrorScope)
+	// Response is generated locally so waiting requests do not need to wait anymore
+	i.release()
 
 	// If process goes through the error directive, response will be generated locally
 	// @see: https://developer.fastly.com/reference/vcl/variables/client-response/resp-is-locally-generated/
@@ -655,6 +967,13 @@ func (i *Interpreter) ProcessError() error {
 	}
 
 	switch state {
+	case DELIVER_STALE:
+		// Deliver synthetic object if stale object does not exist
+		if i.ctx.StaleItem != nil {
+			i.useStaleObject(i.ctx.StaleItem, true)
+		}
+		i.Debugger.Message(fmt.Sprintf("Move state: %s -> DELIVER", i.ctx.Scope))
+		err = i.ProcessDeliver()
 	case DELIVER:
 		i.Debugger.Message(fmt.Sprintf("Move state: %s -> DELIVER", i.ctx.Scope))
 		err = i.ProcessDeliver()
@@ -682,21 +1001,24 @@ func (i *Interpreter) ProcessDeliver() error {
 	} else if i.ctx.BackendResponse != nil {
 		i.ctx.Response = i.ctx.BackendResponse.Clone()
 	}
+	i.processRange()
 
 	// Add Fastly related server info but values are falco's one.
+	// Values are chained when the response comes from the shield POP.
 	// Note that these headers could be removed in vcl_deliver subroutine
-	i.ctx.Response.Header.Set("X-Served-By", cache.LocalDatacenterString)
-	i.ctx.Response.Header.Set("X-Cache", i.ctx.State)
+	servedBy := variable.FALCO_SERVER_HOSTNAME + "-" + variable.Datacenter(i.ctx)
+	chainHeader(i.ctx.Response.Header, "X-Served-By", servedBy)
+	chainHeader(i.ctx.Response.Header, "X-Cache", xCacheValue(i.ctx.State))
 	i.ctx.Response.Header.Set("Date", time.Now().Format(http.TimeFormat))
 	i.ctx.Response.Header.Set("Server", "Falco")
 	i.ctx.Response.Header.Set("Via", "Falco")
 
 	// Additionally set cache related headers
 	if i.ctx.CacheHitItem != nil {
-		i.ctx.Response.Header.Set("X-Cache-Hits", fmt.Sprint(i.ctx.CacheHitItem.Hits))
+		chainHeader(i.ctx.Response.Header, "X-Cache-Hits", fmt.Sprint(i.ctx.CacheHitItem.Hits))
 		i.ctx.Response.Header.Set("Age", fmt.Sprintf("%.0f", time.Since(i.ctx.CacheHitItem.EntryTime).Seconds()))
 	} else {
-		i.ctx.Response.Header.Set("X-Cache-Hits", "0")
+		chainHeader(i.ctx.Response.Header, "X-Cache-Hits", "0")
 	}
 
 	// Simulate Fastly statement lifecycle
@@ -729,15 +1051,15 @@ func (i *Interpreter) ProcessDeliver() error {
 		if i.ctx.Request.Header.Get("Fastly-Debug") != "" {
 			i.ctx.Response.Header.Set(
 				"Fastly-Debug-Path",
-				fmt.Sprintf("(D %s 0) (F %s 0)", cache.LocalDatacenterString, cache.LocalDatacenterString),
+				fmt.Sprintf("(D %s 0) (F %s 0)", servedBy, servedBy),
 			)
 			cacheHit := "M"
-			if i.ctx.State == "HIT" {
+			if xCacheValue(i.ctx.State) == "HIT" {
 				cacheHit = "H"
 			}
 			i.ctx.Response.Header.Set(
 				"Fastly-Debug-TTL",
-				fmt.Sprintf("(%s %s %.3f %.3f %d)", cacheHit, cache.LocalDatacenterString, 0.000, 0.000, 0),
+				fmt.Sprintf("(%s %s %.3f %.3f %d)", cacheHit, servedBy, 0.000, 0.000, 0),
 			)
 		}
 
@@ -780,48 +1102,29 @@ func (i *Interpreter) ProcessLog() error {
 	return nil
 }
 
-var expiresValueLayout = "Mon, 02 Jan 2006 15:04:05 MST"
-
-func (i *Interpreter) determineCacheTTL(resp *http.Response) time.Duration {
-	if v := resp.Header.Get("Surrogate-Control"); v != "" {
-		if maxAge, found := strings.CutPrefix(v, "max-age="); found {
-			if dur, err := time.ParseDuration(maxAge + "s"); err == nil {
-				return dur
-			}
-		}
-	}
-	if v := resp.Header.Get("Cache-Control"); v != "" {
-		if sMaxAge, found := strings.CutPrefix(v, "s-maxage="); found {
-			if dur, err := time.ParseDuration(sMaxAge + "s"); err == nil {
-				return dur
-			}
-		}
-		if maxAge, found := strings.CutPrefix(v, "max-age="); found {
-			if dur, err := time.ParseDuration(maxAge + "s"); err == nil {
-				return dur
-			}
-		}
-	}
-	if v := resp.Header.Get("Expires"); v != "" {
-		if d, err := time.Parse(expiresValueLayout, v); err == nil {
-			return time.Until(d)
-		}
-	}
-	return time.Duration(2 * time.Minute)
-}
-
-func (i *Interpreter) updateCache() {
+// Store the backend response in the cache, returns true if stored
+func (i *Interpreter) updateCache() bool {
 	resp := i.ctx.BackendResponse.Clone()
 	// Note: compare BackendResponseCacheable value
 	// because this value will be changed by user in vcl_fetch directive
 	if i.ctx.BackendResponseCacheable.Value {
 		if i.ctx.BackendResponseTTL.Value.Seconds() > 0 {
 			now := time.Now()
-			i.cache.Set(i.ctx.RequestHash.String(), &cache.CacheItem{
-				Response:  resp,
-				Expires:   now.Add(i.ctx.BackendResponseTTL.Value),
-				EntryTime: now,
+			vary := cache.ParseVary(resp.Header)
+			return i.cache.Set(i.ctx.RequestHash.String(), &cache.CacheItem{
+				Response:      resp,
+				Expires:       now.Add(i.ctx.BackendResponseTTL.Value),
+				EntryTime:     now,
+				URL:           cacheURL(i.ctx.Request),
+				SurrogateKeys: surrogateKeys(resp),
+				Vary:          vary,
+				Variant:       cache.VariantKey(vary, i.ctx.Request.Header),
+				ESI:           i.ctx.TriggerESI,
+
+				StaleWhileRevalidate: i.ctx.BackendResponseStaleWhileRevalidate.Value,
+				StaleIfError:         i.ctx.BackendResponseStaleIfError.Value,
 			})
 		}
 	}
+	return false
 }
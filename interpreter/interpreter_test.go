@@ -1,8 +1,18 @@
 package interpreter
 
 import (
+	"bufio"
+	gocontext "context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"net/http"
 	"net/http/httptest"
@@ -10,8 +20,13 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/config"
 	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/exception"
+	fhttp "github.com/ysugimoto/falco/interpreter/http"
 	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/lexer"
+	"github.com/ysugimoto/falco/parser"
 	"github.com/ysugimoto/falco/resolver"
 	"github.com/ysugimoto/falco/token"
 )
@@ -27,6 +42,29 @@ backend example {
 	)
 }
 
+// Start the origin server which responds by the handler.
+// The server is closed when the test finishes
+func newTestOrigin(t *testing.T, handler http.HandlerFunc) *url.URL {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	parsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Test server URL parsing error: %s", err)
+	}
+	return parsed
+}
+
+// Create the interpreter for the VCL which is prepended the default backend of the origin server
+func newTestInterpreter(t *testing.T, handler http.HandlerFunc, vcl string, opts ...context.Option) *Interpreter {
+	t.Helper()
+	vcl = defaultBackend(newTestOrigin(t, handler)) + "\n" + vcl
+	return New(append([]context.Option{
+		context.WithResolver(resolver.NewStaticResolver("main", vcl)),
+	}, opts...)...)
+}
+
 func assertInterpreter(t *testing.T, vcl string, scope context.Scope, assertions map[string]value.Value, isError bool, opts ...context.Option) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -45,10 +83,11 @@ func assertInterpreter(t *testing.T, vcl string, scope context.Scope, assertions
 	allOpts := append([]context.Option{context.WithResolver(
 		resolver.NewStaticResolver("main", vcl),
 	)}, opts...)
-	ip := New(allOpts...)
+	// Process with request scoped interpreter in order to inspect request states after processing
+	ip := New(allOpts...).clone()
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
-	ip.ServeHTTP(rec, req)
+	ip.serveHTTP(rec, req)
 
 	if rec.Result().StatusCode != 200 {
 		if !isError {
@@ -215,3 +254,1211 @@ func TestSyntheticAfterRestart(t *testing.T) {
 		}, false)
 	})
 }
+
+func TestMockBackends(t *testing.T) {
+	vcl := `
+      sub vcl_recv {
+        return (pass);
+      }
+    `
+	t.Run("Mocked by exact backend name", func(t *testing.T) {
+		assertInterpreter(t, vcl, context.DeliverScope, map[string]value.Value{
+			"resp.status":        &value.Integer{Value: 404},
+			"resp.http.X-Mocked": &value.String{Value: "yes"},
+		}, false, context.WithMockBackends(map[string]*config.MockBackend{
+			"example": {Status: 404, Headers: map[string]string{"X-Mocked": "yes"}, Body: "Not Found"},
+		}))
+	})
+	t.Run("Mocked by glob pattern", func(t *testing.T) {
+		assertInterpreter(t, vcl, context.DeliverScope, map[string]value.Value{
+			"resp.status": &value.Integer{Value: 503},
+		}, false, context.WithMockBackends(map[string]*config.MockBackend{
+			"ex*": {Status: 503},
+		}))
+	})
+	t.Run("Longest glob pattern is used", func(t *testing.T) {
+		// Map iteration order is random, so try several times.
+		// "*ple" is used because it is lexically less than "exa*" which has the same length
+		for range 10 {
+			assertInterpreter(t, vcl, context.DeliverScope, map[string]value.Value{
+				"resp.status": &value.Integer{Value: 504},
+			}, false, context.WithMockBackends(map[string]*config.MockBackend{
+				"*":    {Status: 501},
+				"ex*":  {Status: 502},
+				"exa*": {Status: 503},
+				"*ple": {Status: 504},
+			}))
+		}
+	})
+	t.Run("Not mocked for unmatched backend", func(t *testing.T) {
+		assertInterpreter(t, vcl, context.DeliverScope, map[string]value.Value{
+			"resp.status": &value.Integer{Value: 200},
+		}, false, context.WithMockBackends(map[string]*config.MockBackend{
+			"other": {Status: 503},
+		}))
+	})
+}
+
+func TestTestProcessRequest(t *testing.T) {
+	vcl := `
+sub vcl_recv {
+  return (pass);
+}`
+	ip := New(context.WithResolver(resolver.NewStaticResolver("main", vcl)))
+	if err := ip.TestProcessInit(fhttp.WrapRequest(httptest.NewRequest(http.MethodGet, "http://localhost", nil))); err != nil {
+		t.Fatalf("Unexpected TestProcessInit error: %s", err)
+	}
+
+	// Testing subroutine could be called in the deep call stack
+	stack := make([]*ast.SubroutineDeclaration, maxCallStackExceedCount)
+	ip.callStack = stack
+	for range 2 {
+		_, err := ip.TestProcessRequest(fhttp.WrapRequest(httptest.NewRequest(http.MethodGet, "http://localhost", nil)))
+		if err != nil {
+			t.Fatalf("Request must be processed with new call stack: %s", err)
+		}
+	}
+	if len(ip.callStack) != len(stack) {
+		t.Errorf("Call stack of the testing subroutine must be restored, got %d", len(ip.callStack))
+	}
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	delay := 200 * time.Millisecond
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK")) // nolint:errcheck
+	})
+	vcl := `
+penaltybox banned {}
+ratecounter counter {}
+
+sub vcl_recv {
+  set req.http.X-Count = ratelimit.ratecounter_increment(counter, client.ip, 1);
+  if (ratelimit.penaltybox_has(banned, client.ip)) {
+    error 429;
+  }
+  if (req.url == "/ban") {
+    ratelimit.penaltybox_add(banned, client.ip, 1m);
+  }
+}
+`
+	ip := newTestInterpreter(t, origin, vcl)
+
+	// Slow origin must not block other requests
+	concurrency := 10
+	start := time.Now()
+	var wg sync.WaitGroup
+	codes := make([]int, concurrency)
+	for n := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			ip.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost/%d", n), nil))
+			codes[n] = rec.Result().StatusCode
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed >= delay*time.Duration(concurrency) {
+		t.Errorf("Requests seem to be processed sequentially, elapsed %s", elapsed)
+	}
+	for n := range codes {
+		if codes[n] != http.StatusOK {
+			t.Errorf("Request %d responds unexpected status code %d", n, codes[n])
+		}
+	}
+
+	// Cache, ratecounters and penaltyboxes are shared between requests
+	rec := httptest.NewRecorder()
+	ip.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost/0", nil))
+	if !strings.Contains(rec.Body.String(), `"x-cache": "HIT"`) {
+		t.Errorf("Expected cache hit, got %s", rec.Body.String())
+	}
+	if rc := ip.ratecounters["counter"]; rc == nil || len(rc.Clients) != 1 || len(rc.Clients["192.0.2.1"]) != concurrency+1 {
+		t.Errorf("Ratecounter is not shared between requests")
+	}
+	ip.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost/ban", nil))
+	if pb := ip.penaltyboxes["banned"]; pb == nil || !pb.Has("192.0.2.1") {
+		t.Errorf("Penaltybox is not shared between requests")
+	}
+}
+
+func TestSharedStateDeclarationChanged(t *testing.T) {
+	parse := func(vcl string) (*ast.RatecounterDeclaration, *ast.PenaltyboxDeclaration) {
+		parsed, err := parser.New(lexer.NewFromString(vcl)).ParseVCL()
+		if err != nil {
+			t.Fatalf("Failed to parse VCL: %s", err)
+		}
+		return parsed.Statements[0].(*ast.RatecounterDeclaration), parsed.Statements[1].(*ast.PenaltyboxDeclaration)
+	}
+
+	s := newSharedState()
+	rc, pb := parse("ratecounter counter {}\npenaltybox banned {}")
+	rate, box := s.ratecounter(rc), s.penaltybox(pb)
+
+	// VCL is parsed for each request so the same declaration is parsed again
+	rc, pb = parse("ratecounter counter {}\npenaltybox banned {}")
+	if s.ratecounter(rc) != rate || s.penaltybox(pb) != box {
+		t.Errorf("Ratecounter and penaltybox must be reused for the same declaration")
+	}
+	// Comments are not a part of the declaration
+	rc, pb = parse("# per client\nratecounter counter { # per client\n}\npenaltybox banned { # per client\n}")
+	if s.ratecounter(rc) != rate || s.penaltybox(pb) != box {
+		t.Errorf("Ratecounter and penaltybox must be reused for the comment-only change")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Surrogate-Key", "all "+strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK")) // nolint:errcheck
+	})
+	vcl := `
+sub vcl_recv {
+  return (lookup);
+}
+`
+	ip := newTestInterpreter(t, origin, vcl)
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		ip.ServeHTTP(rec, r)
+		return rec
+	}
+	isCached := func(path string) bool {
+		rec := serve(httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil))
+		return strings.Contains(rec.Body.String(), `"x-cache": "HIT"`)
+	}
+	purgeAPI := func(path string, headers map[string]string) {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost"+path, nil)
+		req.Header.Set("Fastly-Key", "token")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		if rec := serve(req); rec.Code != http.StatusOK {
+			t.Errorf("Purge API %s responds unexpected status code %d", path, rec.Code)
+		}
+	}
+	warmup := func(paths ...string) {
+		for _, p := range paths {
+			isCached(p)
+			if !isCached(p) {
+				t.Fatalf("%s is expected to be cached", p)
+			}
+		}
+	}
+
+	t.Run("single URL purge", func(t *testing.T) {
+		warmup("/foo", "/bar")
+		rec := serve(httptest.NewRequest("FASTLYPURGE", "http://localhost/foo", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("Unexpected status code %d", rec.Code)
+		}
+		if isCached("/foo") {
+			t.Errorf("/foo should be purged")
+		}
+		if !isCached("/bar") {
+			t.Errorf("/bar should not be purged")
+		}
+	})
+
+	t.Run("soft purge", func(t *testing.T) {
+		warmup("/foo")
+		req := httptest.NewRequest("FASTLYPURGE", "http://localhost/foo", nil)
+		req.Header.Set("Fastly-Soft-Purge", "1")
+		serve(req)
+		if isCached("/foo") {
+			t.Errorf("/foo should be marked as stale")
+		}
+	})
+
+	t.Run("surrogate key purge", func(t *testing.T) {
+		warmup("/foo", "/bar", "/baz")
+		purgeAPI("/service/dummy/purge/foo", nil)
+		if isCached("/foo") {
+			t.Errorf("/foo should be purged")
+		}
+		purgeAPI("/service/dummy/purge", map[string]string{"Surrogate-Key": "bar baz"})
+		if isCached("/bar") || isCached("/baz") {
+			t.Errorf("/bar and /baz should be purged")
+		}
+	})
+
+	t.Run("purge all", func(t *testing.T) {
+		warmup("/foo", "/bar")
+		purgeAPI("/service/dummy/purge_all", nil)
+		if isCached("/foo") || isCached("/bar") {
+			t.Errorf("All objects should be purged")
+		}
+	})
+}
+
+func TestStaleObject(t *testing.T) {
+	var version, failing atomic.Int64
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() > 0 {
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=60, stale-if-error=60")
+		w.Header().Set("X-Version", fmt.Sprint(version.Add(1)))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK")) // nolint:errcheck
+	})
+	vcl := `
+sub vcl_recv {
+  if (req.http.No-SWR) {
+    set req.max_stale_while_revalidate = 0s;
+  }
+  return (lookup);
+}
+
+sub vcl_hit {
+  set req.http.X-SWR = obj.stale_while_revalidate;
+}
+
+sub vcl_deliver {
+  set resp.http.X-SWR = req.http.X-SWR;
+  if (resp.stale) {
+    set resp.http.X-Stale = "1";
+  }
+  if (resp.stale.is_error) {
+    set resp.http.X-Stale-Error = "1";
+  }
+  if (resp.stale.is_revalidating) {
+    set resp.http.X-Stale-Revalidating = "1";
+  }
+}
+`
+	ip := newTestInterpreter(t, origin, vcl)
+	serve := func(headers ...string) string {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+		for _, h := range headers {
+			req.Header.Set(h, "1")
+		}
+		rec := httptest.NewRecorder()
+		ip.ServeHTTP(rec, req)
+		ip.revalidations.Wait()
+		return rec.Body.String()
+	}
+	softPurge := func() {
+		req := httptest.NewRequest("FASTLYPURGE", "http://localhost/", nil)
+		req.Header.Set("Fastly-Soft-Purge", "1")
+		ip.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assertContains := func(body string, expects ...string) {
+		t.Helper()
+		for _, e := range expects {
+			if !strings.Contains(body, e) {
+				t.Errorf("Response should contain %s, got %s", e, body)
+			}
+		}
+	}
+
+	serve()
+	assertContains(serve(), `"x-cache": "HIT"`, `"x-version": "1"`, `"x-swr": "60.000"`)
+
+	// Stale object is served while revalidating, and then revalidated object is served
+	softPurge()
+	assertContains(serve(), `"x-version": "1"`, `"x-stale": "1"`, `"x-stale-revalidating": "1"`)
+	assertContains(serve(), `"x-cache": "HIT"`, `"x-version": "2"`)
+
+	// Stale object is served if the origin fails
+	softPurge()
+	failing.Store(1)
+	assertContains(serve("No-SWR"), `"x-version": "2"`, `"x-stale": "1"`, `"x-stale-error": "1"`)
+}
+
+func TestCacheVariants(t *testing.T) {
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept-Encoding")
+		w.Header().Set("X-Encoding", r.Header.Get("Accept-Encoding"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK")) // nolint:errcheck
+	})
+	vcl := `
+sub vcl_recv {
+  return (lookup);
+}
+`
+	ip := newTestInterpreter(t, origin, vcl)
+
+	type lookup struct {
+		Result   string `json:"result"`
+		Variant  string `json:"variant"`
+		Variants []struct {
+			Matched bool `json:"matched"`
+		} `json:"variants"`
+	}
+	serve := func(encoding string) lookup {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		rec := httptest.NewRecorder()
+		ip.ServeHTTP(rec, req)
+
+		var out struct {
+			Lookups []lookup `json:"lookups"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("Failed to decode process JSON: %s", err)
+		}
+		if len(out.Lookups) != 1 {
+			t.Fatalf("Expected 1 lookup trace, got %d", len(out.Lookups))
+		}
+		return out.Lookups[0]
+	}
+
+	if v := serve("gzip"); v.Result != "MISS" || len(v.Variants) != 0 {
+		t.Errorf("Unexpected lookup: %+v", v)
+	}
+	if v := serve("br"); v.Result != "MISS" || len(v.Variants) != 1 || v.Variants[0].Matched {
+		t.Errorf("Different variant must not be hit: %+v", v)
+	}
+	if v := serve("gzip"); v.Result != "HIT" || v.Variant != "accept-encoding=gzip" || len(v.Variants) != 2 {
+		t.Errorf("Unexpected lookup: %+v", v)
+	}
+}
+
+func TestRequestCollapsing(t *testing.T) {
+	var fetches atomic.Int64
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "private")
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK")) // nolint:errcheck
+	})
+	vcl := `
+sub vcl_recv {
+  if (req.http.Ignore-Busy) {
+    set req.hash_ignore_busy = true;
+  }
+  return (lookup);
+}
+`
+	ip := newTestInterpreter(t, origin, vcl)
+	concurrent := func(path string, ignoreBusy bool) []string {
+		var wg sync.WaitGroup
+		bodies := make([]string, 5)
+		for n := range bodies {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+				if ignoreBusy {
+					req.Header.Set("Ignore-Busy", "1")
+				}
+				rec := httptest.NewRecorder()
+				ip.ServeHTTP(rec, req)
+				bodies[n] = rec.Body.String()
+			}()
+		}
+		wg.Wait()
+		return bodies
+	}
+
+	bodies := concurrent("/collapse", false)
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("Concurrent misses should be collapsed to one fetch, got %d fetches", n)
+	}
+	var collapsed int
+	for _, body := range bodies {
+		if strings.Contains(body, `"collapsed": 1`) {
+			collapsed++
+		}
+	}
+	if collapsed != 4 {
+		t.Errorf("Expected 4 requests wait for the busy object, got %d", collapsed)
+	}
+
+	fetches.Store(0)
+	concurrent("/ignore-busy", true)
+	if n := fetches.Load(); n != 5 {
+		t.Errorf("Requests which ignore busy object should fetch independently, got %d fetches", n)
+	}
+
+	// Waiting requests fetch on their own at once when the fetched object is not cached
+	fetches.Store(0)
+	start := time.Now()
+	concurrent("/private", false)
+	if n := fetches.Load(); n != 5 {
+		t.Errorf("Uncacheable object should be fetched by each request, got %d fetches", n)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Waiting requests should not be collapsed again on uncacheable object, took %s", elapsed)
+	}
+
+	// Waiting request gives up when the request is canceled
+	fetches.Store(0)
+	release, _ := ip.cache.Acquire("http://localhost/canceled")
+	defer release(true)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/canceled", nil).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		ip.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Waiting request should return when the request is canceled")
+	}
+	if n := fetches.Load(); n != 0 {
+		t.Errorf("Canceled request should not fetch the backend, got %d fetches", n)
+	}
+}
+
+func TestRangeRequest(t *testing.T) {
+	var ranges []string
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("abcdefghijklmnopqrstuvwxyz")) // nolint:errcheck
+	})
+	vcl := `
+sub vcl_recv {
+  if (req.url ~ "^/pass") {
+    if (req.http.Range-On-Pass) {
+      set req.enable_range_on_pass = true;
+    }
+    return (pass);
+  }
+  return (lookup);
+}
+`
+	ip := newTestInterpreter(
+		t, origin, vcl,
+		context.WithActualResponse(true),
+	)
+	serve := func(path, rangeHeader string, headers ...string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+		req.Header.Set("Range", rangeHeader)
+		for _, h := range headers {
+			req.Header.Set(h, "1")
+		}
+		rec := httptest.NewRecorder()
+		ip.ServeHTTP(rec, req)
+		// Actual response is written as raw HTTP message
+		resp, err := http.ReadResponse(bufio.NewReader(rec.Body), req)
+		if err != nil {
+			t.Fatalf("Failed to read response: %s", err)
+		}
+		return resp
+	}
+	body := func(resp *http.Response) string {
+		defer resp.Body.Close()
+		var buf strings.Builder
+		if _, err := io.Copy(&buf, resp.Body); err != nil {
+			t.Fatalf("Failed to read response body: %s", err)
+		}
+		return buf.String()
+	}
+
+	resp := serve("/", "bytes=0-4")
+	if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("Content-Range") != "bytes 0-4/26" {
+		t.Errorf("Unexpected range response: %d %s", resp.StatusCode, resp.Header.Get("Content-Range"))
+	}
+	if v := body(resp); v != "abcde" {
+		t.Errorf("Unexpected range body: %s", v)
+	}
+	if ranges[0] != "" {
+		t.Errorf("Range header must not be sent to the backend on cache miss, got %s", ranges[0])
+	}
+
+	// Cached object is ranged
+	resp = serve("/", "bytes=-3")
+	if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("X-Cache") != "HIT" {
+		t.Errorf("Unexpected range response: %d %s", resp.StatusCode, resp.Header.Get("X-Cache"))
+	}
+	if v := body(resp); v != "xyz" {
+		t.Errorf("Unexpected suffix range body: %s", v)
+	}
+
+	resp = serve("/", "bytes=0-1, 24-")
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Multiple ranges should be responded as multipart/byteranges, got %s", resp.Header.Get("Content-Type"))
+	}
+	reader := multipart.NewReader(resp.Body, params["boundary"])
+	for _, expect := range []struct{ contentRange, body string }{
+		{contentRange: "bytes 0-1/26", body: "ab"},
+		{contentRange: "bytes 24-25/26", body: "yz"},
+	} {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("Failed to read multipart: %s", err)
+		}
+		b, _ := io.ReadAll(part) // nolint:errcheck
+		if part.Header.Get("Content-Range") != expect.contentRange || string(b) != expect.body {
+			t.Errorf("Unexpected part: %s %s", part.Header.Get("Content-Range"), string(b))
+		}
+	}
+
+	resp = serve("/", "bytes=100-")
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable || resp.Header.Get("Content-Range") != "bytes */26" {
+		t.Errorf("Unexpected unsatisfiable response: %d %s", resp.StatusCode, resp.Header.Get("Content-Range"))
+	}
+
+	// Range request is proxied to the backend on pass unless req.enable_range_on_pass is set
+	ranges = nil
+	if resp = serve("/pass", "bytes=0-4"); resp.StatusCode != http.StatusOK || ranges[0] != "bytes=0-4" {
+		t.Errorf("Range request must be proxied on pass: %d %s", resp.StatusCode, ranges[0])
+	}
+	if resp = serve("/pass", "bytes=0-4", "Range-On-Pass"); resp.StatusCode != http.StatusPartialContent || ranges[1] != "" {
+		t.Errorf("Range request must be processed on pass: %d %s", resp.StatusCode, ranges[1])
+	}
+}
+
+func TestSegmentedCaching(t *testing.T) {
+	var ranges []string
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader("abcdefghijklmnopqrstuvwxyz"))
+	})
+	vcl := `
+sub vcl_recv {
+  set req.enable_segmented_caching = true;
+  set segmented_caching.block_size = 10;
+  return (lookup);
+}
+
+sub vcl_log {
+  log "segments:" segmented_caching.is_outer_req ":" segmented_caching.completed ":"
+    segmented_caching.total_blocks ":" segmented_caching.obj.complete_length ":"
+    segmented_caching.rounded_req.range_low "-" segmented_caching.rounded_req.range_high ":"
+    segmented_caching.client_req.range_low "-" segmented_caching.client_req.range_high;
+}
+`
+	ip := newTestInterpreter(t, origin, vcl)
+	serve := func(rangeHeader string) (string, string) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+		req.Header.Set("Range", rangeHeader)
+		rec := httptest.NewRecorder()
+		ip.ServeHTTP(rec, req)
+
+		var out struct {
+			Logs []struct {
+				Message string `json:"message"`
+			} `json:"logs"`
+			ClientResponse struct {
+				Headers map[string]string `json:"headers"`
+			} `json:"client_response"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("Failed to decode process JSON: %s", err)
+		}
+		if len(out.Logs) != 1 {
+			t.Fatalf("Expected 1 log, got %d", len(out.Logs))
+		}
+		return out.Logs[0].Message, out.ClientResponse.Headers["content-range"]
+	}
+
+	// Only blocks which cover the requested range are fetched
+	log, contentRange := serve("bytes=12-15")
+	if log != "segments:1:1:3:26:10-19:12-15" || contentRange != "bytes 12-15/26" {
+		t.Errorf("Unexpected segmented caching result: %s %s", log, contentRange)
+	}
+	if diff := cmp.Diff([]string{"bytes=10-19"}, ranges); diff != "" {
+		t.Errorf("Unexpected block requests, diff=%s", diff)
+	}
+
+	// Cached block is reused and only missing blocks are fetched
+	ranges = nil
+	log, contentRange = serve("bytes=5-")
+	if log != "segments:1:1:3:26:0-25:5--1" || contentRange != "bytes 5-25/26" {
+		t.Errorf("Unexpected segmented caching result: %s %s", log, contentRange)
+	}
+	if diff := cmp.Diff([]string{"bytes=0-9", "bytes=20-29"}, ranges); diff != "" {
+		t.Errorf("Unexpected block requests, diff=%s", diff)
+	}
+
+	ranges = nil
+	serve("bytes=0-25")
+	if len(ranges) != 0 {
+		t.Errorf("All blocks should be served from the cache, got requests %v", ranges)
+	}
+}
+
+func TestESI(t *testing.T) {
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		level := r.Header.Get("X-ESI-Level")
+		switch r.URL.Path {
+		case "/page":
+			w.Write([]byte( // nolint:errcheck
+				`<html><esi:include src="/header"/>|<esi:include src="/missing" alt="/alt"/>|` +
+					`<esi:include src="/missing" onerror="continue"/>|<esi:include src="nested"/>` +
+					`<esi:remove>REMOVED</esi:remove><!--esi |COMMENT--><esi:comment text="x"/>`,
+			))
+		case "/header":
+			w.Write([]byte("HEADER" + level)) // nolint:errcheck
+		case "/alt":
+			w.Write([]byte("ALT" + level)) // nolint:errcheck
+		case "/nested":
+			w.Write([]byte(`N` + level + `<esi:include src="/header"/>`)) // nolint:errcheck
+		case "/loop":
+			w.Write([]byte(`L` + level + `<esi:include src="/loop" onerror="continue"/>`)) // nolint:errcheck
+		case "/broken":
+			w.Write([]byte(`A<esi:include src="/missing"/>B`)) // nolint:errcheck
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	vcl := defaultBackend(newTestOrigin(t, origin)) + `
+sub vcl_recv {
+  set req.http.X-ESI-Level = req.esi_level;
+  return (lookup);
+}
+
+sub vcl_hash {
+  set req.hash += req.http.X-ESI-Level;
+}
+
+sub vcl_fetch {
+  esi;
+}
+`
+	rslv := &countingResolver{Resolver: resolver.NewStaticResolver("main", vcl)}
+	ip := New(
+		context.WithResolver(rslv),
+		context.WithActualResponse(true),
+	)
+	serve := func(path string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+		rec := httptest.NewRecorder()
+		ip.ServeHTTP(rec, req)
+		resp, err := http.ReadResponse(bufio.NewReader(rec.Body), req)
+		if err != nil {
+			t.Fatalf("Failed to read response: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body) // nolint:errcheck
+		return resp.StatusCode, string(body)
+	}
+
+	expect := "<html>HEADER1|ALT1||N1HEADER2 |COMMENT"
+	if _, body := serve("/page"); body != expect {
+		t.Errorf("Unexpected ESI result, expect=%s, got=%s", expect, body)
+	}
+	if n := rslv.parsed.Load(); n != 1 {
+		t.Errorf("ESI includes must reuse the parsed VCL, main VCL is resolved %d times", n)
+	}
+	for range 2 {
+		// Second request is served from the cache and ESI is still processed
+		if _, body := serve("/page"); body != expect {
+			t.Errorf("Unexpected ESI result, expect=%s, got=%s", expect, body)
+		}
+	}
+	if _, body := serve("/loop"); body != "L0L1L2L3L4L5" {
+		t.Errorf("ESI include must be limited by the depth, got=%s", body)
+	}
+	if status, body := serve("/broken"); status != http.StatusOK || body != "A" {
+		t.Errorf("Response must be truncated at the failed ESI include, got=%d %s", status, body)
+	}
+}
+
+// Resolver which counts how many times the main VCL is resolved
+type countingResolver struct {
+	resolver.Resolver
+	parsed atomic.Int32
+}
+
+func (r *countingResolver) MainVCL() (*resolver.VCL, error) {
+	r.parsed.Add(1)
+	return r.Resolver.MainVCL()
+}
+
+func TestShielding(t *testing.T) {
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("X-Hops", r.Header.Get("X-Hops"))
+		w.Header().Set("X-FF-Count", fmt.Sprint(len(strings.Split(r.Header.Get("Fastly-FF"), ","))))
+		w.Write([]byte("OK")) // nolint:errcheck
+	})
+	vcl := `
+director ssl_shield_shield_pop shield {
+  .shield = "shield-pop";
+  .is_ssl = true;
+}
+
+sub vcl_recv {
+  set req.backend = fastly.try_select_shield(ssl_shield_shield_pop, example);
+  return (lookup);
+}
+
+sub vcl_miss {
+  declare local var.hop STRING;
+  set var.hop = server.datacenter "/" if(req.is_ssl, "ssl", "plain") "/" fastly.ff.visits_this_service;
+  if (bereq.http.X-Hops) {
+    set bereq.http.X-Hops = bereq.http.X-Hops ", " var.hop;
+  } else {
+    set bereq.http.X-Hops = var.hop;
+  }
+}
+`
+	ip := newTestInterpreter(
+		t, origin, vcl,
+		context.WithActualResponse(true),
+		context.WithShielding(&config.ShieldingConfig{Edge: "edge-pop", Shield: "shield-pop"}),
+	)
+	serve := func() *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/shield", nil)
+		rec := httptest.NewRecorder()
+		ip.ServeHTTP(rec, req)
+		resp, err := http.ReadResponse(bufio.NewReader(rec.Body), req)
+		if err != nil {
+			t.Fatalf("Failed to read response: %s", err)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		expect string
+	}{
+		{name: "hops", header: "X-Hops", expect: "edge-pop/plain/1, shield-pop/ssl/2"},
+		{name: "Fastly-FF entries", header: "X-FF-Count", expect: "2"},
+		{name: "served by", header: "X-Served-By", expect: "cache-localsimulator-shield-pop, cache-localsimulator-edge-pop"},
+		{name: "cache state", header: "X-Cache", expect: "MISS, MISS"},
+	}
+	resp := serve()
+	for _, tt := range tests {
+		if v := resp.Header.Get(tt.header); v != tt.expect {
+			t.Errorf("%s: unexpected %s header, expect=%s, got=%s", tt.name, tt.header, tt.expect, v)
+		}
+	}
+
+	// Second request hits the edge cache which stores the response of the shield POP
+	resp = serve()
+	if v := resp.Header.Get("X-Cache"); v != "MISS, HIT" {
+		t.Errorf("Unexpected X-Cache header on the edge hit, expect=MISS, HIT, got=%s", v)
+	}
+}
+
+func TestSetupFastlyHeaders(t *testing.T) {
+	req, err := fhttp.NewRequest(http.MethodGet, "http://localhost", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %s", err)
+	}
+	// Fastly-FF may be sent as multiple header lines
+	req.Header.Add("Fastly-FF", "first")
+	req.Header.Add("Fastly-FF", "second")
+	setupFastlyHeaders(req, "EDGE")
+
+	ff := req.Header.Values("Fastly-FF")
+	if len(ff) != 1 {
+		t.Fatalf("Fastly-FF must be a single header line, got %d", len(ff))
+	}
+	entries := strings.Split(ff[0], ", ")
+	if diff := cmp.Diff([]string{"first", "second"}, entries[:2]); diff != "" {
+		t.Errorf("Fastly-FF entries mismatch, diff=%s", diff)
+	}
+	if len(entries) != 3 || !strings.Contains(entries[2], "!EDGE!") {
+		t.Errorf("Fastly-FF must be chained with the entry of the node, got %s", ff[0])
+	}
+}
+
+func TestBackendProbe(t *testing.T) {
+	var failing atomic.Bool
+	var probed atomic.Int64
+	p := newTestOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			probed.Add(1)
+			if r.Host != "probe.example.com" || failing.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte("primary")) // nolint:errcheck
+	})
+	s := newTestOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secondary")) // nolint:errcheck
+	})
+	vcl := fmt.Sprintf(`
+backend primary {
+  .host = "%s";
+  .port = "%s";
+  .probe = {
+    .request = "GET /health HTTP/1.1" "Host: probe.example.com" "Connection: close";
+    .expected_response = 204;
+    .interval = 10ms;
+    .window = 2;
+    .threshold = 2;
+  }
+}
+
+backend secondary {
+  .host = "%s";
+  .port = "%s";
+}
+
+director failover fallback {
+  { .backend = primary; }
+  { .backend = secondary; }
+}
+
+sub vcl_recv {
+  set req.backend = failover;
+  return (pass);
+}
+
+sub vcl_deliver {
+  set resp.http.X-Primary-Healthy = if(backend.primary.healthy, "1", "0");
+}
+`, p.Hostname(), p.Port(), s.Hostname(), s.Port())
+
+	ip := New(
+		context.WithResolver(resolver.NewStaticResolver("main", vcl)),
+		context.WithActualResponse(true),
+		context.WithBackendProbe(true),
+	)
+	defer ip.Close()
+	serve := func() (string, string) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+		rec := httptest.NewRecorder()
+		ip.ServeHTTP(rec, req)
+		resp, err := http.ReadResponse(bufio.NewReader(rec.Body), req)
+		if err != nil {
+			t.Fatalf("Failed to read response: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body) // nolint:errcheck
+		return string(body), resp.Header.Get("X-Primary-Healthy")
+	}
+	waitHealth := func(healthy bool) {
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) {
+			ip.mu.Lock()
+			probe := ip.probes["primary"]
+			ip.mu.Unlock()
+			if probe != nil && probe.healthy.Load() == healthy {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Backend health did not change to %t", healthy)
+	}
+
+	// Backend starts as healthy from the initial value
+	if body, healthy := serve(); body != "primary" || healthy != "1" {
+		t.Errorf("Request must be sent to the healthy primary backend, got body=%s, healthy=%s", body, healthy)
+	}
+
+	failing.Store(true)
+	waitHealth(false)
+	if body, healthy := serve(); body != "secondary" || healthy != "0" {
+		t.Errorf("Director must fall back to the secondary backend, got body=%s, healthy=%s", body, healthy)
+	}
+
+	failing.Store(false)
+	waitHealth(true)
+	if body, _ := serve(); body != "primary" {
+		t.Errorf("Request must be sent to the recovered primary backend, got body=%s", body)
+	}
+	if probed.Load() == 0 {
+		t.Errorf("Probe request must be sent to the health check path")
+	}
+}
+
+func TestBackendProbePropertyType(t *testing.T) {
+	tests := []string{
+		`.interval = 5;`,
+		`.timeout = "2s";`,
+		`.url = 10;`,
+		`.window = 5s;`,
+		`.threshold = "3";`,
+		`.initial = true;`,
+		`.expected_response = "200";`,
+		`.dummy = "true";`,
+	}
+	for _, tt := range tests {
+		vcl := fmt.Sprintf(`
+backend probed {
+  .host = "example.com";
+  .probe = {
+    %s
+  }
+}
+
+sub vcl_recv {
+  return (pass);
+}`, tt)
+		ip := New(
+			context.WithResolver(resolver.NewStaticResolver("main", vcl)),
+			context.WithBackendProbe(true),
+		)
+		err := ip.ProcessInit(fhttp.WrapRequest(httptest.NewRequest(http.MethodGet, "http://localhost", nil)))
+		ip.Close()
+		if err == nil {
+			t.Errorf("Expected runtime error for probe property %s", tt)
+		}
+	}
+}
+
+func TestBackendProbeLifecycle(t *testing.T) {
+	newProbe := func(definition string) *backendProbe {
+		return &backendProbe{
+			definition: definition,
+			interval:   time.Hour,
+			healthy:    &atomic.Bool{},
+			done:       make(chan struct{}),
+		}
+	}
+	isStopped := func(p *backendProbe) bool {
+		select {
+		case <-p.done:
+			return true
+		default:
+			return false
+		}
+	}
+
+	s := newSharedState()
+	first := s.backendProbe("origin", newProbe("v1"))
+
+	// Shield POP shares the probe of the edge POP
+	if p := s.shield().backendProbe("origin", newProbe("v1")); p != first {
+		t.Errorf("Shield POP must share the running probe")
+	}
+	if len(s.shield().probes) != 0 {
+		t.Errorf("Shield POP must not start its own probes")
+	}
+
+	// Redefined backend restarts the probe
+	second := s.backendProbe("origin", newProbe("v2"))
+	if !isStopped(first) || isStopped(second) {
+		t.Errorf("Probe of the redefined backend must be restarted")
+	}
+
+	// Probe of the removed backend is stopped
+	s.pruneProbes(map[string]*value.Backend{})
+	if !isStopped(second) || len(s.probes) != 0 {
+		t.Errorf("Probe of the removed backend must be stopped")
+	}
+
+	third := s.backendProbe("origin", newProbe("v3"))
+	s.stopProbes()
+	if !isStopped(third) {
+		t.Errorf("Probe must be stopped when the interpreter is closed")
+	}
+}
+
+func TestBackendTimeout(t *testing.T) {
+	origin := newTestOrigin(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("OK")) // nolint:errcheck
+	})
+	vcl := fmt.Sprintf(`
+backend example {
+  .host = "%s";
+  .port = "%s";
+  .first_byte_timeout = 50ms;
+}
+
+sub vcl_recv {
+  return (pass);
+}
+
+sub vcl_pass {
+  set req.http.X-Default-Timeout = bereq.first_byte_timeout;
+  if (req.url == "/override") {
+    set bereq.first_byte_timeout = 1s;
+  }
+}
+
+sub vcl_error {
+  set obj.http.X-Fastly-Error = fastly.error;
+  set obj.http.X-Default-Timeout = req.http.X-Default-Timeout;
+}
+`, origin.Hostname(), origin.Port())
+
+	ip := New(
+		context.WithResolver(resolver.NewStaticResolver("main", vcl)),
+		context.WithActualResponse(true),
+	)
+	serve := func(path string) (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+		rec := httptest.NewRecorder()
+		ip.ServeHTTP(rec, req)
+		resp, err := http.ReadResponse(bufio.NewReader(rec.Body), req)
+		if err != nil {
+			t.Fatalf("Failed to read response: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body) // nolint:errcheck
+		return resp, string(body)
+	}
+
+	resp, body := serve("/")
+	if resp.StatusCode != http.StatusServiceUnavailable || body != "first byte timeout" {
+		t.Errorf("Timed out request must be responded as 503 from vcl_error, got status=%d, body=%s", resp.StatusCode, body)
+	}
+	if v := resp.Header.Get("X-Fastly-Error"); v != "EFIRSTBYTETIMEOUT" {
+		t.Errorf("Unexpected fastly.error value, got=%s", v)
+	}
+	if v := resp.Header.Get("X-Default-Timeout"); v != "0.050" {
+		t.Errorf("bereq.first_byte_timeout must be the backend value, got=%s", v)
+	}
+
+	if resp, _ := serve("/override"); resp.StatusCode != http.StatusOK {
+		t.Errorf("bereq.first_byte_timeout must override the backend value, got status=%d", resp.StatusCode)
+	}
+}
+
+func TestBackendTimeoutPropertyType(t *testing.T) {
+	tests := []string{
+		`.connect_timeout = "1s";`,
+		`.first_byte_timeout = 15;`,
+		`.between_bytes_timeout = true;`,
+	}
+	for _, tt := range tests {
+		vcl := fmt.Sprintf(`
+backend example {
+  .host = "example.com";
+  %s
+}`, tt)
+		ip := New(context.WithResolver(resolver.NewStaticResolver("main", vcl)))
+		err := ip.ProcessInit(fhttp.WrapRequest(httptest.NewRequest(http.MethodGet, "http://localhost", nil)))
+		if err != nil {
+			t.Fatalf("Unexpected ProcessInit error: %s", err)
+		}
+		if err := ip.setBackendTimeouts(ip.ctx, ip.ctx.Backend.Value.Properties); err == nil {
+			t.Errorf("Expected runtime error for backend property %s", tt)
+		}
+	}
+}
+
+type exceptionDebugger struct {
+	DefaultDebugger
+	exceptions []string
+}
+
+func (d *exceptionDebugger) Exception(node ast.Node, e *exception.Exception) {
+	d.exceptions = append(d.exceptions, fmt.Sprintf("%s: %s", e.Type, e.Message))
+}
+
+func TestExceptionDebugger(t *testing.T) {
+	vcl := `
+sub raise_esi {
+  esi;
+}
+sub vcl_recv {
+  if (req.restarts == 0) {
+    error 601 "oops";
+  }
+  return (pass);
+}
+sub vcl_error {
+  if (req.restarts == 0) {
+    restart;
+  }
+}
+sub vcl_deliver {
+  call raise_esi;
+}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	parsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Errorf("Test server URL parsing error: %s", err)
+		return
+	}
+
+	ip := New(context.WithResolver(
+		resolver.NewStaticResolver("main", defaultBackend(parsed)+"\n"+vcl),
+	))
+	d := &exceptionDebugger{}
+	ip.Debugger = d
+	ip.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	// Runtime exception is notified once even it is propagated through subroutines
+	expects := []string{
+		"ErrorStatement: error 601 oops",
+		"RestartStatement: restart",
+		"RuntimeException: esi statement found but it could only be enable on FETCH directive",
+	}
+	if diff := cmp.Diff(expects, d.exceptions); diff != "" {
+		t.Errorf("Exceptions unmatch, diff=%s", diff)
+	}
+}
+
+type snapshotDebugger struct {
+	DefaultDebugger
+	timeline *Timeline
+}
+
+func (d *snapshotDebugger) Snapshot(s *Snapshot) {
+	d.timeline.Append(s)
+}
+
+func TestSnapshotTimeline(t *testing.T) {
+	vcl := `
+sub vcl_recv {
+  declare local var.count INTEGER;
+  set req.http.X-Step = "1";
+  set var.count = 1;
+  set req.http.X-Step = "2";
+  unset req.http.X-Step;
+  set var.count += 1;
+  return (pass);
+}`
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ip := newTestInterpreter(t, origin, vcl)
+	d := &snapshotDebugger{timeline: NewTimeline()}
+	ip.Debugger = d
+	ip.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	history := d.timeline.History("req.http.x-step")
+	var values []string
+	for _, h := range history {
+		values = append(values, h.Before.String()+" -> "+h.After.String())
+	}
+	expects := []string{"(null) -> 1", "1 -> 2", "2 -> (null)"}
+	if diff := cmp.Diff(expects, values); diff != "" {
+		t.Errorf("Header history unmatch, diff=%s", diff)
+	}
+
+	// Values when the third statement (set var.count = 1) is about to be processed
+	count := d.timeline.ValueAt(2, "var.count", &value.Integer{Value: 2})
+	if diff := cmp.Diff(&value.Integer{Value: 0}, count); diff != "" {
+		t.Errorf("var.count value unmatch, diff=%s", diff)
+	}
+	step := d.timeline.ValueAt(2, "req.http.X-Step", &value.String{IsNotSet: true})
+	if diff := cmp.Diff(&value.String{Value: "1"}, step); diff != "" {
+		t.Errorf("req.http.X-Step value unmatch, diff=%s", diff)
+	}
+
+	// Travel back to the third statement with the cursor
+	current := &value.Integer{Value: 2}
+	if v, traveling := d.timeline.Value("var.count", current); traveling || v != current {
+		t.Errorf("Current value should be returned at the present")
+	}
+	for d.timeline.Cursor() > 2 {
+		if d.timeline.Travel(-1) == nil {
+			t.Fatalf("Timeline should travel back")
+		}
+	}
+	if v, traveling := d.timeline.Value("var.count", current); !traveling || v.String() != "0" {
+		t.Errorf("var.count value at the cursor unmatch, got %s", v)
+	}
+	d.timeline.Present()
+	if d.timeline.Traveling() != nil {
+		t.Errorf("Timeline should be back to the present")
+	}
+}
@@ -39,6 +39,10 @@ const (
 	MaxVarnishRestarts   = 3
 	MaxLogLineSize       = 16 * KB
 
+	// ESI limitations
+	MaxESIDepth    = 5
+	MaxESIIncludes = 256
+
 	// Increasable limitations by contacting Fastly support
 	// These are defaults, you can override by configuration
 	MaxACLCounts     = 1000
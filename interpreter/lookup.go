@@ -0,0 +1,88 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/interpreter/cache"
+)
+
+// Look up the cache and move to HIT or MISS state.
+// Concurrent misses for the same hash are collapsed, only one request fetches the backend
+// and others wait for it and look up the cache again unless req.hash_ignore_busy is set.
+// When the fetched object is not cached, waiting requests fetch on their own at once.
+// see: https://developer.fastly.com/learning/concepts/request-collapsing/
+func (i *Interpreter) lookup() error {
+	hash := i.ctx.RequestHash.Value
+	var collapsed int
+	var pass bool
+
+	for {
+		trace := i.cache.Trace(hash, i.ctx.Request.Header)
+		trace.Collapsed = collapsed
+
+		// req.hash_always_miss forces cache miss, but the fetched object is stored
+		if !i.ctx.HashAlwaysMiss.Value {
+			if v := i.cache.Get(hash, i.ctx.Request.Header); v != nil {
+				i.process.Cached = true
+				i.ctx.State = "HIT"
+				i.setCacheObject(v)
+				i.traceLookup(trace, v)
+				i.Debugger.Message(fmt.Sprintf("Move state: %s -> HIT", i.ctx.Scope))
+				return errors.WithStack(i.ProcessHit())
+			}
+			if v := i.lookupStale(); v != nil && i.canServeWhileRevalidate(v) {
+				// Serve stale object while revalidating in background
+				i.useStaleObject(v, false)
+				i.traceLookup(trace, v)
+				i.revalidate()
+				i.Debugger.Message(fmt.Sprintf("Move state: %s -> HIT (stale)", i.ctx.Scope))
+				return errors.WithStack(i.ProcessHit())
+			}
+
+			if !i.ctx.HashIgnoreBusy.Value && !pass {
+				release, wait := i.cache.Acquire(hash)
+				if wait != nil {
+					i.Debugger.Message(fmt.Sprintf("Request collapsing: wait for the busy object of %s", hash))
+					select {
+					case <-wait.Done():
+					case <-i.ctx.Request.Context().Done():
+						return errors.WithStack(i.ctx.Request.Context().Err())
+					}
+					collapsed++
+					pass = wait.Pass()
+					continue
+				}
+				i.releaseBusy = release
+			}
+		}
+
+		i.ctx.State = "MISS"
+		i.traceLookup(trace, nil)
+		i.Debugger.Message(fmt.Sprintf("Move state: %s -> MISS", i.ctx.Scope))
+		return errors.WithStack(i.ProcessMiss())
+	}
+}
+
+// Release the busy object without caching, waiting requests fetch on their own
+func (i *Interpreter) release() {
+	i.releaseObject(false)
+}
+
+// Release the busy object so that waiting requests look up the cache again if the object is cached
+func (i *Interpreter) releaseObject(cached bool) {
+	if i.releaseBusy != nil {
+		i.releaseBusy(!cached)
+		i.releaseBusy = nil
+	}
+}
+
+// Record cache lookup trace of the request with the object which is used for the response
+func (i *Interpreter) traceLookup(trace *cache.LookupTrace, item *cache.CacheItem) {
+	trace.Result = i.ctx.State
+	if item != nil {
+		trace.Variant = item.Variant
+	}
+	i.process.Lookups = append(i.process.Lookups, trace)
+	i.Debugger.Message(fmt.Sprintf("Cache lookup %s: %d variant(s) found", trace.Result, len(trace.Variants)))
+}
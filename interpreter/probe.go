@@ -0,0 +1,269 @@
+package interpreter
+
+import (
+	gocontext "context"
+	"io"
+	ghttp "net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/interpreter/exception"
+	"github.com/ysugimoto/falco/interpreter/http"
+	"github.com/ysugimoto/falco/interpreter/value"
+)
+
+// Default values of the backend health check
+// see: https://www.fastly.com/documentation/reference/vcl/declarations/backend/#health-checks
+const (
+	defaultProbeInterval         = 5 * time.Second
+	defaultProbeTimeout          = 2 * time.Second
+	defaultProbeWindow           = 5
+	defaultProbeThreshold        = 3
+	defaultProbeExpectedResponse = ghttp.StatusOK
+)
+
+// Expected value types of the probe properties
+var probePropertyTypes = map[string]value.Type{
+	"dummy":             value.BooleanType,
+	"url":               value.StringType,
+	"interval":          value.RTimeType,
+	"timeout":           value.RTimeType,
+	"window":            value.IntegerType,
+	"threshold":         value.IntegerType,
+	"initial":           value.IntegerType,
+	"expected_response": value.IntegerType,
+}
+
+// Health check of the backend which is declared in .probe property.
+// Results of the latest checks are kept in the window,
+// and the backend is healthy while succeeded checks reach the threshold
+type backendProbe struct {
+	// Backend declaration string to detect the probe is changed
+	definition string
+	request    *http.Request
+	send       func(req *http.Request) (*http.Response, error)
+
+	interval  time.Duration
+	timeout   time.Duration
+	window    int
+	threshold int
+	initial   int
+	expected  int
+
+	healthy *atomic.Bool
+	mu      sync.Mutex
+	results []bool
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Create the backend probe from .probe property, returns nil if the backend does not have the probe
+// or the probe is dummy. Mocked backend is not probed because the request is never sent
+func (i *Interpreter) newBackendProbe(decl *ast.BackendDeclaration) (*backendProbe, error) {
+	var probe *ast.BackendProbeObject
+	for _, prop := range decl.Properties {
+		if v, ok := prop.Value.(*ast.BackendProbeObject); ok && prop.Key.Value == "probe" {
+			probe = v
+			break
+		}
+	}
+	if probe == nil {
+		return nil, nil
+	}
+	if mock, err := getMockedBackend(i.ctx, decl.Name.Value); err != nil {
+		return nil, errors.WithStack(err)
+	} else if mock != nil {
+		return nil, nil
+	}
+
+	p := &backendProbe{
+		definition: decl.String(),
+		send:       http.SendRequest,
+		interval:   defaultProbeInterval,
+		timeout:    defaultProbeTimeout,
+		window:     defaultProbeWindow,
+		threshold:  defaultProbeThreshold,
+		initial:    -1,
+		expected:   defaultProbeExpectedResponse,
+		healthy:    &atomic.Bool{},
+		done:       make(chan struct{}),
+	}
+
+	var lines []string
+	path := "/"
+	for _, prop := range probe.Values {
+		if prop.Key.Value == "request" {
+			lines = probeRequestLines(prop.Value)
+			continue
+		}
+		v, err := i.ProcessExpression(prop.Value)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if t, ok := probePropertyTypes[prop.Key.Value]; ok && v.Type() != t {
+			return nil, exception.Runtime(
+				&prop.GetMeta().Token,
+				"Probe property .%s of backend %s must be %s type, got %s",
+				prop.Key.Value,
+				decl.Name.Value,
+				t,
+				v.Type(),
+			)
+		}
+		switch prop.Key.Value {
+		case "dummy":
+			if value.Unwrap[*value.Boolean](v).Value {
+				return nil, nil
+			}
+		case "url":
+			path = value.Unwrap[*value.String](v).Value
+		case "interval":
+			p.interval = value.Unwrap[*value.RTime](v).Value
+		case "timeout":
+			p.timeout = value.Unwrap[*value.RTime](v).Value
+		case "window":
+			p.window = int(value.Unwrap[*value.Integer](v).Value)
+		case "threshold":
+			p.threshold = int(value.Unwrap[*value.Integer](v).Value)
+		case "initial":
+			p.initial = int(value.Unwrap[*value.Integer](v).Value)
+		case "expected_response":
+			p.expected = int(value.Unwrap[*value.Integer](v).Value)
+		}
+	}
+	if p.interval <= 0 || p.window <= 0 {
+		return nil, exception.Runtime(
+			&probe.GetMeta().Token,
+			"Probe of backend %s must have positive interval and window",
+			decl.Name.Value,
+		)
+	}
+	// Backend starts as healthy when the initial is not specified
+	if p.initial < 0 {
+		p.initial = p.threshold
+	}
+
+	origin, err := i.backendOrigin(i.ctx, &value.Backend{Value: decl})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if p.request, err = newProbeRequest(origin.String(), path, lines); err != nil {
+		return nil, exception.Runtime(
+			&probe.GetMeta().Token,
+			"Invalid probe request of backend %s: %s",
+			decl.Name.Value,
+			err,
+		)
+	}
+
+	// Initial checks are treated as succeeded
+	for range min(p.initial, p.window) {
+		p.results = append(p.results, true)
+	}
+	p.healthy.Store(len(p.results) >= p.threshold)
+	return p, nil
+}
+
+// .request value is implicitly concatenated strings like "GET / HTTP/1.1" "Host: example.com",
+// each string is a line of the request
+func probeRequestLines(expr ast.Expression) []string {
+	switch t := expr.(type) {
+	case *ast.String:
+		return []string{t.Value}
+	case *ast.InfixExpression:
+		if t.Operator == "+" && !t.Explicit {
+			return append(probeRequestLines(t.Left), probeRequestLines(t.Right)...)
+		}
+	}
+	return strings.Split(expr.String(), "\r\n")
+}
+
+// Create the probe request from request lines, the request path is used when request lines are not specified
+func newProbeRequest(origin, path string, lines []string) (*http.Request, error) {
+	method := ghttp.MethodGet
+	if len(lines) > 0 {
+		fields := strings.Fields(lines[0])
+		if len(fields) < 2 {
+			return nil, errors.Errorf("malformed request line %q", lines[0])
+		}
+		method, path = fields[0], fields[1]
+		lines = lines[1:]
+	}
+
+	req, err := http.NewRequest(method, origin+path, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, line := range lines {
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, errors.Errorf("malformed header line %q", line)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if strings.EqualFold(key, "Host") {
+			req.Host = val
+		}
+		req.Header.Set(key, val)
+	}
+	return req, nil
+}
+
+// Start checking the backend health in background
+func (p *backendProbe) start() {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.record(p.check())
+			}
+		}
+	}()
+}
+
+// Stop checking the backend health, could be called multiple times
+func (p *backendProbe) stop() {
+	p.once.Do(func() {
+		close(p.done)
+	})
+}
+
+// Check the backend health once, the backend must respond expected status code within the timeout
+func (p *backendProbe) check() bool {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), p.timeout)
+	defer cancel()
+
+	resp, err := p.send(p.request.Clone(ctx))
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, resp.Body) // nolint:errcheck
+	resp.Body.Close()
+	return resp.StatusCode == p.expected
+}
+
+// Record the check result in the window and update the health
+func (p *backendProbe) record(ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.results = append(p.results, ok)
+	if len(p.results) > p.window {
+		p.results = p.results[len(p.results)-p.window:]
+	}
+	var succeeded int
+	for _, v := range p.results {
+		if v {
+			succeeded++
+		}
+	}
+	p.healthy.Store(succeeded >= p.threshold)
+}
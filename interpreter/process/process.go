@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ysugimoto/falco/interpreter/cache"
 	"github.com/ysugimoto/falco/interpreter/http"
 	"github.com/ysugimoto/falco/interpreter/value"
 )
@@ -17,6 +18,7 @@ type Process struct {
 	Restarts  int
 	Backend   *value.Backend
 	Cached    bool
+	Lookups   []*cache.LookupTrace
 	Error     error
 	StartTime int64
 	Response  *http.Response
@@ -26,14 +28,22 @@ func New() *Process {
 	return &Process{
 		Flows:     []*Flow{},
 		Logs:      []*Log{},
+		Lookups:   []*cache.LookupTrace{},
 		StartTime: time.Now().UnixMicro(),
 	}
 }
 
+// Merge records of the inner request like ESI include or shield POP request into this process
+func (p *Process) Merge(inner *Process) {
+	p.Flows = append(p.Flows, inner.Flows...)
+	p.Logs = append(p.Logs, inner.Logs...)
+	p.Lookups = append(p.Lookups, inner.Lookups...)
+}
+
 func (p *Process) Finalize(resp *http.Response) ([]byte, error) {
 	var backend string
 	if p.Backend != nil {
-		backend = p.Backend.Value.Name.Value
+		backend = p.Backend.String()
 	}
 
 	var statusCode int
@@ -62,14 +72,15 @@ func (p *Process) Finalize(resp *http.Response) ([]byte, error) {
 	}
 
 	return json.MarshalIndent(struct {
-		Flows          []*Flow `json:"flows"`
-		Logs           []*Log  `json:"logs"`
-		Restarts       int     `json:"restarts"`
-		Backend        string  `json:"backend"`
-		Cached         bool    `json:"cached"`
-		ElapsedTimeUs  int64   `json:"elapsed_time_us"`
-		ElapsedTimeMs  int64   `json:"elapsed_time_ms"`
-		Error          string  `json:"error,omitempty"`
+		Flows          []*Flow              `json:"flows"`
+		Logs           []*Log               `json:"logs"`
+		Restarts       int                  `json:"restarts"`
+		Backend        string               `json:"backend"`
+		Cached         bool                 `json:"cached"`
+		Lookups        []*cache.LookupTrace `json:"lookups"`
+		ElapsedTimeUs  int64                `json:"elapsed_time_us"`
+		ElapsedTimeMs  int64                `json:"elapsed_time_ms"`
+		Error          string               `json:"error,omitempty"`
 		ClientResponse struct {
 			StatusCode    int               `json:"status_code"`
 			ResponseBytes int               `json:"body_bytes"`
@@ -80,7 +91,8 @@ func (p *Process) Finalize(resp *http.Response) ([]byte, error) {
 		Logs:          p.Logs,
 		Restarts:      p.Restarts,
 		Backend:       backend,
-		Cached:        false,
+		Cached:        p.Cached,
+		Lookups:       p.Lookups,
 		ElapsedTimeUs: time.Now().UnixMicro() - p.StartTime,
 		ElapsedTimeMs: time.Now().UnixMilli() - (p.StartTime / 1000),
 		Error:         errMsg,
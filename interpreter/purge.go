@@ -0,0 +1,113 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"fmt"
+	ghttp "net/http"
+	"strings"
+
+	"github.com/ysugimoto/falco/interpreter/http"
+)
+
+// Fastly purge API endpoints
+// see: https://www.fastly.com/documentation/reference/api/purging/
+const (
+	purgeAPIPrefix    = "/service/"
+	purgeIdentifier   = "falco_purge_acceptance"
+	softPurgeHeader   = "Fastly-Soft-Purge"
+	purgeAPIKeyHeader = "Fastly-Key"
+)
+
+// Cache key for purging by URL, combines host and path with query string
+func cacheURL(req *http.Request) string {
+	host := req.Header.Get("Host")
+	if host == "" {
+		host = req.Host
+	}
+	return host + req.URL.RequestURI()
+}
+
+// Surrogate keys are space separated values of Surrogate-Key header
+func surrogateKeys(resp *http.Response) []string {
+	return strings.Fields(resp.Header.Get("Surrogate-Key"))
+}
+
+func isSoftPurge(h ghttp.Header) bool {
+	return h.Get(softPurgeHeader) == "1"
+}
+
+// Purge cached objects of requested URL by FASTLYPURGE method request.
+// Note that the URL is determined after vcl_recv so the request could be modified by the VCL
+func (i *Interpreter) purgeURL() {
+	url := cacheURL(i.ctx.Request)
+	var n int
+	for _, c := range i.caches() {
+		n += c.PurgeURL(url, isSoftPurge(i.ctx.Request.Header))
+	}
+	i.Debugger.Message(fmt.Sprintf("Purged %d object(s) for URL %s", n, url))
+}
+
+// Handle Fastly purge API request if matched.
+// Returns true when the request is handled as purge API request
+func (i *Interpreter) handlePurgeAPI(w ghttp.ResponseWriter, r *ghttp.Request) bool {
+	if r.Method != ghttp.MethodPost || r.Header.Get(purgeAPIKeyHeader) == "" {
+		return false
+	}
+	// Path must be /service/{service_id}/purge_all, /service/{service_id}/purge or /service/{service_id}/purge/{key}
+	if !strings.HasPrefix(r.URL.Path, purgeAPIPrefix) {
+		return false
+	}
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, purgeAPIPrefix), "/")
+	if len(segments) < 2 || segments[0] == "" {
+		return false
+	}
+
+	soft := isSoftPurge(r.Header)
+	switch {
+	case len(segments) == 2 && segments[1] == "purge_all":
+		var n int
+		for _, c := range i.caches() {
+			n += c.PurgeAll()
+		}
+		i.Debugger.Message(fmt.Sprintf("Purged all %d object(s)", n))
+		writePurgeAPIResponse(w, map[string]string{"status": "ok"})
+	case len(segments) == 3 && segments[1] == "purge" && segments[2] != "":
+		n := i.purgeKey(segments[2], soft)
+		i.Debugger.Message(fmt.Sprintf("Purged %d object(s) for surrogate key %s", n, segments[2]))
+		writePurgeAPIResponse(w, map[string]string{"status": "ok", "id": purgeIdentifier})
+	case len(segments) == 2 && segments[1] == "purge":
+		// Batch purge, surrogate keys are specified in Surrogate-Key header
+		keys := strings.Fields(r.Header.Get("Surrogate-Key"))
+		if len(keys) == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(ghttp.StatusBadRequest)
+			w.Write([]byte(`{"msg": "Surrogate-Key header is required"}`)) // nolint:errcheck
+			return true
+		}
+		ids := make(map[string]string, len(keys))
+		for _, key := range keys {
+			n := i.purgeKey(key, soft)
+			i.Debugger.Message(fmt.Sprintf("Purged %d object(s) for surrogate key %s", n, key))
+			ids[key] = purgeIdentifier
+		}
+		writePurgeAPIResponse(w, ids)
+	default:
+		return false
+	}
+	return true
+}
+
+// Purge cached objects by surrogate key on all POPs
+func (i *Interpreter) purgeKey(key string, soft bool) int {
+	var n int
+	for _, c := range i.caches() {
+		n += c.PurgeKey(key, soft)
+	}
+	return n
+}
+
+func writePurgeAPIResponse(w ghttp.ResponseWriter, body map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(ghttp.StatusOK)
+	json.NewEncoder(w).Encode(body) // nolint:errcheck
+}
@@ -0,0 +1,126 @@
+package interpreter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	ghttp "net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/ysugimoto/falco/interpreter/http"
+)
+
+// Remove range related headers from the backend request.
+// Fastly fetches the whole object from the backend and processes Range request by itself
+func stripRangeHeaders(req *http.Request) {
+	req.Header.Del("Range")
+	req.Header.Del("If-Range")
+}
+
+// Process Range request on the delivering response.
+// The response is partial content when the object is assembled by segmented caching,
+// then the Content-Range header tells us the offset and the complete length of the object
+func (i *Interpreter) processRange() {
+	req, resp := i.ctx.Request, i.ctx.Response
+	// ESI processed response could not be ranged because the length is unknown until processing
+	if req.Method != ghttp.MethodGet || i.ctx.IsLocallyGenerated.Value || i.ctx.TriggerESI {
+		return
+	}
+	// Range request is proxied to the backend on pass
+	if i.ctx.IsPassRequest && !i.ctx.EnableRangeOnPass.Value {
+		return
+	}
+	specs, ok := http.ParseRange(req.Header.Get("Range"))
+	if !ok || !matchIfRange(req.Header.Get("If-Range"), resp.Header) {
+		return
+	}
+
+	var offset, size int64
+	switch resp.StatusCode {
+	case ghttp.StatusOK:
+		size = -1
+	case ghttp.StatusPartialContent:
+		r, length, ok := http.ParseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || r.Start < 0 {
+			return
+		}
+		offset, size = r.Start, length
+	default:
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body) // nolint: errcheck
+	body := buf.Bytes()
+	if size < 0 {
+		size = int64(len(body))
+	}
+
+	ranges := http.ResolveRanges(specs, size)
+	if len(ranges) == 0 {
+		i.Debugger.Message(fmt.Sprintf("Range %s is not satisfiable", req.Header.Get("Range")))
+		resp.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		i.writeRangeResponse(ghttp.StatusRequestedRangeNotSatisfiable, nil)
+		return
+	}
+	for _, r := range ranges {
+		// Could not serve the range which is not contained in the response
+		if r.Start < offset || r.End-offset >= int64(len(body)) {
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			return
+		}
+	}
+
+	if len(ranges) == 1 {
+		r := ranges[0]
+		resp.Header.Set("Content-Range", r.ContentRange(size))
+		i.writeRangeResponse(ghttp.StatusPartialContent, body[r.Start-offset:r.End-offset+1])
+		return
+	}
+
+	// Multiple ranges are responded as multipart/byteranges
+	var parts bytes.Buffer
+	w := multipart.NewWriter(&parts)
+	contentType := resp.Header.Get("Content-Type")
+	for _, r := range ranges {
+		h := textproto.MIMEHeader{}
+		if contentType != "" {
+			h.Set("Content-Type", contentType)
+		}
+		h.Set("Content-Range", r.ContentRange(size))
+		pw, _ := w.CreatePart(h)                        // nolint:errcheck
+		pw.Write(body[r.Start-offset : r.End-offset+1]) // nolint:errcheck
+	}
+	w.Close() // nolint:errcheck
+	resp.Header.Del("Content-Range")
+	resp.Header.Set("Content-Type", "multipart/byteranges; boundary="+w.Boundary())
+	i.writeRangeResponse(ghttp.StatusPartialContent, parts.Bytes())
+}
+
+func (i *Interpreter) writeRangeResponse(status int, body []byte) {
+	resp := i.ctx.Response
+	i.Debugger.Message(fmt.Sprintf("Respond range request with status code %d", status))
+	resp.StatusCode = status
+	resp.Status = ghttp.StatusText(status)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", fmt.Sprint(len(body)))
+}
+
+// Range request is processed only if If-Range validator matches the response.
+// Otherwise whole object is responded
+func matchIfRange(v string, h ghttp.Header) bool {
+	if v == "" {
+		return true
+	}
+	// Weak entity tag could not be used for If-Range
+	if strings.HasPrefix(v, `"`) {
+		return v == h.Get("ETag")
+	}
+	if strings.HasPrefix(v, "W/") {
+		return false
+	}
+	return v == h.Get("Last-Modified")
+}
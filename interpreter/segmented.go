@@ -0,0 +1,261 @@
+package interpreter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	ghttp "net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/interpreter/cache"
+	"github.com/ysugimoto/falco/interpreter/cache/cachecontrol"
+	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/exception"
+	"github.com/ysugimoto/falco/interpreter/http"
+	"github.com/ysugimoto/falco/interpreter/value"
+)
+
+// Fixed size block of the object which is fetched by segmented caching
+type segmentBlock struct {
+	number   int64
+	start    int64 // first byte position of the block
+	resp     *http.Response
+	body     []byte
+	length   int64 // complete length of the object
+	cached   bool
+	inRange  bool // false if the block is out of the object
+	rejected bool // true if the backend responds unexpected status code
+}
+
+// Validator of the block to detect the object is changed while fetching blocks
+func (b *segmentBlock) validator() string {
+	if v := b.resp.Header.Get("ETag"); v != "" {
+		return v
+	}
+	return b.resp.Header.Get("Last-Modified")
+}
+
+// Each block is cached with the separated hash
+func segmentHash(hash string, blockSize, n int64) string {
+	return fmt.Sprintf("%s#segment:%d:%d", hash, blockSize, n)
+}
+
+// Fetch the object by fixed size blocks which are cached separately, and assemble the blocks
+// which cover the requested range. Assembled response is partial content when the client requests
+// only a part of the object, and the object itself is not cached.
+// Note that falco does not run inner requests through the VCL, blocks are fetched in the FETCH directive
+func (i *Interpreter) segmentedFetch(backend *value.Backend) (*http.Response, error) {
+	size := i.ctx.SegmentedCacheingBlockSize.Value
+	if size <= 0 {
+		size = context.DefaultSegmentedCachingBlockSize
+	}
+	sc := &context.SegmentedCaching{BlockSize: size, BlockNumber: -1}
+	i.ctx.SegmentedCaching = sc
+
+	// Fetch the first block of the requested range in order to know the complete length of the object
+	specs, isRange := http.ParseRange(i.ctx.Request.Header.Get("Range"))
+	var low int64
+	if isRange {
+		low = rangeLowerBound(specs)
+	}
+	probe, err := i.fetchSegmentBlock(backend, low/size, size)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !probe.inRange && probe.number > 0 {
+		// Requested range is out of the object, fetch the first block for the response
+		if probe, err = i.fetchSegmentBlock(backend, 0, size); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if probe.rejected {
+		return i.failSegmentedFetch(probe), nil
+	}
+
+	length := probe.length
+	start, end := int64(0), length-1
+	if ranges := http.ResolveRanges(specs, length); isRange && len(ranges) > 0 {
+		start, end = ranges[0].Start, ranges[0].End
+		for _, r := range ranges[1:] {
+			start, end = min(start, r.Start), max(end, r.End)
+		}
+	}
+	first, last := start/size, max(end, 0)/size
+
+	sc.CompleteLength = length
+	sc.TotalBlocks = (length + size - 1) / size
+	sc.RoundedRangeLow = first * size
+	sc.RoundedRangeHigh = max(min((last+1)*size, length)-1, 0)
+
+	var body bytes.Buffer
+	cached := true
+	for n := first; n <= last && length > 0; n++ {
+		block := probe
+		if n != probe.number {
+			if block, err = i.fetchSegmentBlock(backend, n, size); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		if block.rejected || !block.inRange {
+			return i.failSegmentedFetch(block), nil
+		}
+		// Object is changed while fetching blocks, purge all blocks of the object
+		if block.validator() != probe.validator() || block.length != length {
+			i.cache.PurgeURL(cacheURL(i.ctx.Request), false)
+			sc.Failed = true
+			sc.Autopurged = true
+			sc.Error = "object changed while fetching segmented blocks"
+			return nil, exception.Runtime(nil, "Segmented caching failed: %s", sc.Error)
+		}
+		sc.BlockNumber = n
+		cached = cached && block.cached
+		body.Write(block.body)
+	}
+	sc.Completed = true
+	if cached && length > 0 {
+		i.Debugger.Message("All segmented blocks are served from the cache")
+	}
+
+	resp := probe.resp.Clone()
+	resp.Header.Del("Content-Range")
+	if first == 0 && sc.RoundedRangeHigh == max(length-1, 0) {
+		resp.StatusCode = ghttp.StatusOK
+	} else {
+		resp.StatusCode = ghttp.StatusPartialContent
+		resp.Header.Set("Content-Range", http.ByteRange{
+			Start: sc.RoundedRangeLow,
+			End:   sc.RoundedRangeHigh,
+		}.ContentRange(length))
+	}
+	resp.Status = ghttp.StatusText(resp.StatusCode)
+	resp.Body = io.NopCloser(bytes.NewReader(body.Bytes()))
+	resp.ContentLength = int64(body.Len())
+	resp.Header.Set("Content-Length", fmt.Sprint(body.Len()))
+	return resp, nil
+}
+
+// Backend response of the failed block is used as the backend response
+func (i *Interpreter) failSegmentedFetch(block *segmentBlock) *http.Response {
+	sc := i.ctx.SegmentedCaching
+	sc.Failed = true
+	sc.BlockNumber = block.number
+	if block.rejected {
+		sc.Error = fmt.Sprintf("backend responded status code %d for block %d", block.resp.StatusCode, block.number)
+	} else {
+		sc.Error = fmt.Sprintf("block %d is out of the object", block.number)
+	}
+	i.Debugger.Message("Segmented caching failed: " + sc.Error)
+	return block.resp
+}
+
+// Lower bound byte position of the requested ranges.
+// Suffix range needs the complete length so it starts from the first byte
+func rangeLowerBound(specs []http.RangeSpec) int64 {
+	low := int64(-1)
+	for _, s := range specs {
+		if s.First < 0 {
+			return 0
+		}
+		if low < 0 || s.First < low {
+			low = s.First
+		}
+	}
+	return max(low, 0)
+}
+
+// Fetch a block from the cache or the backend with Range request
+func (i *Interpreter) fetchSegmentBlock(backend *value.Backend, n, size int64) (*segmentBlock, error) {
+	hash := segmentHash(i.ctx.RequestHash.Value, size, n)
+	if item := i.cache.Get(hash, i.ctx.Request.Header); item != nil {
+		i.Debugger.Message(fmt.Sprintf("Segmented block %d is found in the cache", n))
+		return newSegmentBlock(n, size, item.Response.Clone(), true), nil
+	}
+
+	bereq := i.ctx.BackendRequest
+	defer func() { i.ctx.BackendRequest = bereq }()
+	i.ctx.BackendRequest = bereq.Clone(bereq.Context())
+	i.ctx.BackendRequest.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", n*size, (n+1)*size-1))
+
+	resp, err := i.sendBackendRequest(backend)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block := newSegmentBlock(n, size, resp, false)
+	if block.inRange && !block.rejected {
+		i.storeSegmentBlock(hash, block)
+	}
+	return block, nil
+}
+
+// Store a block to the cache as partial content regardless of the backend supports Range request
+func (i *Interpreter) storeSegmentBlock(hash string, block *segmentBlock) {
+	now := time.Now()
+	freshness := cachecontrol.Determine(block.resp.Header, now)
+	if !freshness.Cacheable || freshness.TTL <= 0 {
+		return
+	}
+
+	resp := block.resp.Clone()
+	resp.StatusCode = ghttp.StatusPartialContent
+	resp.Status = ghttp.StatusText(ghttp.StatusPartialContent)
+	resp.Header.Set("Content-Range", http.ByteRange{
+		Start: block.start,
+		End:   block.start + int64(len(block.body)) - 1,
+	}.ContentRange(block.length))
+	resp.Header.Set("Content-Length", fmt.Sprint(len(block.body)))
+	resp.Body = io.NopCloser(bytes.NewReader(block.body))
+	resp.ContentLength = int64(len(block.body))
+
+	vary := cache.ParseVary(resp.Header)
+	i.cache.Set(hash, &cache.CacheItem{
+		Response:      resp,
+		Expires:       now.Add(freshness.TTL),
+		EntryTime:     now,
+		URL:           cacheURL(i.ctx.Request),
+		SurrogateKeys: surrogateKeys(resp),
+		Vary:          vary,
+		Variant:       cache.VariantKey(vary, i.ctx.Request.Header),
+
+		StaleWhileRevalidate: freshness.StaleWhileRevalidate,
+		StaleIfError:         freshness.StaleIfError,
+	})
+}
+
+func newSegmentBlock(n, size int64, resp *http.Response, cached bool) *segmentBlock {
+	block := &segmentBlock{number: n, start: n * size, resp: resp, cached: cached}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body) // nolint: errcheck
+	resp.Body = io.NopCloser(bytes.NewReader(buf.Bytes()))
+
+	switch resp.StatusCode {
+	case ghttp.StatusPartialContent:
+		r, length, ok := http.ParseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || r.Start != block.start {
+			block.rejected = true
+			return block
+		}
+		block.length = length
+		block.body = buf.Bytes()
+		block.inRange = true
+	case ghttp.StatusOK:
+		// Backend does not support Range request, cut out the block from the whole object
+		body := buf.Bytes()
+		block.length = int64(len(body))
+		if block.start < block.length {
+			block.body = body[block.start:min(block.start+size, block.length)]
+			block.inRange = true
+		}
+	case ghttp.StatusRequestedRangeNotSatisfiable:
+		_, length, ok := http.ParseContentRange(resp.Header.Get("Content-Range"))
+		if !ok {
+			block.rejected = true
+			return block
+		}
+		block.length = length
+	default:
+		block.rejected = true
+	}
+	return block
+}
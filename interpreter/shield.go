@@ -0,0 +1,119 @@
+package interpreter
+
+import (
+	"crypto/tls"
+	"fmt"
+	"maps"
+	ghttp "net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/exception"
+	"github.com/ysugimoto/falco/interpreter/http"
+	"github.com/ysugimoto/falco/interpreter/limitations"
+	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/interpreter/variable"
+)
+
+// Create the backend request to the shield POP.
+// The request is sent to the same service so the URL is built from the client request
+func (i *Interpreter) createShieldRequest(ctx *context.Context, dc *value.DirectorConfig) (*http.Request, error) {
+	if ctx.Shielding == nil {
+		return nil, exception.Runtime(nil, "Shield director %s is selected but origin shielding is not configured", dc.Name)
+	}
+	if ctx.IsShieldPOP() {
+		return nil, exception.Runtime(nil, "Shield director %s could not be selected on the shield POP", dc.Name)
+	}
+
+	scheme := HTTP_SCHEME
+	if dc.IsSSL {
+		scheme = HTTPS_SCHEME
+	}
+	host := ctx.Request.Header.Get("Host")
+	if host == "" {
+		host = ctx.Request.Host
+	}
+	u := &url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     ctx.Request.URL.Path,
+		RawQuery: ctx.Request.URL.RawQuery,
+	}
+
+	req, err := http.NewRequest(ctx.Request.Method, u.String(), ctx.Request.Body)
+	if err != nil {
+		return nil, exception.Runtime(nil, "Failed to create shield request: %s", err)
+	}
+	req.Header = ctx.Request.Header.Clone()
+	setupFastlyHeaders(req, variable.Datacenter(ctx))
+	if err := i.setBackendTimeouts(ctx, nil); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return req, nil
+}
+
+// Send the backend request to the shield POP. The request is re-entered into the same VCL
+// as a new client request on the shield POP, which has its own cache and shared states
+func (i *Interpreter) sendShieldRequest(dc *value.DirectorConfig) (*http.Response, error) {
+	bereq := i.ctx.BackendRequest
+	if err := limitations.CheckFastlyRequestLimit(bereq); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Shield POP receives the request as a server request
+	req := bereq.Clone(bereq.Context())
+	req.URL = &url.URL{Path: bereq.URL.Path, RawQuery: bereq.URL.RawQuery}
+	req.RequestURI = req.URL.RequestURI()
+	req.Host = bereq.URL.Host
+	if v := bereq.Header.Get("Host"); v != "" {
+		req.Host = v
+	}
+	req.RemoteAddr = i.ctx.Request.RemoteAddr
+	req.TLS = nil
+	if bereq.URL.Scheme == HTTPS_SCHEME {
+		req.TLS = &tls.ConnectionState{
+			Version:           tls.VersionTLS13,
+			CipherSuite:       tls.TLS_AES_128_GCM_SHA256,
+			HandshakeComplete: true,
+		}
+	}
+
+	shield := i.clone()
+	shield.sharedState = i.shield()
+	if err := shield.ProcessInit(req); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	shield.ctx.Datacenter = i.ctx.Shielding.Shield
+	maps.Copy(shield.ctx.MockedBackends, i.ctx.MockedBackends)
+
+	i.Debugger.Message(
+		fmt.Sprintf("Fetching shield (%s) on POP %s %s", dc.Name, i.ctx.Shielding.Shield, bereq.URL.String()),
+	)
+	err := shield.ProcessRecv()
+	// Flows of the shield POP are recorded in the edge process
+	i.process.Merge(shield.process)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	resp := shield.ctx.Response
+	if resp == nil {
+		return nil, exception.Runtime(nil, "Shield POP %s does not respond", i.ctx.Shielding.Shield)
+	}
+	i.Debugger.Message(
+		fmt.Sprintf("Shield (%s) responds status code %d", dc.Name, resp.StatusCode),
+	)
+	return resp, nil
+}
+
+// Set the header value, or append it with comma separated when the header already exists
+// like X-Cache header which is chained on each POP
+func chainHeader(h ghttp.Header, key, val string) {
+	if v := h.Values(key); len(v) > 0 {
+		h.Set(key, strings.Join(v, ", ")+", "+val)
+		return
+	}
+	h.Set(key, val)
+}
@@ -0,0 +1,294 @@
+package interpreter
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/token"
+)
+
+// Debugger which records snapshots of processed statements to travel back in time.
+// The snapshot is notified before the statement is processed,
+// and variable writes by the statement are appended to the snapshot after processed
+type SnapshotDebugger interface {
+	Snapshot(*Snapshot)
+}
+
+// Variable write by the statement, values are copied at the time
+type VariableWrite struct {
+	Name   string
+	Before value.Value
+	After  value.Value
+}
+
+// Snapshot of the statement which is processed in the request
+type Snapshot struct {
+	Token  token.Token
+	Scope  context.Scope
+	Writes []VariableWrite
+}
+
+// Take the snapshot of the statement if the debugger records them
+func (i *Interpreter) takeSnapshot(stmt ast.Statement) *Snapshot {
+	d, ok := i.Debugger.(SnapshotDebugger)
+	if !ok {
+		return nil
+	}
+	s := &Snapshot{
+		Token: stmt.GetMeta().Token,
+		Scope: i.ctx.Scope,
+	}
+	d.Snapshot(s)
+	return s
+}
+
+// Start recording the variable write by the statement.
+// Returned function should be called after the statement is processed successfully
+func (i *Interpreter) recordWrite(s *Snapshot, stmt ast.Statement) func() {
+	if s == nil {
+		return func() {}
+	}
+	name := writtenVariable(stmt)
+	if name == "" {
+		return func() {}
+	}
+	before := i.peekVariable(name)
+	return func() {
+		s.Writes = append(s.Writes, VariableWrite{
+			Name:   name,
+			Before: before,
+			After:  i.peekVariable(name),
+		})
+	}
+}
+
+// Variable name which is written by the statement.
+// Note that implicit writes like header.set() function and re.group.* by regex matching are not recorded
+func writtenVariable(stmt ast.Statement) string {
+	switch t := stmt.(type) {
+	case *ast.SetStatement:
+		return t.Ident.Value
+	case *ast.UnsetStatement:
+		return t.Ident.Value
+	case *ast.AddStatement:
+		return t.Ident.Value
+	case *ast.RemoveStatement:
+		return t.Ident.Value
+	case *ast.DeclareStatement:
+		return t.Name.Value
+	}
+	return ""
+}
+
+// Copy the current value of the variable, returns null if the variable could not be read
+func (i *Interpreter) peekVariable(name string) value.Value {
+	var v value.Value
+	var err error
+	if strings.HasPrefix(name, "var.") {
+		v, err = i.localVars.Get(name)
+	} else {
+		v, err = i.vars.Get(i.ctx.Scope, name)
+	}
+	if err != nil || v == nil {
+		return value.Null
+	}
+	return v.Copy()
+}
+
+// Timeline of snapshots in the request. The debugger could show the state at the past statement
+// by reverting variable writes after the statement from the current values.
+// The cursor is the position which the debugger is looking at,
+// it is placed at the latest snapshot unless traveling back in time
+type Timeline struct {
+	mu        sync.Mutex
+	snapshots []*Snapshot
+	cursor    int
+}
+
+func NewTimeline() *Timeline {
+	return &Timeline{}
+}
+
+// Value history of the variable
+type VariableHistory struct {
+	Index int
+	Token token.Token
+	Scope context.Scope
+	VariableWrite
+}
+
+// Append the snapshot of the present statement, the cursor is moved to the present
+func (t *Timeline) Append(s *Snapshot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.snapshots = append(t.snapshots, s)
+	t.cursor = len(t.snapshots) - 1
+}
+
+// Reset the timeline for the new request
+func (t *Timeline) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.snapshots = nil
+	t.cursor = 0
+}
+
+func (t *Timeline) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.snapshots)
+}
+
+// Get the snapshot at the index, returns nil if out of range
+func (t *Timeline) At(index int) *Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.at(index)
+}
+
+func (t *Timeline) at(index int) *Snapshot {
+	if index < 0 || index >= len(t.snapshots) {
+		return nil
+	}
+	return t.snapshots[index]
+}
+
+func (t *Timeline) Cursor() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.cursor
+}
+
+// Bring the cursor back to the present, resuming the process should call it
+func (t *Timeline) Present() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cursor = max(len(t.snapshots)-1, 0)
+}
+
+// Returns true if the cursor is at the present
+func (t *Timeline) IsPresent() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.isPresent()
+}
+
+func (t *Timeline) isPresent() bool {
+	return t.cursor >= len(t.snapshots)-1
+}
+
+// Get the snapshot at the cursor while traveling back in time, returns nil at the present
+func (t *Timeline) Traveling() *Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.isPresent() {
+		return nil
+	}
+	return t.at(t.cursor)
+}
+
+// Move the cursor by delta, returns the snapshot at the moved cursor.
+// Returns nil and the cursor is not moved if out of range
+func (t *Timeline) Travel(delta int) *Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.at(t.cursor + delta)
+	if s != nil {
+		t.cursor += delta
+	}
+	return s
+}
+
+// Travel back to the snapshot which matches, or the beginning of the timeline.
+// Returns true if the snapshot is found
+func (t *Timeline) ReverseUntil(match func(*Snapshot) bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for t.cursor > 0 {
+		t.cursor--
+		if match(t.snapshots[t.cursor]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Travel forward to the snapshot which matches before the present.
+// Returns false if the cursor reaches the present
+func (t *Timeline) ForwardUntil(match func(*Snapshot) bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for !t.isPresent() {
+		t.cursor++
+		if !t.isPresent() && match(t.snapshots[t.cursor]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find writes of the variable in order. Header name is case-insensitive so compare name case-insensitively
+func (t *Timeline) History(name string) []VariableHistory {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var history []VariableHistory
+	for index, s := range t.snapshots {
+		for _, w := range s.Writes {
+			if strings.EqualFold(w.Name, name) {
+				history = append(history, VariableHistory{
+					Index:         index,
+					Token:         s.Token,
+					Scope:         s.Scope,
+					VariableWrite: w,
+				})
+			}
+		}
+	}
+	return history
+}
+
+// Get the value of the variable at the cursor, returns the current value and false at the present
+func (t *Timeline) Value(name string, current value.Value) (value.Value, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.isPresent() {
+		return current, false
+	}
+	return t.valueAt(t.cursor, name, current), true
+}
+
+// Get the value of the variable when the statement at the index is about to be processed.
+// The first write at or after the index has the value before it, otherwise current value is not changed
+func (t *Timeline) ValueAt(index int, name string, current value.Value) value.Value {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.valueAt(index, name, current)
+}
+
+func (t *Timeline) valueAt(index int, name string, current value.Value) value.Value {
+	for i := max(index, 0); i < len(t.snapshots); i++ {
+		for _, w := range t.snapshots[i].Writes {
+			if strings.EqualFold(w.Name, name) {
+				return w.Before
+			}
+		}
+	}
+	return current
+}
@@ -0,0 +1,112 @@
+package interpreter
+
+import (
+	gocontext "context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/config"
+	"github.com/ysugimoto/falco/interpreter/cache"
+	"github.com/ysugimoto/falco/interpreter/http"
+	"github.com/ysugimoto/falco/interpreter/value"
+)
+
+// Set object which is found in the cache
+func (i *Interpreter) setCacheObject(item *cache.CacheItem) {
+	i.ctx.CacheHitItem = item
+	i.ctx.Object = item.Response.Clone()
+	i.ctx.ObjectGrace = &value.RTime{Value: item.StaleIfError}
+	i.ctx.ObjectStaleWhileRevalidate = &value.RTime{Value: item.StaleWhileRevalidate}
+	i.ctx.TriggerESI = item.ESI
+}
+
+// Lookup expired object which is still in the grace periods on cache miss
+func (i *Interpreter) lookupStale() *cache.CacheItem {
+	item := i.cache.GetStale(i.ctx.RequestHash.Value, i.ctx.Request.Header)
+	i.ctx.StaleItem = item
+	i.ctx.StaleExists = &value.Boolean{Value: item != nil}
+	return item
+}
+
+// Stale object could be served while revalidating in the stale-while-revalidate period.
+// The period is limited by req.max_stale_while_revalidate
+func (i *Interpreter) canServeWhileRevalidate(item *cache.CacheItem) bool {
+	return item.StaleAge() <= min(item.StaleWhileRevalidate, i.ctx.MaxStaleWhileRevalidate.Value)
+}
+
+// Stale object could be served on the origin failure in the stale-if-error period.
+// The period is limited by req.max_stale_if_error
+func (i *Interpreter) canServeIfError(item *cache.CacheItem) bool {
+	return item.StaleAge() <= min(item.StaleIfError, i.ctx.MaxStaleIfError.Value)
+}
+
+// Use stale object for the response
+func (i *Interpreter) useStaleObject(item *cache.CacheItem, isError bool) {
+	i.setCacheObject(item)
+	i.process.Cached = true
+	i.ctx.State = "HIT-STALE"
+	i.ctx.IsLocallyGenerated = &value.Boolean{Value: false}
+	i.ctx.Stale = &value.Boolean{Value: true}
+	i.ctx.StaleIsError = &value.Boolean{Value: isError}
+}
+
+// Revalidate stale object in background.
+// Background fetch processes vcl_miss and vcl_fetch subroutines to update the cache
+// but the response is not delivered to the client
+func (i *Interpreter) revalidate() {
+	hash, variant := i.ctx.RequestHash.Value, i.ctx.CacheHitItem.Variant
+	if !i.cache.StartRevalidation(hash, variant) {
+		i.Debugger.Message("Revalidation is already running")
+		return
+	}
+	i.ctx.StaleIsRevalidating = &value.Boolean{Value: true}
+
+	bg := i.clone()
+	// Debugger could not inspect concurrent process so background fetch is not debuggable
+	if _, ok := i.Debugger.(DefaultDebugger); !ok {
+		bg.Debugger = nopDebugger{}
+	}
+	req := i.ctx.Request.Clone(gocontext.Background())
+	backend := i.ctx.Backend
+	mocks := maps.Clone(i.ctx.MockedBackends)
+
+	i.revalidations.Add(1)
+	go func() {
+		defer i.revalidations.Done()
+		defer i.cache.FinishRevalidation(hash, variant)
+
+		bg.Debugger.Message("Start background revalidation")
+		if err := bg.backgroundFetch(req, hash, backend, mocks); err != nil {
+			bg.Debugger.Message(fmt.Sprintf("Background revalidation failed: %s", err))
+		}
+	}()
+}
+
+func (i *Interpreter) backgroundFetch(
+	req *http.Request,
+	hash string,
+	backend *value.Backend,
+	mocks map[string]*config.MockBackend,
+) error {
+
+	if err := i.ProcessInit(req); err != nil {
+		return errors.WithStack(err)
+	}
+	i.ctx.Backend = backend
+	i.ctx.RequestHash = &value.String{Value: hash}
+	i.ctx.IsBackgroundFetch = &value.Boolean{Value: true}
+	i.ctx.State = "MISS"
+	maps.Copy(i.ctx.MockedBackends, mocks)
+
+	return errors.WithStack(i.ProcessMiss())
+}
+
+// X-Cache header value, stale object is also treated as HIT
+func xCacheValue(state string) string {
+	if strings.HasPrefix(state, "HIT") {
+		return "HIT"
+	}
+	return state
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/ysugimoto/falco/interpreter/assign"
 	"github.com/ysugimoto/falco/interpreter/context"
 	"github.com/ysugimoto/falco/interpreter/exception"
-	"github.com/ysugimoto/falco/interpreter/function"
 	fe "github.com/ysugimoto/falco/interpreter/function/errors"
 	"github.com/ysugimoto/falco/interpreter/limitations"
 	"github.com/ysugimoto/falco/interpreter/operator"
@@ -39,7 +38,10 @@ func (i *Interpreter) ProcessBlockStatement(
 	var err error
 	var debugState = ds
 
-	for _, stmt := range statements {
+	for index := 0; index < len(statements); index++ {
+		stmt := statements[index]
+		// Take snapshot before the debugger stops on the statement
+		commitWrite := i.recordWrite(i.takeSnapshot(stmt), stmt)
 		// Call debugger
 		if debugState != DebugStepOut {
 			debugState = i.Debugger.Run(stmt)
@@ -84,11 +86,16 @@ func (i *Interpreter) ProcessBlockStatement(
 			}
 			err = i.ProcessSyntheticBase64Statement(t)
 
-		// TODO: implement
-		// case *ast.GotoStatement:
-		// 	err = i.ProcessGotoStatement(t)
-		// case *ast.GotoDestinationStatement:
-		// 	err = i.ProcessGotoDesticationStatement(t)
+		case *ast.GotoStatement:
+			i.ProcessGotoStatement(t)
+			if next, found := i.findGotoDestination(statements, index); found {
+				index = next - 1
+				continue
+			}
+			// Destination is not found in this block, jump to the outer block
+			return value.Null, GOTO, DebugPass, nil
+		case *ast.GotoDestinationStatement:
+			// Nothing to do, destination statement is just a marker to jump
 
 		// Probably change status statements
 		case *ast.FunctionCallStatement:
@@ -124,6 +131,12 @@ func (i *Interpreter) ProcessBlockStatement(
 			if val != value.Null {
 				return val, NONE, DebugPass, err
 			}
+			if state == GOTO {
+				if next, found := i.findGotoDestination(statements, index); found {
+					index = next - 1
+					continue
+				}
+			}
 			if state != NONE {
 				return value.Null, state, DebugPass, nil
 			}
@@ -135,6 +148,12 @@ func (i *Interpreter) ProcessBlockStatement(
 			if val != value.Null {
 				return val, NONE, DebugPass, err
 			}
+			if state == GOTO {
+				if next, found := i.findGotoDestination(statements, index); found {
+					index = next - 1
+					continue
+				}
+			}
 			if state != NONE {
 				return value.Null, state, DebugPass, nil
 			}
@@ -157,6 +176,7 @@ func (i *Interpreter) ProcessBlockStatement(
 			}
 
 			// restart statement force change state to RESTART
+			i.notifyException(t, restartException(t))
 			return value.Null, RESTART, DebugPass, nil
 
 		case *ast.ReturnStatement:
@@ -184,6 +204,7 @@ func (i *Interpreter) ProcessBlockStatement(
 			if err := i.ProcessErrorStatement(t); err != nil {
 				return value.Null, ERROR, DebugPass, errors.WithStack(err)
 			}
+			i.notifyException(t, i.errorException(t))
 			return value.Null, ERROR, DebugPass, nil
 
 		case *ast.BlockStatement:
@@ -196,6 +217,12 @@ func (i *Interpreter) ProcessBlockStatement(
 			if val != value.Null {
 				return val, NONE, DebugPass, nil
 			}
+			if state == GOTO {
+				if next, found := i.findGotoDestination(statements, index); found {
+					index = next - 1
+					continue
+				}
+			}
 			if state != NONE {
 				return value.Null, state, DebugPass, nil
 			}
@@ -210,6 +237,7 @@ func (i *Interpreter) ProcessBlockStatement(
 		if err != nil {
 			return value.Null, INTERNAL_ERROR, DebugPass, errors.WithStack(err)
 		}
+		commitWrite()
 	}
 	return value.Null, NONE, DebugPass, nil
 }
@@ -233,6 +261,40 @@ func (i *Interpreter) ProcessDeclareStatement(stmt *ast.DeclareStatement) error
 	return nil
 }
 
+// Store the goto statement to find its destination in the current or outer blocks.
+// Fastly only allows to jump forward in the same subroutine, so the destination is looked up
+// from the statements after the goto statement, or after the block which contains goto statement.
+func (i *Interpreter) ProcessGotoStatement(stmt *ast.GotoStatement) {
+	i.gotoStatement = stmt
+}
+
+// Find the destination of pending goto statement from the statements after the offset.
+// Returns the index of the destination statement if found
+func (i *Interpreter) findGotoDestination(statements []ast.Statement, offset int) (int, bool) {
+	if i.gotoStatement == nil {
+		return 0, false
+	}
+	name := i.gotoStatement.Destination.Value + ":"
+	for j := offset + 1; j < len(statements); j++ {
+		if d, ok := statements[j].(*ast.GotoDestinationStatement); ok && d.Name.Value == name {
+			i.gotoStatement = nil
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// Raise an exception for the goto statement which could not find the destination
+func (i *Interpreter) unresolvedGotoError() error {
+	stmt := i.gotoStatement
+	i.gotoStatement = nil
+	return exception.Runtime(
+		&stmt.GetMeta().Token,
+		"Goto destination %s is not found. Goto can only jump forward in the same subroutine",
+		stmt.Destination.Value,
+	)
+}
+
 func (i *Interpreter) ProcessReturnStatement(stmt *ast.ReturnStatement) State {
 	if stmt.ReturnExpression == nil {
 		return BARE_RETURN
@@ -488,7 +550,7 @@ func (i *Interpreter) ProcessFunctionCallStatement(stmt *ast.FunctionCallStateme
 	}
 
 	// Builtin function will not change any state
-	fn, err := function.Exists(i.ctx.Scope, stmt.Function.Value)
+	fn, err := i.functions.Exists(i.ctx.Scope, stmt.Function.Value)
 	if err != nil {
 		return NONE, exception.Runtime(&stmt.GetMeta().Token, "%s", err.Error())
 	}
@@ -894,3 +894,117 @@ func TestSwitchStatement(t *testing.T) {
 		})
 	}
 }
+
+func TestGotoStatement(t *testing.T) {
+	tests := []struct {
+		name       string
+		vcl        string
+		assertions map[string]value.Value
+		isError    bool
+	}{
+		{
+			name: "Jump forward in the same block",
+			vcl: `
+				sub vcl_recv {
+					set req.http.foo = "1";
+					goto skip;
+					set req.http.foo = "2";
+					skip:
+					set req.http.bar = "1";
+				}`,
+			assertions: map[string]value.Value{
+				"req.http.foo": &value.String{Value: "1"},
+				"req.http.bar": &value.String{Value: "1"},
+			},
+		},
+		{
+			name: "Jump out from nested if block",
+			vcl: `
+				sub vcl_recv {
+					if (req.http.Host) {
+						goto skip;
+						set req.http.foo = "1";
+					} else {
+						if (!req.http.Host) {
+							goto skip;
+						}
+						set req.http.foo = "2";
+					}
+					set req.http.foo = "3";
+					skip:
+					set req.http.bar = "1";
+				}`,
+			assertions: map[string]value.Value{
+				"req.http.foo": &value.String{IsNotSet: true},
+				"req.http.bar": &value.String{Value: "1"},
+			},
+		},
+		{
+			name: "Jump out from switch case",
+			vcl: `
+				sub vcl_recv {
+					switch (req.http.Host) {
+					case "localhost":
+						goto skip;
+						break;
+					default:
+						set req.http.foo = "1";
+						break;
+					}
+					set req.http.foo = "2";
+					skip:
+					set req.http.bar = "1";
+				}`,
+			assertions: map[string]value.Value{
+				"req.http.foo": &value.String{IsNotSet: true},
+				"req.http.bar": &value.String{Value: "1"},
+			},
+		},
+		{
+			name: "Backward jump raises an error",
+			vcl: `
+				sub vcl_recv {
+					back:
+					goto back;
+				}`,
+			isError: true,
+		},
+		{
+			name: "Jump into other subroutine raises an error",
+			vcl: `
+				sub other_sub {
+					goto dest;
+				}
+				sub vcl_recv {
+					call other_sub;
+					dest:
+					set req.http.foo = "1";
+				}`,
+			isError: true,
+		},
+		{
+			name: "Jump in functional subroutine",
+			vcl: `
+				sub compute STRING {
+					if (req.http.Host) {
+						goto done;
+					}
+					return "not reached";
+					done:
+					return "done";
+				}
+				sub vcl_recv {
+					set req.http.foo = compute();
+				}`,
+			assertions: map[string]value.Value{
+				"req.http.foo": &value.String{Value: "done"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertInterpreter(t, tt.vcl, context.RecvScope, tt.assertions, tt.isError)
+		})
+	}
+}
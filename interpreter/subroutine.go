@@ -63,6 +63,13 @@ func (i *Interpreter) ProcessSubroutine(sub *ast.SubroutineDeclaration, ds Debug
 
 	// Ignore debug status and must return state, not a value
 	_, state, _, err := i.ProcessBlockStatement(statements, ds, false)
+	if err != nil {
+		// Notify here because local variables are restored after returning
+		i.notifyException(sub, err)
+	}
+	if err == nil && state == GOTO {
+		return NONE, errors.WithStack(i.unresolvedGotoError())
+	}
 	return state, err
 }
 
@@ -99,7 +106,11 @@ func (i *Interpreter) ProcessFunctionSubroutine(sub *ast.SubroutineDeclaration,
 	var err error
 	var debugState = ds
 
-	for _, stmt := range sub.Block.Statements {
+	statements := sub.Block.Statements
+	for index := 0; index < len(statements); index++ {
+		stmt := statements[index]
+		// Take snapshot before the debugger stops on the statement
+		commitWrite := i.recordWrite(i.takeSnapshot(stmt), stmt)
 		// Call debugger
 		if debugState != DebugStepOut {
 			debugState = i.Debugger.Run(stmt)
@@ -131,10 +142,14 @@ func (i *Interpreter) ProcessFunctionSubroutine(sub *ast.SubroutineDeclaration,
 			err = i.ProcessSyntheticStatement(t)
 		case *ast.SyntheticBase64Statement:
 			err = i.ProcessSyntheticBase64Statement(t)
-		// case *ast.GotoStatement:
-		// 	err = i.ProcessGotoStatement(t)
-		// case *ast.GotoDestinationStatement:
-		// 	err = i.ProcessGotoDesticationStatement(t)
+		case *ast.GotoStatement:
+			i.ProcessGotoStatement(t)
+			if index, err = i.resolveFunctionalGoto(statements, index); err != nil {
+				return value.Null, NONE, errors.WithStack(err)
+			}
+			continue
+		case *ast.GotoDestinationStatement:
+			// Nothing to do, destination statement is just a marker to jump
 		// Probably change status statements
 		case *ast.BlockStatement:
 			var val value.Value
@@ -143,6 +158,12 @@ func (i *Interpreter) ProcessFunctionSubroutine(sub *ast.SubroutineDeclaration,
 			if val != value.Null {
 				return val, NONE, nil
 			}
+			if state == GOTO {
+				if index, err = i.resolveFunctionalGoto(statements, index); err != nil {
+					return value.Null, NONE, errors.WithStack(err)
+				}
+				continue
+			}
 			if state != NONE {
 				return value.Null, state, nil
 			}
@@ -176,6 +197,12 @@ func (i *Interpreter) ProcessFunctionSubroutine(sub *ast.SubroutineDeclaration,
 			if val != value.Null {
 				return val, NONE, nil
 			}
+			if state == GOTO {
+				if index, err = i.resolveFunctionalGoto(statements, index); err != nil {
+					return value.Null, NONE, errors.WithStack(err)
+				}
+				continue
+			}
 			if state != NONE {
 				return value.Null, state, nil
 			}
@@ -186,11 +213,18 @@ func (i *Interpreter) ProcessFunctionSubroutine(sub *ast.SubroutineDeclaration,
 			if val != value.Null {
 				return val, NONE, nil
 			}
+			if state == GOTO {
+				if index, err = i.resolveFunctionalGoto(statements, index); err != nil {
+					return value.Null, NONE, errors.WithStack(err)
+				}
+				continue
+			}
 			if state != NONE {
 				return value.Null, state, nil
 			}
 		case *ast.RestartStatement:
 			// restart statement force change state to RESTART
+			i.notifyException(t, restartException(t))
 			return value.Null, RESTART, nil
 		case *ast.ReturnStatement:
 			var val value.Value
@@ -224,6 +258,7 @@ func (i *Interpreter) ProcessFunctionSubroutine(sub *ast.SubroutineDeclaration,
 			// error statement force change state to ERROR
 			err = i.ProcessErrorStatement(t)
 			if err == nil {
+				i.notifyException(t, i.errorException(t))
 				return value.Null, ERROR, nil
 			}
 		case *ast.EsiStatement:
@@ -234,6 +269,7 @@ func (i *Interpreter) ProcessFunctionSubroutine(sub *ast.SubroutineDeclaration,
 		if err != nil {
 			return value.Null, INTERNAL_ERROR, errors.WithStack(err)
 		}
+		commitWrite()
 	}
 
 	return value.Null, NONE, exception.Runtime(
@@ -243,6 +279,16 @@ func (i *Interpreter) ProcessFunctionSubroutine(sub *ast.SubroutineDeclaration,
 	)
 }
 
+// Find goto destination from the top level statements of functional subroutine.
+// Returns the index of previous statement of the destination to continue the statement loop
+func (i *Interpreter) resolveFunctionalGoto(statements []ast.Statement, offset int) (int, error) {
+	next, found := i.findGotoDestination(statements, offset)
+	if !found {
+		return offset, i.unresolvedGotoError()
+	}
+	return next - 1, nil
+}
+
 func (i *Interpreter) ProcessExpressionReturnStatement(stmt *ast.ReturnStatement) (value.Value, State, error) {
 	val, err := i.ProcessExpression(stmt.ReturnExpression)
 	if err != nil {
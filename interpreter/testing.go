@@ -10,7 +10,9 @@ import (
 	"github.com/ysugimoto/falco/ast"
 	icontext "github.com/ysugimoto/falco/interpreter/context"
 	"github.com/ysugimoto/falco/interpreter/http"
+	"github.com/ysugimoto/falco/interpreter/process"
 	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/interpreter/variable"
 )
 
 const testBackendResponseBody = "falco_test_response"
@@ -40,6 +42,8 @@ func (i *Interpreter) TestProcessInit(r *http.Request) error {
 		}
 	}
 
+	i.defaultBackend = i.ctx.Backend
+
 	// On testing process, all request/response variables should be set initially
 	i.ctx.BackendRequest, err = i.createBackendRequest(i.ctx, i.ctx.Backend)
 	if err != nil {
@@ -73,3 +77,66 @@ func (i *Interpreter) ProcessTestSubroutine(scope icontext.Scope, sub *ast.Subro
 	}
 	return nil
 }
+
+// Process request through the full lifecycle, RECV to LOG, against the mocked origin
+// and returns the cache state of the request like HIT or MISS.
+// The request is processed with a new context, but declarations, mocks and testing settings
+// are shared with the current one so that tests can set up them before running the request.
+func (i *Interpreter) TestProcessRequest(r *http.Request) (string, error) {
+	// Wait for background revalidations of previous requests
+	// so that the cache state is deterministic in testing
+	i.revalidations.Wait()
+	prev := i.ctx
+
+	// Request is processed with new local variables and call stack like a client request,
+	// and turn back to them of the testing subroutine after processed
+	local, stack := i.localVars, i.callStack
+	i.localVars = variable.LocalVariables{}
+	i.callStack = []*ast.SubroutineDeclaration{}
+	defer func() {
+		i.localVars = local
+		i.callStack = stack
+	}()
+
+	i.inheritContext(prev, r)
+	i.originMock = testOriginResponse
+
+	err := i.ProcessRecv()
+	if err != nil {
+		i.process.Error = err
+	}
+	i.process.Restarts = i.ctx.Restarts
+	i.process.Backend = i.ctx.Backend
+
+	// Store cache state of the request as testing state, e.g HIT or MISS
+	i.ctx.ReturnState = &value.String{Value: i.ctx.State}
+	// Turn back to the scope of testing subroutine in order to assert variables
+	i.SetScope(prev.Scope)
+
+	return i.ctx.State, err
+}
+
+// Mocked origin response, always responds 200 OK with testing body
+func testOriginResponse(req *http.Request) (*http.Response, error) {
+	return http.WrapResponse(
+		&ghttp.Response{
+			StatusCode: ghttp.StatusOK,
+			Status:     ghttp.StatusText(ghttp.StatusOK),
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header: ghttp.Header{
+				"Content-Type": {"text/plain"},
+			},
+			Body:          io.NopCloser(strings.NewReader(testBackendResponseBody)),
+			ContentLength: int64(len(testBackendResponseBody)),
+			Trailer:       ghttp.Header{},
+			Request:       req.Request,
+		},
+	), nil
+}
+
+// Process returns the process of the current request, including subroutine flows
+func (i *Interpreter) Process() *process.Process {
+	return i.process
+}
@@ -8,6 +8,11 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"maps"
+	ghttp "net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gobwas/glob"
@@ -28,6 +33,31 @@ const (
 	HTTP_SCHEME  = "http"
 )
 
+// Default backend timeouts which are used when the backend does not declare them
+// see: https://www.fastly.com/documentation/reference/vcl/declarations/backend/
+const (
+	defaultConnectTimeout      = time.Second
+	defaultFirstByteTimeout    = 15 * time.Second
+	defaultBetweenBytesTimeout = 10 * time.Second
+)
+
+// fastly.error values and 503 response for backend timeouts
+var backendTimeoutErrors = map[error]string{
+	http.ErrConnectTimeout:      "ECONNTIMEOUT",
+	http.ErrFirstByteTimeout:    "EFIRSTBYTETIMEOUT",
+	http.ErrBetweenBytesTimeout: "EBETWEENBYTESTIMEOUT",
+}
+
+// Get obj.response and fastly.error values of the backend timeout, returns empty values if the error is not timeout
+func backendTimeoutError(err error) (response, code string) {
+	for e, c := range backendTimeoutErrors {
+		if errors.Is(err, e) {
+			return e.Error(), c
+		}
+	}
+	return "", ""
+}
+
 func getOverrideBackend(ctx *icontext.Context, backendName string) (*config.OverrideBackend, error) {
 	for key, val := range ctx.OverrideBackends {
 		p, err := glob.Compile(key)
@@ -42,22 +72,88 @@ func getOverrideBackend(ctx *icontext.Context, backendName string) (*config.Over
 	return nil, nil
 }
 
-func setupFastlyHeaders(req *http.Request) {
+func getMockedBackend(ctx *icontext.Context, backendName string) (*config.MockBackend, error) {
+	// Exact backend name takes precedence over glob patterns
+	if val, ok := ctx.MockedBackends[backendName]; ok {
+		return val, nil
+	}
+	// Longer pattern is matched first as more specific one, so that the mock is determined
+	// even if multiple patterns match. Patterns which have the same length are sorted lexically
+	keys := slices.SortedFunc(maps.Keys(ctx.MockedBackends), func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	for _, key := range keys {
+		p, err := glob.Compile(key)
+		if err != nil {
+			return nil, exception.System("Invalid glob pattern is provided: %s, %s", key, err)
+		}
+		if !p.Match(backendName) {
+			continue
+		}
+		return ctx.MockedBackends[key], nil
+	}
+	return nil, nil
+}
+
+// Record the backend request and respond mocked response instead of sending to the origin
+func (i *Interpreter) sendMockedBackendRequest(
+	name string,
+	req *http.Request,
+	mock *config.MockBackend,
+) (*http.Response, error) {
+
+	var body bytes.Buffer
+	if req.Body != nil {
+		if _, err := body.ReadFrom(req.Body); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body.Bytes()))
+	}
+	i.ctx.MockedBackendRequests = append(i.ctx.MockedBackendRequests, &icontext.MockedBackendRequest{
+		Backend: name,
+		Request: req,
+		Body:    body.String(),
+	})
+
+	status := mock.Status
+	if status == 0 {
+		status = ghttp.StatusOK
+	}
+	header := ghttp.Header{}
+	for key, val := range mock.Headers {
+		header.Set(key, val)
+	}
+	return http.WrapResponse(&ghttp.Response{
+		StatusCode:    status,
+		Status:        ghttp.StatusText(status),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(mock.Body)),
+		ContentLength: int64(len(mock.Body)),
+		Trailer:       ghttp.Header{},
+		Request:       req.Request,
+	}), nil
+}
+
+func setupFastlyHeaders(req *http.Request, datacenter string) {
 	// Fastly-FF
 	// https://www.fastly.com/documentation/reference/http/http-headers/Fastly-FF/#format
+	// Each node chains its entry, so the shield POP receives the entry of the edge POP
 	mac := hmac.New(sha256.New, []byte("falco"))
 	mac.Write([]byte(variable.FALCO_VIRTUAL_SERVICE_ID))
 	hash := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-	ff := fmt.Sprintf("%s!%s!%s", hash, variable.FALCO_DATACENTER, variable.FALCO_SERVER_HOSTNAME)
-	if req.Header.Get("Fastly-FF") != "" {
-		req.Header.Add("Fastly-FF", ","+ff)
-	} else {
-		req.Header.Set("Fastly-FF", ff)
-	}
+	ff := fmt.Sprintf("%s!%s!%s", hash, datacenter, variable.FALCO_SERVER_HOSTNAME)
+	chainHeader(req.Header, "Fastly-FF", ff)
 	// TODO: cdn-loop, fastly-client, fastly-client-ip, x-forwarded-for, x-forwarded-host, x-forwarded-server, x-varnish,
 }
 
-func (i *Interpreter) createBackendRequest(ctx *icontext.Context, backend *value.Backend) (*http.Request, error) {
+// Get the origin URL of the backend like "https://example.com:443", host and scheme may be overridden by config
+func (i *Interpreter) backendOrigin(ctx *icontext.Context, backend *value.Backend) (*url.URL, error) {
 	var port string
 	if v, err := i.getBackendProperty(backend.Value.Properties, "port"); err != nil {
 		return nil, errors.WithStack(err)
@@ -109,20 +205,31 @@ func (i *Interpreter) createBackendRequest(ctx *icontext.Context, backend *value
 		}
 	}
 
-	url := fmt.Sprintf("%s://%s:%s%s", scheme, host, port, i.ctx.Request.URL.Path)
+	return &url.URL{Scheme: scheme, Host: host + ":" + port}, nil
+}
+
+func (i *Interpreter) createBackendRequest(ctx *icontext.Context, backend *value.Backend) (*http.Request, error) {
+	origin, err := i.backendOrigin(ctx, backend)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	u := origin.String() + i.ctx.Request.URL.Path
 	query := i.ctx.Request.URL.Query()
 	if v := query.Encode(); v != "" {
-		url += "?" + v
+		u += "?" + v
 	}
 
-	req, err := http.NewRequest(i.ctx.Request.Method, url, i.ctx.Request.Body)
+	req, err := http.NewRequest(i.ctx.Request.Method, u, i.ctx.Request.Body)
 	if err != nil {
 		return nil, exception.Runtime(nil, "Failed to create backend request: %s", err)
 	}
 	req.Header = i.ctx.Request.Header.Clone()
-	setupFastlyHeaders(req)
+	setupFastlyHeaders(req, variable.Datacenter(ctx))
+	if err := i.setBackendTimeouts(ctx, backend.Value.Properties); err != nil {
+		return nil, errors.WithStack(err)
+	}
 
-	hostHeader, err := i.getOriginHostHeader(backend, host)
+	hostHeader, err := i.getOriginHostHeader(backend, origin.Hostname())
 	if err != nil {
 		return nil, errors.WithStack(err)
 	} else if hostHeader != nil {
@@ -156,25 +263,55 @@ func (i *Interpreter) getOriginHostHeader(backend *value.Backend, defaultHost st
 	return nil, nil
 }
 
-func (i *Interpreter) sendBackendRequest(backend *value.Backend) (*http.Response, error) {
-	fbt, err := i.getBackendProperty(backend.Value.Properties, "first_byte_timeout")
-	if err != nil {
-		return nil, errors.WithStack(err)
+// Set bereq.*_timeout values from the backend properties, these values could be changed in vcl_miss or vcl_pass
+func (i *Interpreter) setBackendTimeouts(ctx *icontext.Context, props []*ast.BackendProperty) error {
+	timeouts := []struct {
+		key   string
+		value **value.RTime
+		def   time.Duration
+	}{
+		{key: "connect_timeout", value: &ctx.ConnectTimeout, def: defaultConnectTimeout},
+		{key: "first_byte_timeout", value: &ctx.FirstByteTimeout, def: defaultFirstByteTimeout},
+		{key: "between_bytes_timeout", value: &ctx.BetweenBytesTimeout, def: defaultBetweenBytesTimeout},
 	}
+	for _, t := range timeouts {
+		v, err := i.getBackendProperty(props, t.key)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		*t.value = &value.RTime{Value: t.def}
+		if v == nil {
+			continue
+		}
+		rt, ok := v.(*value.RTime)
+		if !ok {
+			return exception.Runtime(nil, "Backend property .%s must be %s type, got %s", t.key, value.RTimeType, v.Type())
+		}
+		(*t.value).Value = rt.Value
+	}
+	return nil
+}
 
-	timeout := 15 * time.Second // 15 seconds as default
-	if fbt != nil {
-		timeout = value.Unwrap[*value.RTime](fbt).Value
+func (i *Interpreter) sendBackendRequest(backend *value.Backend) (*http.Response, error) {
+	if backend.Director != nil {
+		// Shield director sends the request to the shield POP instead of the origin
+		if backend.Director.Type == value.DIRECTORTYPE_SHIELD {
+			return i.sendShieldRequest(backend.Director)
+		}
+		if i.ctx.DirectorBackend == nil {
+			return nil, exception.System("Backend is not determined by director %s", backend.Director.Name)
+		}
+		backend = i.ctx.DirectorBackend
 	}
 
-	// Use bereq.fetch_timeout variable value if specified
+	// bereq.fetch_timeout limits the whole backend fetch if specified
+	ctx := i.ctx.Request.Context()
 	if i.ctx.FetchTimeout != nil && i.ctx.FetchTimeout.Value > 0 {
-		timeout = i.ctx.FetchTimeout.Value
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.ctx.FetchTimeout.Value)
+		defer cancel()
 	}
 
-	ctx, to := context.WithTimeout(i.ctx.Request.Context(), timeout)
-	defer to()
-
 	req := i.ctx.BackendRequest.Clone(ctx)
 
 	// Check Fastly limitations
@@ -192,7 +329,25 @@ func (i *Interpreter) sendBackendRequest(backend *value.Backend) (*http.Response
 		fmt.Sprintf("Fetching backend (%s) %s%s", backend.Value.Name.Value, req.URL.String(), suffix),
 	)
 
-	resp, err := http.SendRequest(req)
+	mock, err := getMockedBackend(i.ctx, backend.Value.Name.Value)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var resp *http.Response
+	if mock != nil {
+		i.Debugger.Message(fmt.Sprintf("Backend (%s) is mocked", backend.Value.Name.Value))
+		resp, err = i.sendMockedBackendRequest(backend.Value.Name.Value, req, mock)
+	} else if i.originMock != nil {
+		// Origin is mocked on testing, the request is never sent to the actual origin
+		resp, err = i.originMock(req)
+	} else {
+		resp, err = http.SendRequestWithTimeouts(req, http.Timeouts{
+			Connect:      i.ctx.ConnectTimeout.Value,
+			FirstByte:    i.ctx.FirstByteTimeout.Value,
+			BetweenBytes: i.ctx.BetweenBytesTimeout.Value,
+		})
+	}
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
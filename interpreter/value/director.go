@@ -8,6 +8,8 @@ type DirectorConfig struct {
 	Key           string // only exists on chash
 	Seed          uint32 // only exists on chash
 	VNodesPerNode int    // only exists on chash
+	Shield        string // only exists on shield
+	IsSSL         bool   // only exists on shield
 	Backends      []*DirectorConfigBackend
 }
 
@@ -65,8 +65,9 @@ func calculateRate(entries []rateEntry, window time.Duration) float64 {
 type Ratecounter struct {
 	Decl *ast.RatecounterDeclaration
 
-	// Ratecounter's client count is native map, not a sync.Map.
-	// This means we won't think about race coundition, allows unexpected behavior under the concurrency.
+	// Ratecounter is shared between concurrent requests on the simulator,
+	// client entries are guarded by mutex
+	mu      sync.Mutex
 	Clients map[string][]rateEntry
 
 	// Ratecounter related value like ratecounter.{NAME}.bucket.10s could be accessible after some ratecounter related functions have been called:
@@ -87,6 +88,9 @@ func NewRatecounter(decl *ast.RatecounterDeclaration) *Ratecounter {
 // Increment() increments access entry manually.
 // This function should be called via ratelimit.ratecounter_increment() VCL function
 func (r *Ratecounter) Increment(entry string, delta int64, window time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, ok := r.Clients[entry]; !ok {
 		r.Clients[entry] = []rateEntry{}
 	}
@@ -101,6 +105,9 @@ func (r *Ratecounter) Increment(entry string, delta int64, window time.Duration)
 // Bucket() returns access count for provided window.
 // This function will be called for specific variables like ratecounter.{NAME}.bucket.10s
 func (r *Ratecounter) Bucket(entry string, window time.Duration) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if !r.IsAccessible {
 		return 0
 	}
@@ -114,6 +121,9 @@ func (r *Ratecounter) Bucket(entry string, window time.Duration) int64 {
 // Rate() returns access rate for provided window.
 // This function will be called for specific variables like ratecounter.{NAME}.rate.1s
 func (r *Ratecounter) Rate(entry string, window time.Duration) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if !r.IsAccessible {
 		return 0
 	}
@@ -50,6 +50,18 @@ func getRawUrlPath(u *url.URL) string {
 	return result
 }
 
+// Count the Fastly nodes which the request has passed through.
+// Each node appends its entry to Fastly-FF header with comma separated
+func countFastlyFF(ff string) int64 {
+	var count int64
+	for _, v := range strings.Split(ff, ",") {
+		if strings.TrimSpace(v) != "" {
+			count++
+		}
+	}
+	return count
+}
+
 // nolint: funlen,gocognit,gocyclo
 func (v *AllScopeVariables) Get(s context.Scope, name string) (value.Value, error) {
 	req := v.ctx.Request
@@ -84,12 +96,8 @@ func (v *AllScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 		CLIENT_CLASS_SPAM,
 		CLIENT_PLATFORM_MEDIAPLAYER,
 		REQ_BACKEND_IS_SHIELD,
-		REQ_IS_BACKGROUND_FETCH,
 		REQ_IS_CLUSTERING,
 		REQ_IS_ESI_SUBREQ,
-		RESP_STALE,
-		RESP_STALE_IS_ERROR,
-		RESP_STALE_IS_REVALIDATING,
 		WORKSPACE_OVERFLOWED:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
@@ -219,7 +227,7 @@ func (v *AllScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		return &value.String{Value: ""}, nil
+		return v.ctx.FastlyError, nil
 	case MATH_1_PI:
 		return &value.Float{Value: 1 / math.Pi}, nil
 	case MATH_2_PI:
@@ -333,15 +341,16 @@ func (v *AllScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 	case FASTLY_FF_VISITS_THIS_POP:
 		return &value.Integer{Value: 1}, nil
 
-	// Returns common value -- do not consider of clustering
+	// Count of Fastly nodes which the request has visited, the current node is counted after the cache lookup.
+	// Clustering is not considered
 	// see: https://developer.fastly.com/reference/vcl/variables/miscellaneous/fastly-ff-visits-this-service/
 	case FASTLY_FF_VISITS_THIS_SERVICE:
+		visits := countFastlyFF(req.Header.Get("Fastly-FF"))
 		switch s {
 		case context.MissScope, context.HitScope, context.FetchScope:
-			return &value.Integer{Value: 1}, nil
-		default:
-			return &value.Integer{Value: 0}, nil
+			visits++
 		}
+		return &value.Integer{Value: visits}, nil
 
 	// Returns tentative value -- you may know your customer_id in the contraction :-)
 	case REQ_CUSTOMER_ID:
@@ -699,7 +708,7 @@ func (v *AllScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		return &value.String{Value: FALCO_DATACENTER}, nil
+		return &value.String{Value: Datacenter(v.ctx)}, nil
 	case SERVER_HOSTNAME:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
@@ -716,7 +725,27 @@ func (v *AllScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 		}
 		return &value.String{Value: "US"}, nil
 	case STALE_EXISTS:
-		return v.ctx.StaleContents, nil
+		return v.ctx.StaleExists, nil
+	case REQ_IS_BACKGROUND_FETCH:
+		if v := lookupOverride(v.ctx, name); v != nil {
+			return v, nil
+		}
+		return v.ctx.IsBackgroundFetch, nil
+	case RESP_STALE:
+		if v := lookupOverride(v.ctx, name); v != nil {
+			return v, nil
+		}
+		return v.ctx.Stale, nil
+	case RESP_STALE_IS_ERROR:
+		if v := lookupOverride(v.ctx, name); v != nil {
+			return v, nil
+		}
+		return v.ctx.StaleIsError, nil
+	case RESP_STALE_IS_REVALIDATING:
+		if v := lookupOverride(v.ctx, name); v != nil {
+			return v, nil
+		}
+		return v.ctx.StaleIsRevalidating, nil
 	case TIME_ELAPSED_MSEC:
 		return &value.String{
 			Value: fmt.Sprint(time.Since(v.ctx.RequestStartTime).Milliseconds()),
@@ -786,8 +815,8 @@ func (v *AllScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 		return val, nil
 	}
 
-	if injectedVariable != nil {
-		if val, err := injectedVariable.Get(v.ctx, s, name); err == nil {
+	if v.ctx.InjectedVariable != nil {
+		if val, err := v.ctx.InjectedVariable.Get(v.ctx, s, name); err == nil {
 			return val, nil
 		}
 	}
@@ -969,8 +998,8 @@ func (v *AllScopeVariables) Set(s context.Scope, name, operator string, val valu
 		return nil
 	}
 
-	if injectedVariable != nil {
-		if err := injectedVariable.Set(v.ctx, s, name, operator, val); err == nil {
+	if v.ctx.InjectedVariable != nil {
+		if err := v.ctx.InjectedVariable.Set(v.ctx, s, name, operator, val); err == nil {
 			return nil
 		}
 	}
@@ -2,6 +2,8 @@ package variable
 
 import (
 	"crypto/tls"
+
+	"github.com/ysugimoto/falco/interpreter/context"
 )
 
 // Frequent occurrences string constant
@@ -13,6 +15,15 @@ const (
 	FALCO_DATACENTER         = "FALCO"
 )
 
+// Datacenter returns the POP name which processes the request.
+// The name is changed for each hop when origin shielding is configured
+func Datacenter(ctx *context.Context) string {
+	if ctx.Datacenter != "" {
+		return ctx.Datacenter
+	}
+	return FALCO_DATACENTER
+}
+
 // Mapping from tls package ciphersuite name (IANA) to OpenSSL name
 // see: src/crypto/tls/cipher_suites.go
 // see: https://testssl.sh/openssl-iana.mapping.html
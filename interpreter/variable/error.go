@@ -89,8 +89,7 @@ func (v *ErrorScopeVariables) Get(s context.Scope, name string) (value.Value, er
 		// alias for obj.grace
 		return v.ctx.ObjectGrace, nil
 	case OBJ_STALE_WHILE_REVALIDATE:
-		// Return fixed value because we don't support SWR yet
-		return &value.RTime{Value: 60 * time.Second}, nil
+		return v.ctx.ObjectStaleWhileRevalidate, nil
 	case OBJ_STATUS:
 		return &value.Integer{Value: int64(v.ctx.Object.StatusCode)}, nil
 	case OBJ_TTL:
@@ -62,8 +62,7 @@ func (v *HitScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		// Return fixed value because we don't support SWR yet
-		return &value.RTime{Value: 60 * time.Second}, nil
+		return v.ctx.ObjectStaleWhileRevalidate, nil
 	case OBJ_STATUS:
 		return &value.Integer{Value: int64(v.ctx.Object.StatusCode)}, nil
 	case OBJ_TTL:
@@ -9,11 +9,12 @@ import (
 	"strings"
 	"time"
 
-	"net/http"
+	ghttp "net/http"
 	"net/netip"
 
 	"github.com/pkg/errors"
 	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/http"
 	"github.com/ysugimoto/falco/interpreter/limitations"
 	"github.com/ysugimoto/falco/interpreter/value"
 )
@@ -120,8 +121,7 @@ func (v *LogScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 		// alias for obj.grace
 		return v.ctx.ObjectGrace, nil
 	case OBJ_STALE_WHILE_REVALIDATE:
-		// Return fixed value because we don't support SWR yet
-		return &value.RTime{Value: 60 * time.Second}, nil
+		return v.ctx.ObjectStaleWhileRevalidate, nil
 	case OBJ_TTL:
 		return v.ctx.ObjectTTL, nil
 
@@ -260,22 +260,30 @@ func (v *LogScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 			Value: time.Since(v.ctx.RequestEndTime),
 		}, nil
 
-	// FIXME: segmented_caching related variables is just fake value
+	// Segmented caching variables reflect the result of the outer request.
+	// Note that inner requests are not processed through the VCL in falco
 	case SEGMENTED_CACHING_AUTOPURGED:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		return &value.Boolean{Value: false}, nil
+		sc := v.ctx.SegmentedCaching
+		return &value.Boolean{Value: sc != nil && sc.Autopurged}, nil
 	case SEGMENTED_CACHING_BLOCK_NUMBER:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		return &value.Integer{Value: 1}, nil
+		if sc := v.ctx.SegmentedCaching; sc != nil {
+			return &value.Integer{Value: sc.BlockNumber}, nil
+		}
+		return &value.Integer{Value: -1}, nil
 	case SEGMENTED_CACHING_BLOCK_SIZE:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		return &value.Integer{Value: 1}, nil
+		if sc := v.ctx.SegmentedCaching; sc != nil {
+			return &value.Integer{Value: sc.BlockSize}, nil
+		}
+		return v.ctx.SegmentedCacheingBlockSize, nil
 	case SEGMENTED_CACHING_CANCELLED: // nolint: misspell
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
@@ -285,44 +293,37 @@ func (v *LogScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		return &value.Boolean{Value: false}, nil
+		specs, ok := http.ParseRange(req.Header.Get("Range"))
+		return &value.Boolean{Value: ok && specs[0].IsOpenEnded()}, nil
 	case SEGMENTED_CACHING_CLIENT_REQ_IS_RANGE:
-		return &value.Boolean{Value: req.Header.Get("Range") != ""}, nil
+		_, ok := http.ParseRange(req.Header.Get("Range"))
+		return &value.Boolean{Value: ok}, nil
 	case SEGMENTED_CACHING_CLIENT_REQ_RANGE_HIGH:
-		spl := strings.SplitN(req.Header.Get("Range"), "-", 2)
-		if len(spl) != 2 {
-			return &value.Integer{Value: 0}, nil
-		}
-		high, err := strconv.ParseInt(spl[1], 10, 64)
-		if err != nil {
-			return value.Null, errors.WithStack(err)
-		}
+		_, high := v.clientRequestRange()
 		return &value.Integer{Value: high}, nil
 	case SEGMENTED_CACHING_CLIENT_REQ_RANGE_LOW:
-		spl := strings.SplitN(req.Header.Get("Range"), "-", 2)
-		if len(spl) != 2 {
-			return &value.Integer{Value: 0}, nil
-		}
-		low, err := strconv.ParseInt(spl[0], 10, 64)
-		if err != nil {
-			return value.Null, errors.WithStack(err)
-		}
+		low, _ := v.clientRequestRange()
 		return &value.Integer{Value: low}, nil
 	case SEGMENTED_CACHING_COMPLETED:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		return &value.Boolean{Value: false}, nil
+		sc := v.ctx.SegmentedCaching
+		return &value.Boolean{Value: sc != nil && sc.Completed}, nil
 	case SEGMENTED_CACHING_ERROR:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
+		if sc := v.ctx.SegmentedCaching; sc != nil {
+			return &value.String{Value: sc.Error}, nil
+		}
 		return &value.String{Value: ""}, nil
 	case SEGMENTED_CACHING_FAILED:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		return &value.Boolean{Value: false}, nil
+		sc := v.ctx.SegmentedCaching
+		return &value.Boolean{Value: sc != nil && sc.Failed}, nil
 	case SEGMENTED_CACHING_IS_INNER_REQ:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
@@ -332,26 +333,38 @@ func (v *LogScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
-		return &value.Boolean{Value: true}, nil
+		return &value.Boolean{Value: v.ctx.SegmentedCaching != nil}, nil
 	case SEGMENTED_CACHING_OBJ_COMPLETE_LENGTH:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
+		if sc := v.ctx.SegmentedCaching; sc != nil {
+			return &value.Integer{Value: sc.CompleteLength}, nil
+		}
 		return &value.Integer{Value: 0}, nil
 	case SEGMENTED_CACHING_ROUNDED_REQ_RANGE_HIGH:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
+		if sc := v.ctx.SegmentedCaching; sc != nil {
+			return &value.Integer{Value: sc.RoundedRangeHigh}, nil
+		}
 		return &value.Integer{Value: 0}, nil
 	case SEGMENTED_CACHING_ROUNDED_REQ_RANGE_LOW:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
+		if sc := v.ctx.SegmentedCaching; sc != nil {
+			return &value.Integer{Value: sc.RoundedRangeLow}, nil
+		}
 		return &value.Integer{Value: 0}, nil
 	case SEGMENTED_CACHING_TOTAL_BLOCKS:
 		if v := lookupOverride(v.ctx, name); v != nil {
 			return v, nil
 		}
+		if sc := v.ctx.SegmentedCaching; sc != nil {
+			return &value.Integer{Value: sc.TotalBlocks}, nil
+		}
 		return &value.Integer{Value: 0}, nil
 	case FASTLY_INFO_REQUEST_ID:
 		return v.ctx.RequestID, nil
@@ -403,6 +416,25 @@ func (v *LogScopeVariables) Get(s context.Scope, name string) (value.Value, erro
 	return val, nil
 }
 
+// Returns the first range of the client request. High is -1 for the open-ended range.
+// Suffix range is resolved by the complete length if the object is fetched by segmented caching
+func (v *LogScopeVariables) clientRequestRange() (low, high int64) {
+	specs, ok := http.ParseRange(v.ctx.Request.Header.Get("Range"))
+	if !ok {
+		return 0, 0
+	}
+	spec := specs[0]
+	if spec.First >= 0 {
+		return spec.First, spec.Last
+	}
+	if sc := v.ctx.SegmentedCaching; sc != nil {
+		if r, ok := spec.Resolve(sc.CompleteLength); ok {
+			return r.Start, r.End
+		}
+	}
+	return -1, -1
+}
+
 func (v *LogScopeVariables) getFromRegex(name string) (value.Value, error) {
 	// HTTP response header matching
 	if match := responseHttpHeaderRegex.FindStringSubmatch(name); match != nil {
@@ -462,7 +494,7 @@ func (v *LogScopeVariables) Set(s context.Scope, name, operator string, val valu
 			return errors.WithStack(err)
 		}
 		v.ctx.Response.StatusCode = int(left.Value)
-		v.ctx.Response.Status = http.StatusText(int(left.Value))
+		v.ctx.Response.Status = ghttp.StatusText(int(left.Value))
 		return nil
 	case SEGMENTED_CACHING_BLOCK_SIZE:
 		if err := doAssign(v.ctx.SegmentedCacheingBlockSize, operator, val); err != nil {
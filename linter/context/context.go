@@ -677,3 +677,55 @@ func splitName(name string) (string, []string) {
 
 	return first, remains
 }
+
+// Functions returns all function specs that the context knows, including user defined functions.
+// This is used for external tools like the language server to list available functions.
+func (c *Context) Functions() Functions {
+	return c.functions
+}
+
+// LookupFunction finds the function spec by name without checking current scope.
+// Different from GetFunction, this method is used to inspect the spec e.g. displaying documentation
+func (c *Context) LookupFunction(name string) (*BuiltinFunction, bool) {
+	first, remains := splitName(name)
+
+	obj, ok := c.functions[first]
+	if !ok {
+		return nil, false
+	}
+	for _, key := range remains {
+		v, ok := obj.Items[key]
+		if !ok {
+			return nil, false
+		}
+		obj = v
+	}
+	if obj == nil || obj.Value == nil {
+		return nil, false
+	}
+	return obj.Value, true
+}
+
+// LookupVariable finds the variable accessor by name without checking current scope.
+// Different from Get, this method does not mark the variable as used and does not create dynamic property.
+func (c *Context) LookupVariable(name string) (*Accessor, bool) {
+	first, remains := splitName(name)
+
+	obj, ok := c.Variables[first]
+	if !ok {
+		return nil, false
+	}
+	for _, key := range remains {
+		if v, ok := obj.Items[key]; ok {
+			obj = v
+		} else if v, ok := obj.Items["%any%"]; ok {
+			obj = v
+		} else {
+			return nil, false
+		}
+	}
+	if obj == nil || obj.Value == nil {
+		return nil, false
+	}
+	return obj.Value, true
+}
@@ -4041,7 +4041,7 @@ func predefinedVariables() Variables {
 				"exists": {
 					Items: map[string]*Object{},
 					Value: &Accessor{
-						Get:       types.StringType,
+						Get:       types.BoolType,
 						Set:       types.NeverType,
 						Unset:     false,
 						Scopes:    RECV | HASH | HIT | MISS | PASS | FETCH | ERROR | DELIVER | LOG,
@@ -26,6 +26,7 @@ type LintError struct {
 	Message   string
 	Reference string
 	Rule      Rule
+	Fix       *Fix
 }
 
 func (l *LintError) Match(r Rule) *LintError {
@@ -324,11 +325,15 @@ func UnusedExternalDeclaration(name, declType string) *LintError {
 }
 
 func UnusedVariable(m *ast.Meta, name string) *LintError {
-	return &LintError{
+	err := &LintError{
 		Severity: WARNING,
 		Token:    m.Token,
 		Message:  fmt.Sprintf(`Variable "%s" is unused`, name),
 	}
+	if m.Token.Type == token.DECLARE && m.EndLine > 0 {
+		err.WithFix("Remove unused declaration", DeleteRange(m.Token, m.EndLine, m.EndPosition))
+	}
+	return err
 }
 
 func NonEmptyPenaltyboxBlock(m *ast.Meta, name string) *LintError {
@@ -399,11 +404,24 @@ func CustomLinterCommandFailed(message string, m *ast.Meta) *LintError {
 }
 
 func DeprecatedVariable(name string, m *ast.Meta) *LintError {
-	return &LintError{
+	err := &LintError{
 		Severity: WARNING,
 		Token:    m.Token,
 		Message:  fmt.Sprintf(`Variable "%s" is deprecated`, name),
 	}
+	if replacement, ok := deprecatedVariableReplacements[name]; ok {
+		err.WithFix(fmt.Sprintf(`Use "%s" instead`, replacement), ReplaceToken(m.Token, replacement))
+	}
+	return err
+}
+
+func ConflictingCacheDirective(m *ast.Meta, name, message string) *LintError {
+	err := &LintError{
+		Severity: WARNING,
+		Token:    m.Token,
+		Message:  fmt.Sprintf("%s: %s", name, message),
+	}
+	return err.Match(CACHE_CONTROL_CONFLICT)
 }
 
 func UncapturedRegexVariable(name string, m *ast.Meta) *LintError {
@@ -0,0 +1,182 @@
+package linter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ysugimoto/falco/linter/context"
+	"github.com/ysugimoto/falco/token"
+)
+
+// The state which is filled when the empty return statement is fixed in the state-machine method.
+// Each value is the state that Fastly moves to by default on the end of subroutine
+var defaultReturnStates = map[int]string{
+	context.RECV:    "lookup",
+	context.HASH:    "hash",
+	context.HIT:     "deliver",
+	context.MISS:    "fetch",
+	context.PASS:    "pass",
+	context.FETCH:   "deliver",
+	context.ERROR:   "deliver",
+	context.DELIVER: "deliver",
+	context.LOG:     "deliver",
+}
+
+// Deprecated variables which have the alternative variable
+var deprecatedVariableReplacements = map[string]string{
+	"client.platform.tvplayer": "client.platform.smarttv",
+}
+
+// Position is a location in the source file.
+// Line and Column are 1-based and Column counts runes, same as token.Token
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) before(o Position) bool {
+	if p.Line != o.Line {
+		return p.Line < o.Line
+	}
+	return p.Column < o.Column
+}
+
+// TextEdit replaces the text between Start (inclusive) and End (exclusive) with NewText.
+// The edit is an insertion when Start equals End, and a deletion when NewText is empty
+type TextEdit struct {
+	File    string
+	Start   Position
+	End     Position
+	NewText string
+}
+
+// Fix is a suggested change to resolve the lint error
+type Fix struct {
+	Description string
+	Edits       []*TextEdit
+}
+
+// Replace the token literal with the provided text
+func ReplaceToken(t token.Token, text string) *TextEdit {
+	return &TextEdit{
+		File:    t.File,
+		Start:   Position{Line: t.Line, Column: t.Position},
+		End:     Position{Line: t.Line, Column: t.Position + len([]rune(t.Literal))},
+		NewText: text,
+	}
+}
+
+// Insert the text right after the token literal
+func InsertAfterToken(t token.Token, text string) *TextEdit {
+	end := Position{Line: t.Line, Column: t.Position + len([]rune(t.Literal))}
+	return &TextEdit{
+		File:    t.File,
+		Start:   end,
+		End:     end,
+		NewText: text,
+	}
+}
+
+// Delete the text from the token to the end position (inclusive) which is stored in ast.Meta.
+// On applying, the statement terminator and the line which becomes blank are also deleted
+func DeleteRange(t token.Token, endLine, endPosition int) *TextEdit {
+	return &TextEdit{
+		File:  t.File,
+		Start: Position{Line: t.Line, Column: t.Position},
+		End:   Position{Line: endLine, Column: endPosition + 1},
+	}
+}
+
+// WithFix sets the suggested fix to the lint error
+func (e *LintError) WithFix(description string, edits ...*TextEdit) *LintError {
+	e.Fix = &Fix{
+		Description: description,
+		Edits:       edits,
+	}
+	return e
+}
+
+// ApplyFixes applies the text edits to the source and returns the fixed source.
+// Edits are applied from the end of the source so that the positions of preceding edits are not shifted.
+// The edit which overlaps with other edit is skipped, and the number of applied edits is returned
+func ApplyFixes(source string, edits []*TextEdit) (string, int, error) {
+	sorted := make([]*TextEdit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[j].Start.before(sorted[i].Start)
+	})
+
+	lines := strings.SplitAfter(source, "\n")
+	var applied int
+	var boundary *Position
+
+	for _, edit := range sorted {
+		if edit.End.before(edit.Start) {
+			return "", 0, fmt.Errorf("invalid edit range at line %d, column %d", edit.Start.Line, edit.Start.Column)
+		}
+		// Skip overlapped edit
+		if boundary != nil && boundary.before(edit.End) {
+			continue
+		}
+		start, err := offsetOf(lines, edit.Start)
+		if err != nil {
+			return "", 0, err
+		}
+		end, err := offsetOf(lines, edit.End)
+		if err != nil {
+			return "", 0, err
+		}
+
+		src := strings.Join(lines, "")
+		if edit.NewText == "" {
+			start, end = expandDeletion(src, start, end)
+		}
+		src = src[:start] + edit.NewText + src[end:]
+		lines = strings.SplitAfter(src, "\n")
+
+		applied++
+		b := edit.Start
+		boundary = &b
+	}
+
+	return strings.Join(lines, ""), applied, nil
+}
+
+// Convert 1-based line and rune column position to the byte offset
+func offsetOf(lines []string, p Position) (int, error) {
+	if p.Line < 1 || p.Line > len(lines) {
+		return 0, fmt.Errorf("line %d is out of range", p.Line)
+	}
+	var offset int
+	for i := 0; i < p.Line-1; i++ {
+		offset += len(lines[i])
+	}
+
+	line := []rune(lines[p.Line-1])
+	if p.Column < 1 || p.Column-1 > len(line) {
+		return 0, fmt.Errorf("column %d is out of range at line %d", p.Column, p.Line)
+	}
+	return offset + len(string(line[:p.Column-1])), nil
+}
+
+// Expand deletion range to include the following semicolon,
+// and the whole line if the line becomes blank after deletion
+func expandDeletion(src string, start, end int) (int, int) {
+	rest := strings.TrimLeft(src[end:], " \t")
+	if strings.HasPrefix(rest, ";") {
+		end = len(src) - len(rest) + 1
+	}
+
+	lineStart := strings.LastIndex(src[:start], "\n") + 1
+	lineEnd := strings.Index(src[end:], "\n")
+	if lineEnd == -1 {
+		lineEnd = len(src)
+	} else {
+		lineEnd += end + 1
+	}
+	if strings.TrimSpace(src[lineStart:start]) == "" && strings.TrimSpace(src[end:lineEnd]) == "" {
+		return lineStart, lineEnd
+	}
+	return start, end
+}
@@ -0,0 +1,147 @@
+package linter
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ysugimoto/falco/lexer"
+	"github.com/ysugimoto/falco/linter/context"
+	"github.com/ysugimoto/falco/parser"
+)
+
+func assertFix(t *testing.T, input, expect string) {
+	vcl, err := parser.New(lexer.NewFromString(input)).ParseVCL()
+	if err != nil {
+		t.Errorf("unexpected parser error: %s", err)
+		t.FailNow()
+	}
+
+	l := New(testConfig)
+	l.Lint(vcl, context.New())
+
+	var edits []*TextEdit
+	for _, le := range l.Errors {
+		if le.Fix != nil {
+			edits = append(edits, le.Fix.Edits...)
+		}
+	}
+	fixed, _, err := ApplyFixes(input, edits)
+	if err != nil {
+		t.Errorf("unexpected fix error: %s", err)
+		return
+	}
+	if diff := cmp.Diff(expect, fixed); diff != "" {
+		t.Errorf("Fixed result mismatch, diff=%s", diff)
+	}
+}
+
+func TestLintFix(t *testing.T) {
+	t.Run("disallow-empty-return", func(t *testing.T) {
+		input := `sub vcl_recv {
+  #FASTLY RECV
+  return;
+}`
+		expect := `sub vcl_recv {
+  #FASTLY RECV
+  return (lookup);
+}`
+		assertFix(t, input, expect)
+	})
+
+	t.Run("operator/assignment", func(t *testing.T) {
+		input := `sub vcl_recv {
+  #FASTLY RECV
+  add req.http.Foo += "bar";
+}`
+		expect := `sub vcl_recv {
+  #FASTLY RECV
+  add req.http.Foo = "bar";
+}`
+		assertFix(t, input, expect)
+	})
+
+	t.Run("unused/variable", func(t *testing.T) {
+		input := `sub vcl_recv {
+  #FASTLY RECV
+  declare local var.unused STRING;
+  declare local var.valued STRING = "foo";
+}`
+		expect := `sub vcl_recv {
+  #FASTLY RECV
+  declare local var.valued STRING = "foo";
+}`
+		assertFix(t, input, expect)
+	})
+
+	t.Run("deprecated", func(t *testing.T) {
+		input := `sub vcl_recv {
+  #FASTLY RECV
+  if (client.platform.tvplayer) {
+    set req.http.Foo = "bar";
+  }
+}`
+		expect := `sub vcl_recv {
+  #FASTLY RECV
+  if (client.platform.smarttv) {
+    set req.http.Foo = "bar";
+  }
+}`
+		assertFix(t, input, expect)
+	})
+
+	t.Run("subroutine/boilerplate-macro", func(t *testing.T) {
+		input := `sub vcl_recv {
+  set req.http.Foo = "bar";
+}`
+		expect := `sub vcl_recv {
+#FASTLY RECV
+  set req.http.Foo = "bar";
+}`
+		assertFix(t, input, expect)
+	})
+}
+
+func TestApplyFixes(t *testing.T) {
+	input := "set req.http.Foo = \"ほげ\"; set req.http.Bar += \"baz\";\n"
+
+	t.Run("apply edits in multi-byte line", func(t *testing.T) {
+		fixed, applied, err := ApplyFixes(input, []*TextEdit{
+			{Start: Position{Line: 1, Column: 43}, End: Position{Line: 1, Column: 45}, NewText: "="},
+			{Start: Position{Line: 1, Column: 5}, End: Position{Line: 1, Column: 17}, NewText: "req.http.Baz"},
+		})
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			return
+		}
+		if applied != 2 {
+			t.Errorf("Expected 2 edits are applied, got %d", applied)
+		}
+		expect := "set req.http.Baz = \"ほげ\"; set req.http.Bar = \"baz\";\n"
+		if diff := cmp.Diff(expect, fixed); diff != "" {
+			t.Errorf("Fixed result mismatch, diff=%s", diff)
+		}
+	})
+
+	t.Run("overlapped edit is skipped", func(t *testing.T) {
+		_, applied, err := ApplyFixes(input, []*TextEdit{
+			{Start: Position{Line: 1, Column: 1}, End: Position{Line: 1, Column: 10}, NewText: ""},
+			{Start: Position{Line: 1, Column: 5}, End: Position{Line: 1, Column: 17}, NewText: "req.http.Baz"},
+		})
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			return
+		}
+		if applied != 1 {
+			t.Errorf("Expected 1 edit is applied, got %d", applied)
+		}
+	})
+
+	t.Run("out of range edit", func(t *testing.T) {
+		_, _, err := ApplyFixes(input, []*TextEdit{
+			{Start: Position{Line: 3, Column: 1}, End: Position{Line: 3, Column: 1}, NewText: "foo"},
+		})
+		if err == nil {
+			t.Errorf("Expected error but got nil")
+		}
+	})
+}
@@ -21,13 +21,18 @@ type Linter struct {
 	lexers     map[string]*lexer.Lexer
 	ignore     *ignore
 	conf       *config.LinterConfig
+
+	// Local variable declarations which have initial value.
+	// Unused variable fix must not remove them because the value expression may have side effects
+	valuedDeclarations map[*ast.Meta]struct{}
 }
 
 func New(c *config.LinterConfig, opts ...optionFunc) *Linter {
 	l := &Linter{
-		lexers: make(map[string]*lexer.Lexer),
-		ignore: &ignore{},
-		conf:   c,
+		lexers:             make(map[string]*lexer.Lexer),
+		ignore:             &ignore{},
+		conf:               c,
+		valuedDeclarations: make(map[*ast.Meta]struct{}),
 	}
 	for i := range opts {
 		opts[i](l)
@@ -165,7 +170,11 @@ func (l *Linter) lintUnusedVariables(ctx *context.Context) {
 		if o.IsUsed {
 			continue
 		}
-		l.Error(UnusedVariable(o.Meta, k).Match(UNUSED_VARIABLE))
+		err := UnusedVariable(o.Meta, k)
+		if _, ok := l.valuedDeclarations[o.Meta]; ok {
+			err.Fix = nil
+		}
+		l.Error(err.Match(UNUSED_VARIABLE))
 	}
 }
 
@@ -650,5 +659,10 @@ func (l *Linter) lintFastlyBoilerPlateMacro(sub *ast.SubroutineDeclaration, ctx
 			`Subroutine "%s" is missing Fastly boilerplate comment "#FASTLY %s" inside definition`, sub.Name.Value, strings.ToUpper(scope),
 		),
 	}
+	// Put boilerplate comment at the beginning of subroutine block
+	err.WithFix(
+		fmt.Sprintf(`Add "#FASTLY %s" comment`, strings.ToUpper(scope)),
+		InsertAfterToken(sub.Block.GetMeta().Token, "\n#FASTLY "+strings.ToUpper(scope)),
+	)
 	l.Error(err.Match(SUBROUTINE_BOILERPLATE_MACRO))
 }
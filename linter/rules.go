@@ -74,6 +74,7 @@ const (
 	TIME_CALCULATION                     = "operator/time-calculation"
 	DEPRECATED                           = "deprecated"
 	UNCAPTURED_REGEX_VARIABLE            = "regex/uncaptured-variable"
+	CACHE_CONTROL_CONFLICT               = "cache-control/conflict"
 )
 
 var references = map[Rule]string{
@@ -112,4 +113,5 @@ var references = map[Rule]string{
 	UNRECOGNIZE_CALL_SCOPE:           "https://github.com/ysugimoto/falco/blob/main/docs/linter.md#user-defined-subroutine",
 	SUBROUTINE_RECURSIVE_CALL:        "https://www.fastly.com/documentation/reference/vcl/subroutines/#recursion",
 	FORBIDDEN_BACKWARD_JUMP:          "https://fiddle.fastly.dev/fiddle/4814c144",
+	CACHE_CONTROL_CONFLICT:           "https://developer.fastly.com/learning/concepts/cache-freshness/",
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/interpreter/cache/cachecontrol"
 	"github.com/ysugimoto/falco/linter/context"
 	"github.com/ysugimoto/falco/linter/types"
 )
@@ -104,6 +105,9 @@ func (l *Linter) lintDeclareStatement(stmt *ast.DeclareStatement, ctx *context.C
 		l.Error(err.Match(DECLARE_STATEMENT_INVALID_TYPE))
 	}
 
+	if stmt.Value != nil {
+		l.valuedDeclarations[stmt.GetMeta()] = struct{}{}
+	}
 	if err := ctx.Declare(stmt.Name.Value, vt, stmt.GetMeta()); err != nil {
 		err := &LintError{
 			Severity: ERROR,
@@ -195,10 +199,28 @@ func (l *Linter) lintSetStatement(stmt *ast.SetStatement, ctx *context.Context)
 		l.lintAssignOperator(stmt.Operator, stmt.Ident.Value, left, right, isLiteralExpression(stmt.Value))
 	}
 PASS:
+	l.lintCacheDirectives(stmt)
 
 	return types.NeverType
 }
 
+// Check conflicting cache directives which are set to the backend response.
+// Only string literal could be checked because the value is determined in runtime otherwise
+func (l *Linter) lintCacheDirectives(stmt *ast.SetStatement) {
+	switch strings.ToLower(stmt.Ident.Value) {
+	case "beresp.http.cache-control", "beresp.http.surrogate-control":
+	default:
+		return
+	}
+	lit, ok := stmt.Value.(*ast.String)
+	if !ok {
+		return
+	}
+	for _, message := range cachecontrol.Parse(lit.Value).Conflicts() {
+		l.Error(ConflictingCacheDirective(stmt.Value.GetMeta(), stmt.Ident.Value, message))
+	}
+}
+
 func (l *Linter) lintUnsetStatement(stmt *ast.UnsetStatement, ctx *context.Context) types.Type {
 	if !isValidVariableNameWithWildcard(stmt.Ident.Value) {
 		l.Error(InvalidName(stmt.Ident.GetMeta(), stmt.Ident.Value, "unset").Match(UNSET_STATEMENT_SYNTAX))
@@ -401,6 +423,7 @@ func (l *Linter) lintAddStatement(stmt *ast.AddStatement, ctx *context.Context)
 			Token:    stmt.Operator.Token,
 			Message:  fmt.Sprintf(`Operator "%s" may not be used on add statement`, stmt.Operator.Operator),
 		}
+		err.WithFix(`Use "=" operator`, ReplaceToken(stmt.Operator.Token, "="))
 		l.Error(err.Match(OPERATOR_ASSIGNMENT))
 	}
 	l.lintAssignOperator(stmt.Operator, stmt.Ident.Value, left, right, isLiteralExpression(stmt.Value))
@@ -600,6 +623,12 @@ func (l *Linter) lintReturnStatement(stmt *ast.ReturnStatement, ctx *context.Con
 				Token:    stmt.GetMeta().Token,
 				Message:  "Empty return is disallowed in state-machine method",
 			}
+			if state, ok := defaultReturnStates[ctx.Mode()]; ok {
+				err.WithFix(
+					fmt.Sprintf("Return %s state explicitly", state),
+					InsertAfterToken(stmt.GetMeta().Token, fmt.Sprintf(" (%s)", state)),
+				)
+			}
 			l.Error(err.Match(DISALLOW_EMPTY_RETURN))
 		}
 		return types.NeverType
@@ -138,6 +138,27 @@ sub foo {
 
 		assertError(t, input)
 	})
+	t.Run("pass with valid cache directives", func(t *testing.T) {
+		input := `
+sub vcl_fetch {
+	#FASTLY FETCH
+	set beresp.http.Cache-Control = "public, max-age=60, stale-while-revalidate=30";
+	return (deliver);
+}`
+
+		assertNoError(t, input)
+	})
+
+	t.Run("conflicting cache directives", func(t *testing.T) {
+		input := `
+sub vcl_fetch {
+	#FASTLY FETCH
+	set beresp.http.Cache-Control = "no-store, max-age=60";
+	return (deliver);
+}`
+
+		assertErrorWithSeverity(t, input, WARNING)
+	})
 }
 
 func TestLintUnsetStatement(t *testing.T) {
@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ysugimoto/falco/config"
+	"github.com/ysugimoto/falco/linter"
+)
+
+type Server struct {
+	config  *config.Config
+	session *session
+}
+
+func New(c *config.Config) *Server {
+	return &Server{
+		config: c,
+	}
+}
+
+func (s *Server) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.session = newSession(
+		bufio.NewReader(os.Stdin),
+		bufio.NewWriter(os.Stdout),
+		s.config,
+	)
+
+	// Stdout is used for the protocol communication so we must not output any logs
+	log.SetOutput(io.Discard)
+
+	return s.session.start(ctx)
+}
+
+// Factory linter severity overrides from configuration, same as lint command does
+func severityOverrides(c *config.LinterConfig) map[string]linter.Severity {
+	overrides := make(map[string]linter.Severity)
+	if c == nil {
+		return overrides
+	}
+	for key, value := range c.Rules {
+		switch strings.ToUpper(value) {
+		case "ERROR":
+			overrides[key] = linter.ERROR
+		case "WARNING":
+			overrides[key] = linter.WARNING
+		case "INFO":
+			overrides[key] = linter.INFO
+		case "IGNORE":
+			overrides[key] = linter.IGNORE
+		}
+	}
+	return overrides
+}
@@ -0,0 +1,150 @@
+package lsp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ysugimoto/falco/ast"
+	lcontext "github.com/ysugimoto/falco/linter/context"
+)
+
+var callStatementRegex = regexp.MustCompile(`\bcall\s+$`)
+
+// All scopes bitmask, used when the scope of position could not be determined
+const allScopes = lcontext.RECV | lcontext.HASH | lcontext.HIT | lcontext.MISS | lcontext.PASS |
+	lcontext.FETCH | lcontext.ERROR | lcontext.DELIVER | lcontext.LOG
+
+// Make completion items at the position.
+// Variables and functions are filtered by the scope of enclosing subroutine
+func (a *analysis) completion(doc *document, pos Position) []CompletionItem {
+	line := doc.line(pos.Line)
+	// Client may send the position beyond the end of line, clamp it
+	end := runeOffset(line, pos.Character)
+	pos.Character = utf16Offset(line, end)
+
+	prefix := prefixAt(line, pos)
+	start := end - len([]rune(prefix))
+	replace := Range{
+		Start: Position{Line: pos.Line, Character: utf16Offset(line, start)},
+		End:   pos,
+	}
+
+	scope := allScopes
+	sub := a.enclosingSubroutine(doc.path, pos)
+	if sub != nil {
+		if s, ok := a.ctx.Subroutines[sub.Name.Value]; ok && s.Scopes > 0 {
+			scope = s.Scopes
+		}
+	}
+
+	var items []CompletionItem
+	add := func(label string, kind int, detail string) {
+		if !strings.HasPrefix(label, prefix) {
+			return
+		}
+		items = append(items, CompletionItem{
+			Label:    label,
+			Kind:     kind,
+			Detail:   detail,
+			TextEdit: &textEdit{Range: replace, NewText: label},
+		})
+	}
+
+	// After "call" keyword, only subroutine names are available
+	runes := []rune(line)
+	if callStatementRegex.MatchString(string(runes[:start])) {
+		for name, s := range a.ctx.Subroutines {
+			if s.Decl == nil || s.Decl.ReturnType != nil || lcontext.IsFastlySubroutine(name) {
+				continue
+			}
+			add(name, completionKindFunction, "subroutine")
+		}
+		return sortCompletionItems(items)
+	}
+
+	// Predefined variables
+	for name, obj := range a.ctx.Variables {
+		if name == "var" {
+			// Local variables are collected from subroutine declaration
+			continue
+		}
+		collectVariables(name, obj, scope, add)
+	}
+
+	// Local variables and parameters in the subroutine
+	if sub != nil {
+		for _, p := range sub.Parameters {
+			add(p.Name.Value, completionKindVariable, p.Type.Value)
+		}
+		walkStatements(sub.Block.Statements, func(stmt ast.Statement) {
+			if t, ok := stmt.(*ast.DeclareStatement); ok {
+				add(t.Name.Value, completionKindVariable, t.ValueType.Value)
+			}
+		})
+	}
+
+	// Builtin and user defined functions
+	for name, spec := range a.ctx.Functions() {
+		collectFunctions(name, spec, scope, add)
+	}
+
+	// Root declarations which could be referenced as identifier
+	for name := range a.ctx.Tables {
+		add(name, completionKindValue, "table")
+	}
+	for name := range a.ctx.Acls {
+		add(name, completionKindValue, "acl")
+	}
+	for name, b := range a.ctx.Backends {
+		if b.DirectorDecl != nil {
+			add(name, completionKindValue, "director")
+		} else {
+			add(name, completionKindValue, "backend")
+		}
+	}
+	for name := range a.ctx.Penaltyboxes {
+		add(name, completionKindValue, "penaltybox")
+	}
+	for name := range a.ctx.Ratecounters {
+		add(name, completionKindValue, "ratecounter")
+	}
+
+	return sortCompletionItems(items)
+}
+
+func collectVariables(name string, obj *lcontext.Object, scope int, add func(string, int, string)) {
+	if obj.Value != nil && obj.Value.Scopes&scope > 0 {
+		detail := obj.Value.Get.String()
+		if obj.Value.Deprecated {
+			detail += " (deprecated)"
+		}
+		add(name, completionKindVariable, detail)
+	}
+	for key, child := range obj.Items {
+		// Dynamic property like req.http.{NAME}, suggest its prefix only
+		if key == "%any%" {
+			if child.Value != nil && child.Value.Scopes&scope > 0 {
+				add(name+".", completionKindModule, "")
+			}
+			continue
+		}
+		collectVariables(name+"."+key, child, scope, add)
+	}
+}
+
+func collectFunctions(name string, spec *lcontext.FunctionSpec, scope int, add func(string, int, string)) {
+	if spec.Value != nil && spec.Value.Scopes&scope > 0 {
+		add(name, completionKindFunction, spec.Value.Return.String())
+	}
+	for key, child := range spec.Items {
+		collectFunctions(name+"."+key, child, scope, add)
+	}
+}
+
+func sortCompletionItems(items []CompletionItem) []CompletionItem {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
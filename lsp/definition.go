@@ -0,0 +1,109 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/token"
+)
+
+// Find definition locations of the symbol at the position
+func (a *analysis) definition(doc *document, pos Position) []Location {
+	line := doc.line(pos.Line)
+
+	// Jump to included module file
+	if m := includeStatementRegex.FindStringSubmatch(line); m != nil {
+		vcl, err := a.resolver.Resolve(&ast.IncludeStatement{
+			Module: &ast.String{Value: m[1]},
+		})
+		if err != nil {
+			return nil
+		}
+		return []Location{{URI: pathToURI(vcl.Name)}}
+	}
+
+	word, _ := wordAt(line, pos)
+	if word == "" {
+		return nil
+	}
+
+	// Goto destination and local variables are scoped in the subroutine
+	if sub := a.enclosingSubroutine(doc.path, pos); sub != nil {
+		if loc := findInSubroutine(sub, word); loc != nil {
+			return []Location{*loc}
+		}
+	}
+
+	if name := declarationNameInVariable(word); name != "" {
+		word = name
+	}
+	if tok, ok := a.declarationToken(word); ok {
+		return []Location{{URI: pathToURI(tok.File), Range: tokenRange(tok)}}
+	}
+	return nil
+}
+
+// Find declared token for root declarations via linter context
+func (a *analysis) declarationToken(name string) (token.Token, bool) {
+	if v, ok := a.ctx.Subroutines[name]; ok && v.Decl != nil {
+		return v.Decl.Name.GetMeta().Token, true
+	}
+	if v, ok := a.ctx.Tables[name]; ok && v.Decl != nil {
+		return v.Decl.Name.GetMeta().Token, true
+	}
+	if v, ok := a.ctx.Acls[name]; ok && v.Decl != nil {
+		return v.Decl.Name.GetMeta().Token, true
+	}
+	if v, ok := a.ctx.Backends[name]; ok {
+		switch {
+		case v.DirectorDecl != nil:
+			return v.DirectorDecl.Name.GetMeta().Token, true
+		case v.BackendDecl != nil:
+			return v.BackendDecl.Name.GetMeta().Token, true
+		}
+	}
+	if v, ok := a.ctx.Penaltyboxes[name]; ok && v.Decl != nil {
+		return v.Decl.Name.GetMeta().Token, true
+	}
+	if v, ok := a.ctx.Ratecounters[name]; ok && v.Decl != nil {
+		return v.Decl.Name.GetMeta().Token, true
+	}
+	return token.Null, false
+}
+
+// Find goto destination, declared local variable or parameter in the subroutine
+func findInSubroutine(sub *ast.SubroutineDeclaration, word string) *Location {
+	var found *ast.Meta
+
+	if strings.HasPrefix(word, "var.") {
+		for _, p := range sub.Parameters {
+			if p.Name.Value == word {
+				found = p.Name.GetMeta()
+			}
+		}
+	}
+
+	walkStatements(sub.Block.Statements, func(stmt ast.Statement) {
+		if found != nil {
+			return
+		}
+		switch t := stmt.(type) {
+		case *ast.GotoDestinationStatement:
+			if t.Name.Value == word+":" {
+				found = t.GetMeta()
+			}
+		case *ast.DeclareStatement:
+			if t.Name.Value == word {
+				found = t.Name.GetMeta()
+			}
+		}
+	})
+
+	if found == nil {
+		return nil
+	}
+	return &Location{
+		URI:   pathToURI(found.Token.File),
+		Range: tokenRange(found.Token),
+	}
+}
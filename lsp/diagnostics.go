@@ -0,0 +1,71 @@
+package lsp
+
+import (
+	"github.com/ysugimoto/falco/linter"
+	"github.com/ysugimoto/falco/parser"
+)
+
+const diagnosticSource = "falco"
+
+// Factory diagnostics from analysis result, grouped by file path.
+// Lint errors may be reported in included modules so the caller should publish for each files
+func (a *analysis) diagnostics(mainPath string, overrides map[string]linter.Severity) map[string][]Diagnostic {
+	diagnostics := map[string][]Diagnostic{
+		mainPath: {},
+	}
+
+	if a.fatalError != nil {
+		file := mainPath
+		d := Diagnostic{
+			Severity: severityError,
+			Source:   diagnosticSource,
+			Message:  a.fatalError.Error(),
+		}
+		if pe, ok := a.fatalError.(*parser.ParseError); ok {
+			d.Range = tokenRange(pe.Token)
+			d.Message = pe.Message
+			if pe.Token.File != "" {
+				file = pe.Token.File
+			}
+		}
+		diagnostics[file] = append(diagnostics[file], d)
+	}
+
+	for _, le := range a.lintErrors {
+		severity := le.Severity
+		if v, ok := overrides[string(le.Rule)]; ok {
+			severity = v
+		}
+
+		d := Diagnostic{
+			Range:   tokenRange(le.Token),
+			Source:  diagnosticSource,
+			Message: le.Message,
+			Code:    string(le.Rule),
+		}
+		switch severity {
+		case linter.ERROR:
+			d.Severity = severityError
+		case linter.WARNING:
+			d.Severity = severityWarning
+		case linter.INFO:
+			d.Severity = severityInformation
+		default:
+			// linter.IGNORE
+			continue
+		}
+		if le.Reference != "" {
+			d.CodeDescription = &codeDescription{Href: le.Reference}
+		}
+
+		// Some lint errors like unused external declaration do not have a file.
+		// Then report it in the main file
+		file := le.Token.File
+		if file == "" {
+			file = mainPath
+		}
+		diagnostics[file] = append(diagnostics[file], d)
+	}
+
+	return diagnostics
+}
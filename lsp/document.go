@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/config"
+	"github.com/ysugimoto/falco/lexer"
+	"github.com/ysugimoto/falco/linter"
+	lcontext "github.com/ysugimoto/falco/linter/context"
+	"github.com/ysugimoto/falco/parser"
+	"github.com/ysugimoto/falco/resolver"
+	"github.com/ysugimoto/falco/token"
+)
+
+// document represents a VCL file which is opened in the editor
+type document struct {
+	uri     string
+	path    string
+	version int
+	text    string
+
+	// Latest analysis result, updated on open, change and save
+	analysis *analysis
+}
+
+// Get line string in the document, line is zero-based
+func (d *document) line(n int) string {
+	lines := strings.Split(d.text, "\n")
+	if n < 0 || n >= len(lines) {
+		return ""
+	}
+	return strings.TrimRight(lines[n], "\r")
+}
+
+// analysis is the result of parsing and linting for a document
+type analysis struct {
+	vcl        *ast.VCL
+	ctx        *lcontext.Context
+	resolver   resolver.Resolver
+	lintErrors []*linter.LintError
+	fatalError error
+}
+
+// overlayResolver resolves include statements through the original file resolver
+// but prefers unsaved buffer contents which are opened in the editor
+type overlayResolver struct {
+	resolver.Resolver
+	main    *resolver.VCL
+	overlay func(path string) (string, bool)
+}
+
+func (o *overlayResolver) MainVCL() (*resolver.VCL, error) {
+	return o.main, nil
+}
+
+func (o *overlayResolver) Resolve(stmt *ast.IncludeStatement) (*resolver.VCL, error) {
+	vcl, err := o.Resolver.Resolve(stmt)
+	if err != nil {
+		return nil, err
+	}
+	if text, ok := o.overlay(vcl.Name); ok {
+		return &resolver.VCL{Name: vcl.Name, Data: text}, nil
+	}
+	return vcl, nil
+}
+
+// Parse and lint the document, the result is cached in the document
+func analyze(
+	doc *document,
+	c *config.LinterConfig,
+	includePaths []string,
+	overlay func(path string) (string, bool),
+) (result *analysis) {
+
+	main := &resolver.VCL{Name: doc.path, Data: doc.text}
+	var base resolver.Resolver = &resolver.EmptyResolver{}
+	// File resolver needs the main file exists on the disk.
+	// For unsaved new buffer, include statements could not be resolved
+	if rs, err := resolver.NewFileResolvers(doc.path, includePaths); err == nil && len(rs) > 0 {
+		base = rs[0]
+	}
+	rslv := &overlayResolver{Resolver: base, main: main, overlay: overlay}
+
+	result = &analysis{
+		ctx:      lcontext.New(lcontext.WithResolver(rslv)),
+		resolver: rslv,
+	}
+
+	vcl, err := parser.New(lexer.NewFromString(doc.text, lexer.WithFile(doc.path))).ParseVCLOrSnippet()
+	if err != nil {
+		result.fatalError = errors.Cause(err)
+		return result
+	}
+	result.vcl = vcl
+
+	// Linter may panic on the incomplete VCL which is being edited.
+	// The server must not die so recover it and report as fatal error
+	defer func() {
+		if r := recover(); r != nil {
+			result.fatalError = errors.Errorf("Unexpected linter panic: %v", r)
+		}
+	}()
+
+	lt := linter.New(c)
+	lt.Lint(vcl, result.ctx)
+	result.lintErrors = lt.Errors
+	if lt.FatalError != nil {
+		result.fatalError = lt.FatalError.Error
+	}
+	return result
+}
+
+// Convert document URI to filesystem path
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// Convert filesystem path to document URI
+func pathToURI(path string) string {
+	u := &url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
+
+// Convert token to LSP range.
+// Note that token line and position are one-based but LSP is zero-based
+func tokenRange(t token.Token) Range {
+	line := max(t.Line-1, 0)
+	start := max(t.Position-1, 0)
+	return Range{
+		Start: Position{Line: line, Character: start},
+		End:   Position{Line: line, Character: start + len([]rune(t.Literal))},
+	}
+}
@@ -0,0 +1,124 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ysugimoto/falco/ast"
+	lcontext "github.com/ysugimoto/falco/linter/context"
+	"github.com/ysugimoto/falco/linter/types"
+)
+
+// Make hover documentation of the symbol at the position
+func (a *analysis) hover(doc *document, pos Position) *Hover {
+	word, rng := wordAt(doc.line(pos.Line), pos)
+	if word == "" {
+		return nil
+	}
+
+	var contents string
+	if sub, ok := a.ctx.Subroutines[word]; ok && sub.Decl != nil {
+		contents = subroutineDocument(sub.Decl)
+	} else if fn, ok := a.ctx.LookupFunction(word); ok {
+		contents = functionDocument(word, fn)
+	} else if v, ok := a.ctx.LookupVariable(word); ok {
+		contents = variableDocument(word, v)
+	} else if tok, ok := a.declarationToken(word); ok {
+		contents = fmt.Sprintf("```vcl\n%s %s\n```", tok.Literal, word)
+		if tok.File != "" {
+			contents += "\n\nDeclared in " + tok.File
+		}
+	}
+
+	if contents == "" {
+		return nil
+	}
+	return &Hover{
+		Contents: markupContent{Kind: "markdown", Value: contents},
+		Range:    &rng,
+	}
+}
+
+func subroutineDocument(decl *ast.SubroutineDeclaration) string {
+	var sb strings.Builder
+
+	sb.WriteString("```vcl\nsub " + decl.Name.Value)
+	if len(decl.Parameters) > 0 {
+		params := make([]string, len(decl.Parameters))
+		for i, p := range decl.Parameters {
+			params[i] = p.Type.Value + " " + p.Name.Value
+		}
+		sb.WriteString("(" + strings.Join(params, ", ") + ")")
+	}
+	if decl.ReturnType != nil {
+		sb.WriteString(" " + decl.ReturnType.Value)
+	}
+	sb.WriteString("\n```")
+
+	for _, c := range decl.Leading {
+		if line := strings.TrimLeft(c.String(), "#/* "); line != "" {
+			sb.WriteString("\n\n" + line)
+		}
+	}
+	return sb.String()
+}
+
+func functionDocument(name string, fn *lcontext.BuiltinFunction) string {
+	var sb strings.Builder
+
+	sb.WriteString("```vcl\n")
+	if len(fn.Arguments) == 0 {
+		sb.WriteString(fmt.Sprintf("%s %s()\n", fn.Return, name))
+	}
+	// Arguments may have multiple signatures
+	for _, args := range fn.Arguments {
+		sig := make([]string, len(args))
+		for i := range args {
+			sig[i] = args[i].String()
+		}
+		sb.WriteString(fmt.Sprintf("%s %s(%s)\n", fn.Return, name, strings.Join(sig, ", ")))
+	}
+	sb.WriteString("```")
+
+	if scopes := strings.TrimSpace(lcontext.ScopesString(fn.Scopes)); scopes != "" {
+		sb.WriteString("\n\nAvailable scopes: " + scopes)
+	}
+	if fn.Reference != "" {
+		sb.WriteString("\n\nSee reference documentation: " + fn.Reference)
+	}
+	return sb.String()
+}
+
+func variableDocument(name string, v *lcontext.Accessor) string {
+	var sb strings.Builder
+
+	valueType := v.Get
+	if valueType == types.NeverType {
+		valueType = v.Set
+	}
+	sb.WriteString(fmt.Sprintf("```vcl\n%s %s\n```", valueType, name))
+
+	var access []string
+	if v.Get != types.NeverType {
+		access = append(access, "readable")
+	}
+	if v.Set != types.NeverType {
+		access = append(access, "writable")
+	}
+	if v.Unset {
+		access = append(access, "unsettable")
+	}
+	if len(access) > 0 {
+		sb.WriteString("\n\nAccess: " + strings.Join(access, ", "))
+	}
+	if scopes := strings.TrimSpace(lcontext.ScopesString(v.Scopes)); scopes != "" {
+		sb.WriteString("\n\nAvailable scopes: " + scopes)
+	}
+	if v.Deprecated {
+		sb.WriteString("\n\n**Deprecated**")
+	}
+	if v.Reference != "" {
+		sb.WriteString("\n\nSee reference documentation: " + v.Reference)
+	}
+	return sb.String()
+}
@@ -0,0 +1,221 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ysugimoto/falco/config"
+)
+
+const testMainVCL = `backend F_origin {
+  .host = "example.com";
+}
+
+table my_table {
+  "foo": "bar",
+}
+
+include "mod";
+
+sub vcl_recv {
+  #FASTLY RECV
+  declare local var.X STRING;
+  set var.X = table.lookup(my_table, "foo");
+  call my_sub;
+  goto done;
+  set req.http.Foo = var.X;
+  done:
+  set req.backend = F_origin;
+  return (lookup);
+}
+`
+
+const testModVCL = `// @scope: recv
+sub my_sub {
+  set req.http.X-Mod = "1";
+}
+`
+
+func setupDocument(t *testing.T, text string) *document {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.vcl")
+	if err := os.WriteFile(main, []byte(text), 0o644); err != nil {
+		t.Fatalf("Failed to write main VCL: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mod.vcl"), []byte(testModVCL), 0o644); err != nil {
+		t.Fatalf("Failed to write included VCL: %s", err)
+	}
+	doc := &document{
+		uri:  pathToURI(main),
+		path: main,
+		text: text,
+	}
+	doc.analysis = analyze(doc, &config.LinterConfig{}, []string{dir}, func(path string) (string, bool) {
+		return "", false
+	})
+	return doc
+}
+
+func TestWordAt(t *testing.T) {
+	tests := []struct {
+		line   string
+		char   int
+		expect string
+	}{
+		{line: "  set req.http.Foo = var.X;", char: 8, expect: "req.http.Foo"},
+		{line: "  call my_sub;", char: 9, expect: "my_sub"},
+		{line: "  goto done;", char: 2, expect: "goto"},
+		{line: "  goto done;", char: 11, expect: "done"},
+		{line: "  goto done;", char: 12, expect: ""},
+	}
+
+	for _, tt := range tests {
+		word, _ := wordAt(tt.line, Position{Character: tt.char})
+		if diff := cmp.Diff(tt.expect, word); diff != "" {
+			t.Errorf("wordAt(%q, %d) mismatch, diff=%s", tt.line, tt.char, diff)
+		}
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	doc := setupDocument(t, testMainVCL)
+	if doc.analysis.fatalError != nil {
+		t.Fatalf("Unexpected fatal error: %s", doc.analysis.fatalError)
+	}
+
+	tests := []struct {
+		name   string
+		pos    Position
+		file   string
+		expect Range
+	}{
+		{
+			name:   "subroutine in included file",
+			pos:    Position{Line: 14, Character: 8},
+			file:   "mod.vcl",
+			expect: Range{Start: Position{Line: 1, Character: 4}, End: Position{Line: 1, Character: 10}},
+		},
+		{
+			name:   "goto destination",
+			pos:    Position{Line: 15, Character: 7},
+			file:   "main.vcl",
+			expect: Range{Start: Position{Line: 17, Character: 2}, End: Position{Line: 17, Character: 7}},
+		},
+		{
+			name:   "table",
+			pos:    Position{Line: 13, Character: 30},
+			file:   "main.vcl",
+			expect: Range{Start: Position{Line: 4, Character: 6}, End: Position{Line: 4, Character: 14}},
+		},
+		{
+			name:   "backend",
+			pos:    Position{Line: 18, Character: 22},
+			file:   "main.vcl",
+			expect: Range{Start: Position{Line: 0, Character: 8}, End: Position{Line: 0, Character: 16}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locations := doc.analysis.definition(doc, tt.pos)
+			if len(locations) != 1 {
+				t.Fatalf("Expected one location, got %d", len(locations))
+			}
+			if filepath.Base(uriToPath(locations[0].URI)) != tt.file {
+				t.Errorf("Unexpected definition file: %s", locations[0].URI)
+			}
+			if diff := cmp.Diff(tt.expect, locations[0].Range); diff != "" {
+				t.Errorf("Range mismatch, diff=%s", diff)
+			}
+		})
+	}
+}
+
+func TestHover(t *testing.T) {
+	doc := setupDocument(t, testMainVCL)
+
+	t.Run("builtin function", func(t *testing.T) {
+		h := doc.analysis.hover(doc, Position{Line: 13, Character: 18})
+		if h == nil {
+			t.Fatal("Expected hover content, got nil")
+		}
+		expect := Range{Start: Position{Line: 13, Character: 14}, End: Position{Line: 13, Character: 26}}
+		if diff := cmp.Diff(&expect, h.Range); diff != "" {
+			t.Errorf("Range mismatch, diff=%s", diff)
+		}
+	})
+
+	t.Run("predefined variable", func(t *testing.T) {
+		h := doc.analysis.hover(doc, Position{Line: 16, Character: 10})
+		if h == nil {
+			t.Fatal("Expected hover content, got nil")
+		}
+	})
+}
+
+func TestCompletion(t *testing.T) {
+	doc := setupDocument(t, testMainVCL)
+
+	items := doc.analysis.completion(doc, Position{Line: 14, Character: 7})
+	labels := make([]string, len(items))
+	for i := range items {
+		labels[i] = items[i].Label
+	}
+	if diff := cmp.Diff([]string{"my_sub"}, labels); diff != "" {
+		t.Errorf("Completion items mismatch, diff=%s", diff)
+	}
+
+	t.Run("position beyond the end of line", func(t *testing.T) {
+		items := doc.analysis.completion(doc, Position{Line: 14, Character: 100})
+		if len(items) == 0 {
+			t.Fatal("Expected completion items, got empty")
+		}
+		expect := Range{
+			Start: Position{Line: 14, Character: 14},
+			End:   Position{Line: 14, Character: 14},
+		}
+		if diff := cmp.Diff(expect, items[0].TextEdit.Range); diff != "" {
+			t.Errorf("Completion range mismatch, diff=%s", diff)
+		}
+	})
+
+	t.Run("non-ASCII text before the position", func(t *testing.T) {
+		// Emoji is counted as two UTF-16 code units in LSP character offset
+		text := strings.Replace(testMainVCL, "  call my_sub;", `  set req.http.Foo = "😀"; call my_sub;`, 1)
+		doc := setupDocument(t, text)
+		items := doc.analysis.completion(doc, Position{Line: 14, Character: 35})
+		if len(items) != 1 {
+			t.Fatalf("Expected single completion item, got %d", len(items))
+		}
+		if items[0].Label != "my_sub" {
+			t.Errorf("Expected completion label my_sub, got %s", items[0].Label)
+		}
+		expect := Range{
+			Start: Position{Line: 14, Character: 32},
+			End:   Position{Line: 14, Character: 35},
+		}
+		if diff := cmp.Diff(expect, items[0].TextEdit.Range); diff != "" {
+			t.Errorf("Completion range mismatch, diff=%s", diff)
+		}
+	})
+}
+
+func TestDiagnostics(t *testing.T) {
+	text := `sub vcl_recv {
+  #FASTLY RECV
+  set req.http.Foo = 1
+}
+`
+	doc := setupDocument(t, text)
+	diagnostics := doc.analysis.diagnostics(doc.path, nil)
+	ds, ok := diagnostics[doc.path]
+	if !ok || len(ds) == 0 {
+		t.Fatalf("Expected diagnostics for main file, got %v", diagnostics)
+	}
+	if ds[0].Severity != severityError {
+		t.Errorf("Expected error severity, got %d", ds[0].Severity)
+	}
+}
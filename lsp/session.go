@@ -0,0 +1,380 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/ysugimoto/falco/config"
+	"github.com/ysugimoto/falco/linter"
+)
+
+var errExit = errors.New("exit")
+
+type session struct {
+	reader *bufio.Reader
+	writer *bufio.Writer
+	config *config.Config
+
+	overrides    map[string]linter.Severity
+	includePaths []string
+
+	mu        sync.Mutex
+	writeMu   sync.Mutex
+	documents map[string]*document // keyed by file path
+	// Track the files which diagnostics are published by analyzing each document,
+	// in order to clear them on next publishing
+	published map[string][]string
+}
+
+func newSession(r *bufio.Reader, w *bufio.Writer, c *config.Config) *session {
+	return &session{
+		reader:       r,
+		writer:       w,
+		config:       c,
+		overrides:    severityOverrides(c.Linter),
+		includePaths: c.IncludePaths,
+		documents:    make(map[string]*document),
+		published:    make(map[string][]string),
+	}
+}
+
+func (s *session) start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		msg, err := readMessage(s.reader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if re, ok := err.(*responseError); ok {
+				s.sendError(nil, re)
+				continue
+			}
+			return err
+		}
+
+		// LSP requires to process text synchronization messages in order,
+		// so we handle messages sequentially
+		if err := s.handle(msg); err != nil {
+			if err == errExit {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (s *session) handle(msg *message) error {
+	if msg.Method == "exit" {
+		return errExit
+	}
+
+	result, err := s.dispatch(msg)
+	if err != nil {
+		log.Printf("failed to handle %s: %s", msg.Method, err)
+	}
+	// Notification does not need to respond
+	if !msg.isRequest() {
+		return nil
+	}
+
+	if err != nil {
+		re, ok := err.(*responseError)
+		if !ok {
+			re = &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		s.sendError(msg.ID, re)
+		return nil
+	}
+	return s.send(&response{JSONRPC: jsonrpcVersion, ID: msg.ID, Result: result})
+}
+
+// Dispatch message to the handler for the method.
+// Unexpected panic in the handler is recovered as an internal error in order not to stop the server
+func (s *session) dispatch(msg *message) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &responseError{
+				Code:    codeInternalError,
+				Message: fmt.Sprintf("panic on handling %s: %v", msg.Method, r),
+			}
+		}
+	}()
+
+	switch msg.Method {
+	case "initialize":
+		result, err = s.onInitialize(msg.Params)
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		// nothing to do
+	case "shutdown":
+		result = nil
+	case "textDocument/didOpen":
+		err = s.onDidOpen(msg.Params)
+	case "textDocument/didChange":
+		err = s.onDidChange(msg.Params)
+	case "textDocument/didSave":
+		err = s.onDidSave(msg.Params)
+	case "textDocument/didClose":
+		err = s.onDidClose(msg.Params)
+	case "textDocument/definition":
+		result, err = s.onDefinition(msg.Params)
+	case "textDocument/hover":
+		result, err = s.onHover(msg.Params)
+	case "textDocument/completion":
+		result, err = s.onCompletion(msg.Params)
+	default:
+		if msg.isRequest() {
+			err = &responseError{
+				Code:    codeMethodNotFound,
+				Message: fmt.Sprintf("method %s is not supported", msg.Method),
+			}
+		}
+	}
+
+	return result, err
+}
+
+func (s *session) send(msg any) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return writeMessage(s.writer, msg)
+}
+
+func (s *session) sendError(id *json.RawMessage, err *responseError) {
+	if e := s.send(&errorResponse{JSONRPC: jsonrpcVersion, ID: id, Error: err}); e != nil {
+		log.Printf("failed to send error response: %s", e)
+	}
+}
+
+func (s *session) notify(method string, params any) error {
+	return s.send(&notification{JSONRPC: jsonrpcVersion, Method: method, Params: params})
+}
+
+func decodeParams(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *session) onInitialize(params json.RawMessage) (any, error) {
+	var p initializeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.InitializationOptions != nil {
+		s.includePaths = append(s.includePaths, p.InitializationOptions.IncludePaths...)
+	}
+
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync": map[string]any{
+				"openClose": true,
+				"change":    textDocumentSyncFull,
+				"save":      map[string]any{"includeText": true},
+			},
+			"definitionProvider": true,
+			"hoverProvider":      true,
+			"completionProvider": map[string]any{
+				"triggerCharacters": []string{"."},
+			},
+		},
+		"serverInfo": map[string]any{
+			"name": "falco",
+		},
+	}, nil
+}
+
+func (s *session) onDidOpen(params json.RawMessage) error {
+	var p didOpenParams
+	if err := decodeParams(params, &p); err != nil {
+		return err
+	}
+
+	doc := &document{
+		uri:     p.TextDocument.URI,
+		path:    uriToPath(p.TextDocument.URI),
+		version: p.TextDocument.Version,
+		text:    p.TextDocument.Text,
+	}
+	s.mu.Lock()
+	s.documents[doc.path] = doc
+	s.mu.Unlock()
+
+	return s.publishDiagnostics(doc)
+}
+
+func (s *session) onDidChange(params json.RawMessage) error {
+	var p didChangeParams
+	if err := decodeParams(params, &p); err != nil {
+		return err
+	}
+
+	doc := s.document(p.TextDocument.URI)
+	if doc == nil || len(p.ContentChanges) == 0 {
+		return nil
+	}
+	// We only support full document synchronization so the last change is the whole text
+	s.mu.Lock()
+	doc.version = p.TextDocument.Version
+	doc.text = p.ContentChanges[len(p.ContentChanges)-1].Text
+	s.mu.Unlock()
+
+	return s.publishDiagnostics(doc)
+}
+
+func (s *session) onDidSave(params json.RawMessage) error {
+	var p didSaveParams
+	if err := decodeParams(params, &p); err != nil {
+		return err
+	}
+
+	doc := s.document(p.TextDocument.URI)
+	if doc == nil {
+		return nil
+	}
+	if p.Text != nil {
+		s.mu.Lock()
+		doc.text = *p.Text
+		s.mu.Unlock()
+	}
+
+	return s.publishDiagnostics(doc)
+}
+
+func (s *session) onDidClose(params json.RawMessage) error {
+	var p didCloseParams
+	if err := decodeParams(params, &p); err != nil {
+		return err
+	}
+
+	path := uriToPath(p.TextDocument.URI)
+	s.mu.Lock()
+	delete(s.documents, path)
+	published := s.published[path]
+	delete(s.published, path)
+	s.mu.Unlock()
+
+	// Clear diagnostics which are published by closed document
+	for _, file := range published {
+		if err := s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+			URI:         pathToURI(file),
+			Diagnostics: []Diagnostic{},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *session) onDefinition(params json.RawMessage) (any, error) {
+	var p textDocumentPositionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	doc := s.document(p.TextDocument.URI)
+	if doc == nil {
+		return nil, nil
+	}
+	return s.analysis(doc).definition(doc, p.Position), nil
+}
+
+func (s *session) onHover(params json.RawMessage) (any, error) {
+	var p textDocumentPositionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	doc := s.document(p.TextDocument.URI)
+	if doc == nil {
+		return nil, nil
+	}
+	return s.analysis(doc).hover(doc, p.Position), nil
+}
+
+func (s *session) onCompletion(params json.RawMessage) (any, error) {
+	var p textDocumentPositionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	doc := s.document(p.TextDocument.URI)
+	if doc == nil {
+		return nil, nil
+	}
+	return &completionList{
+		Items: s.analysis(doc).completion(doc, p.Position),
+	}, nil
+}
+
+func (s *session) document(uri string) *document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.documents[uriToPath(uri)]
+}
+
+// Get analysis of the document, analyze if not yet
+func (s *session) analysis(doc *document) *analysis {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc.analysis == nil {
+		doc.analysis = analyze(doc, s.config.Linter, s.includePaths, s.overlay)
+	}
+	return doc.analysis
+}
+
+// Find the unsaved buffer content of the file.
+// Note that this function is called during analysis, lock is already held by caller
+func (s *session) overlay(path string) (string, bool) {
+	if doc, ok := s.documents[path]; ok {
+		return doc.text, true
+	}
+	return "", false
+}
+
+// Analyze document and publish diagnostics for the main and included files
+func (s *session) publishDiagnostics(doc *document) error {
+	s.mu.Lock()
+	doc.analysis = analyze(doc, s.config.Linter, s.includePaths, s.overlay)
+	diagnostics := doc.analysis.diagnostics(doc.path, s.overrides)
+	previous := s.published[doc.path]
+	files := make([]string, 0, len(diagnostics))
+	for file := range diagnostics {
+		files = append(files, file)
+	}
+	s.published[doc.path] = files
+	s.mu.Unlock()
+
+	// Clear diagnostics of the files which had problems on previous analysis
+	for _, file := range previous {
+		if _, ok := diagnostics[file]; !ok {
+			diagnostics[file] = []Diagnostic{}
+		}
+	}
+
+	for file, ds := range diagnostics {
+		uri := pathToURI(file)
+		if file == doc.path {
+			uri = doc.uri
+		}
+		if err := s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+			URI:         uri,
+			Diagnostics: ds,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
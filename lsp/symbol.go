@@ -0,0 +1,125 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ysugimoto/falco/ast"
+)
+
+var includeStatementRegex = regexp.MustCompile(`^\s*include\s+"([^"]+)"`)
+
+// VCL identifier could contain dot (variable), hyphen (HTTP header name) and colon (object access)
+func isIdentChar(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || r == ':' ||
+		(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// Convert LSP character offset, which is counted in UTF-16 code units, to rune offset in the line.
+// The offset is clamped to the end of line
+func runeOffset(line string, character int) int {
+	var units, offset int
+	for _, r := range line {
+		if units >= character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		offset++
+	}
+	return offset
+}
+
+// Convert rune offset in the line to LSP character offset which is counted in UTF-16 code units
+func utf16Offset(line string, offset int) int {
+	var units int
+	for i, r := range []rune(line) {
+		if i >= offset {
+			break
+		}
+		units += utf16.RuneLen(r)
+	}
+	return units
+}
+
+// Find the word and its range at the position
+func wordAt(line string, pos Position) (string, Range) {
+	runes := []rune(line)
+	if pos.Character > utf16Offset(line, len(runes)) {
+		return "", Range{}
+	}
+
+	start := runeOffset(line, pos.Character)
+	end := start
+	for start > 0 && isIdentChar(runes[start-1]) {
+		start--
+	}
+	for end < len(runes) && isIdentChar(runes[end]) {
+		end++
+	}
+	return string(runes[start:end]), Range{
+		Start: Position{Line: pos.Line, Character: utf16Offset(line, start)},
+		End:   Position{Line: pos.Line, Character: utf16Offset(line, end)},
+	}
+}
+
+// Find the word prefix before the position, used for completion
+func prefixAt(line string, pos Position) string {
+	runes := []rune(line)
+	end := runeOffset(line, pos.Character)
+	start := end
+	for start > 0 && isIdentChar(runes[start-1]) {
+		start--
+	}
+	return string(runes[start:end])
+}
+
+// Find the subroutine declaration which surrounds the position
+func (a *analysis) enclosingSubroutine(path string, pos Position) *ast.SubroutineDeclaration {
+	line := pos.Line + 1
+	for _, sub := range a.ctx.Subroutines {
+		decl := sub.Decl
+		if decl == nil || decl.Token.File != path {
+			continue
+		}
+		if decl.Token.Line <= line && line <= decl.Block.EndLine {
+			return decl
+		}
+	}
+	return nil
+}
+
+// Walk statements recursively including nested blocks
+func walkStatements(statements []ast.Statement, fn func(stmt ast.Statement)) {
+	for _, stmt := range statements {
+		fn(stmt)
+		switch t := stmt.(type) {
+		case *ast.BlockStatement:
+			walkStatements(t.Statements, fn)
+		case *ast.IfStatement:
+			walkStatements(t.Consequence.Statements, fn)
+			for _, a := range t.Another {
+				walkStatements(a.Consequence.Statements, fn)
+			}
+			if t.Alternative != nil {
+				walkStatements(t.Alternative.Consequence.Statements, fn)
+			}
+		case *ast.SwitchStatement:
+			for _, c := range t.Cases {
+				walkStatements(c.Statements, fn)
+			}
+		}
+	}
+}
+
+// Split dynamic variable name which contains declaration name like "backend.F_origin.healthy".
+// Returns declaration name if the variable refers to a declaration
+func declarationNameInVariable(word string) string {
+	for _, prefix := range []string{"backend.", "director.", "ratecounter."} {
+		if rest, found := strings.CutPrefix(word, prefix); found {
+			name, _, _ := strings.Cut(rest, ".")
+			return name
+		}
+	}
+	return ""
+}
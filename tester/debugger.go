@@ -6,10 +6,14 @@ import (
 
 	"github.com/ysugimoto/falco/ast"
 	"github.com/ysugimoto/falco/interpreter"
+	"github.com/ysugimoto/falco/interpreter/exception"
 )
 
 type Debugger struct {
 	stack []string
+
+	// External debugger like DAP which also receives debugging events
+	attached interpreter.Debugger
 }
 
 func NewDebugger() *Debugger {
@@ -17,11 +21,17 @@ func NewDebugger() *Debugger {
 }
 
 func (d *Debugger) Run(node ast.Node) interpreter.DebugState {
+	if d.attached != nil {
+		return d.attached.Run(node)
+	}
 	return interpreter.DebugPass
 }
 
 func (d *Debugger) Message(msg string) {
-	// Discard message
+	if d.attached != nil {
+		d.attached.Message(msg)
+	}
+	// Otherwise, discard message
 }
 
 func (d *Debugger) Log(stmt *ast.LogStatement, value string) {
@@ -31,4 +41,21 @@ func (d *Debugger) Log(stmt *ast.LogStatement, value string) {
 		value, filepath.Base(token.File), token.Line, token.Position,
 	)
 	d.stack = append(d.stack, msg)
+	if d.attached != nil {
+		d.attached.Log(stmt, value)
+	}
+}
+
+// Implements interpreter.ExceptionDebugger to pass the exception to the attached debugger
+func (d *Debugger) Exception(node ast.Node, e *exception.Exception) {
+	if v, ok := d.attached.(interpreter.ExceptionDebugger); ok {
+		v.Exception(node, e)
+	}
+}
+
+// Implements interpreter.SnapshotDebugger to pass the snapshot to the attached debugger
+func (d *Debugger) Snapshot(s *interpreter.Snapshot) {
+	if v, ok := d.attached.(interpreter.SnapshotDebugger); ok {
+		v.Snapshot(s)
+	}
 }
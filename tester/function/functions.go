@@ -89,6 +89,23 @@ func testingFunctions(i *interpreter.Interpreter, defs *Definiions) Functions {
 				return false
 			},
 		},
+		// Special testing function of "testing.run_request"
+		// We need to process the full request lifecycle in this function
+		// so pass *interpreter.Interpreter pointer to the function
+		"testing.run_request": {
+			Scope: allScope,
+			Call: func(ctx *context.Context, args ...value.Value) (value.Value, error) {
+				unwrapped, err := unwrapIdentArguments(i, args)
+				if err != nil {
+					return value.Null, errors.WithStack(err)
+				}
+				return Testing_run_request(ctx, i, unwrapped...)
+			},
+			CanStatementCall: true,
+			IsIdentArgument: func(i int) bool {
+				return false
+			},
+		},
 		"testing.fixed_time": {Scope: allScope,
 			Call: func(ctx *context.Context, args ...value.Value) (value.Value, error) {
 				unwrapped, err := unwrapIdentArguments(i, args)
@@ -196,6 +213,14 @@ func testingFunctions(i *interpreter.Interpreter, defs *Definiions) Functions {
 				return false
 			},
 		},
+		"testing.mock_backend": {
+			Scope:            allScope,
+			Call:             Testing_mock_backend,
+			CanStatementCall: true,
+			IsIdentArgument: func(i int) bool {
+				return false
+			},
+		},
 		"testing.set_backend_health": {
 			Scope:            allScope,
 			Call:             Testing_set_backend_health,
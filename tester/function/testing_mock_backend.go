@@ -0,0 +1,71 @@
+package function
+
+import (
+	"strings"
+
+	"github.com/ysugimoto/falco/config"
+	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/function/errors"
+	"github.com/ysugimoto/falco/interpreter/value"
+)
+
+const Testing_mock_backend_Name = "testing.mock_backend"
+
+var Testing_mock_backend_ArgumentTypes = []value.Type{
+	value.BackendType,
+	value.IntegerType,
+	value.StringType,
+	value.StringType,
+}
+
+func Testing_mock_backend_Validate(args []value.Value) error {
+	if len(args) < 2 || len(args) > 4 {
+		return errors.ArgumentNotInRange(Testing_mock_backend_Name, 2, 4, args)
+	}
+	for i := range args {
+		if args[i].Type() != Testing_mock_backend_ArgumentTypes[i] {
+			return errors.TypeMismatch(
+				Testing_mock_backend_Name,
+				i+1,
+				Testing_mock_backend_ArgumentTypes[i],
+				args[i].Type(),
+			)
+		}
+	}
+	return nil
+}
+
+func Testing_mock_backend(
+	ctx *context.Context,
+	args ...value.Value,
+) (value.Value, error) {
+
+	if err := Testing_mock_backend_Validate(args); err != nil {
+		return nil, errors.NewTestingError("%s", err.Error())
+	}
+
+	name := value.Unwrap[*value.Backend](args[0]).String()
+	if _, ok := ctx.Backends[name]; !ok {
+		return value.Null, errors.NewTestingError("Backend %s not found in context", name)
+	}
+
+	mock := &config.MockBackend{
+		Status:  int(value.Unwrap[*value.Integer](args[1]).Value),
+		Headers: make(map[string]string),
+	}
+	if len(args) > 2 {
+		h, err := parseHeaderLines(value.Unwrap[*value.String](args[2]).Value)
+		if err != nil {
+			return value.Null, err
+		}
+		for key, val := range h {
+			mock.Headers[key] = strings.Join(val, ", ")
+		}
+	}
+	if len(args) > 3 {
+		mock.Body = value.Unwrap[*value.String](args[3]).Value
+	}
+
+	ctx.MockedBackends[name] = mock
+	return value.Null, nil
+}
@@ -0,0 +1,87 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/config"
+	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/value"
+)
+
+func Test_Testing_mock_backend(t *testing.T) {
+	backend := &value.Backend{
+		Value: &ast.BackendDeclaration{
+			Name: &ast.Ident{Value: "origin"},
+		},
+	}
+	unknown := &value.Backend{
+		Value: &ast.BackendDeclaration{
+			Name: &ast.Ident{Value: "unknown"},
+		},
+	}
+
+	tests := []struct {
+		args    []value.Value
+		expect  *config.MockBackend
+		isError bool
+	}{
+		{
+			args: []value.Value{backend, &value.Integer{Value: 404}},
+			expect: &config.MockBackend{
+				Status:  404,
+				Headers: map[string]string{},
+			},
+		},
+		{
+			args: []value.Value{
+				backend,
+				&value.Integer{Value: 200},
+				&value.String{Value: "Content-Type: application/json\nX-Foo: bar"},
+				&value.String{Value: `{"ok":true}`},
+			},
+			expect: &config.MockBackend{
+				Status: 200,
+				Headers: map[string]string{
+					"Content-Type": "application/json",
+					"X-Foo":        "bar",
+				},
+				Body: `{"ok":true}`,
+			},
+		},
+		{
+			args:    []value.Value{unknown, &value.Integer{Value: 200}},
+			isError: true,
+		},
+		{
+			args:    []value.Value{backend, &value.String{Value: "200"}},
+			isError: true,
+		},
+		{
+			args:    []value.Value{backend, &value.Integer{Value: 200}, &value.String{Value: "invalid"}},
+			isError: true,
+		},
+	}
+
+	for i, tt := range tests {
+		ctx := &context.Context{
+			Backends:       map[string]*value.Backend{"origin": backend},
+			MockedBackends: map[string]*config.MockBackend{},
+		}
+		_, err := Testing_mock_backend(ctx, tt.args...)
+		if tt.isError {
+			if err == nil {
+				t.Errorf("[%d] Expected error but got nil", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%d] Unexpected error: %s", i, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.expect, ctx.MockedBackends["origin"]); diff != "" {
+			t.Errorf("[%d] Mocked backend mismatch, diff=%s", i, diff)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package function
+
+import (
+	ghttp "net/http"
+	"strings"
+
+	"github.com/ysugimoto/falco/interpreter"
+	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/function/errors"
+	"github.com/ysugimoto/falco/interpreter/http"
+	"github.com/ysugimoto/falco/interpreter/value"
+)
+
+const Testing_run_request_Name = "testing.run_request"
+
+var Testing_run_request_ArgumentTypes = []value.Type{
+	value.StringType,
+	value.StringType,
+	value.StringType,
+	value.StringType,
+}
+
+func Testing_run_request_Validate(args []value.Value) error {
+	if len(args) < 2 || len(args) > 4 {
+		return errors.ArgumentNotInRange(Testing_run_request_Name, 2, 4, args)
+	}
+	for i := range args {
+		if args[i].Type() != Testing_run_request_ArgumentTypes[i] {
+			return errors.TypeMismatch(
+				Testing_run_request_Name,
+				i+1,
+				Testing_run_request_ArgumentTypes[i],
+				args[i].Type(),
+			)
+		}
+	}
+	return nil
+}
+
+func Testing_run_request(
+	ctx *context.Context,
+	i *interpreter.Interpreter,
+	args ...value.Value,
+) (value.Value, error) {
+
+	if err := Testing_run_request_Validate(args); err != nil {
+		return nil, errors.NewTestingError("%s", err.Error())
+	}
+
+	method := value.Unwrap[*value.String](args[0]).Value
+	url := value.Unwrap[*value.String](args[1]).Value
+	// Relative URL is requested to the host of the testing request
+	if strings.HasPrefix(url, "/") {
+		url = ctx.Request.URL.Scheme + "://" + ctx.Request.Host + url
+	}
+
+	var body string
+	if len(args) > 3 {
+		body = value.Unwrap[*value.String](args[3]).Value
+	}
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		return value.Null, errors.NewTestingError("Invalid request: %s", err.Error())
+	}
+	if len(args) > 2 {
+		if err := setRequestHeaders(req, value.Unwrap[*value.String](args[2]).Value); err != nil {
+			return value.Null, err
+		}
+	}
+
+	state, err := i.TestProcessRequest(req)
+	if err != nil {
+		return value.Null, errors.NewTestingError("%s", err.Error())
+	}
+	return &value.String{Value: state}, nil
+}
+
+// Set request headers from newline separated "Name: Value" lines
+func setRequestHeaders(req *http.Request, headers string) error {
+	h, err := parseHeaderLines(headers)
+	if err != nil {
+		return err
+	}
+	for name, values := range h {
+		if name == "Host" {
+			req.Host = values[0]
+		}
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	return nil
+}
+
+// Parse newline separated "Name: Value" lines to the header
+func parseHeaderLines(headers string) (ghttp.Header, error) {
+	h := ghttp.Header{}
+	for _, line := range strings.Split(headers, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, val, found := strings.Cut(line, ":")
+		if !found {
+			return nil, errors.NewTestingError("Invalid header line: %s", line)
+		}
+		h.Add(strings.TrimSpace(name), strings.TrimSpace(val))
+	}
+	return h, nil
+}
@@ -0,0 +1,123 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ysugimoto/falco/interpreter"
+	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/http"
+	"github.com/ysugimoto/falco/interpreter/value"
+	"github.com/ysugimoto/falco/resolver"
+)
+
+func Test_Testing_run_request(t *testing.T) {
+	vcl := `
+backend origin {
+  .host = "example.com";
+  .port = "443";
+}
+
+sub vcl_recv {
+  if (req.http.Authorization) {
+    return (pass);
+  }
+  return (lookup);
+}
+
+sub vcl_deliver {
+  set resp.http.X-Method = req.method;
+  set resp.http.X-Body = req.body;
+}
+`
+	ip := interpreter.New(context.WithResolver(resolver.NewStaticResolver("main", vcl)))
+	req, err := http.NewRequest("GET", "http://localhost", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := ip.TestProcessInit(req); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		args   []value.Value
+		expect string
+	}{
+		{
+			args: []value.Value{
+				&value.String{Value: "GET"},
+				&value.String{Value: "/"},
+			},
+			expect: "MISS",
+		},
+		{
+			args: []value.Value{
+				&value.String{Value: "GET"},
+				&value.String{Value: "http://localhost/"},
+			},
+			expect: "HIT",
+		},
+		{
+			args: []value.Value{
+				&value.String{Value: "POST"},
+				&value.String{Value: "/"},
+				&value.String{Value: "Authorization: Bearer token\nX-Foo: bar"},
+				&value.String{Value: "foo=bar"},
+			},
+			expect: "MISS",
+		},
+	}
+
+	for i, tt := range tests {
+		ret, err := Testing_run_request(&context.Context{Request: req}, ip, tt.args...)
+		if err != nil {
+			t.Errorf("[%d] Unexpected error: %s", i, err)
+			continue
+		}
+		if diff := cmp.Diff(&value.String{Value: tt.expect}, ret); diff != "" {
+			t.Errorf("[%d] Return value mismatch, diff=%s", i, diff)
+		}
+	}
+
+	resp, err := ip.IdentValue("resp.http.X-Method", &interpreter.ExpressionOption{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(&value.String{Value: "POST"}, resp); diff != "" {
+		t.Errorf("resp.http.X-Method mismatch, diff=%s", diff)
+	}
+}
+
+func Test_Testing_run_request_Validate(t *testing.T) {
+	tests := []struct {
+		args    []value.Value
+		isError bool
+	}{
+		{args: []value.Value{&value.String{Value: "GET"}}, isError: true},
+		{args: []value.Value{&value.String{Value: "GET"}, &value.Integer{Value: 1}}, isError: true},
+		{args: []value.Value{&value.String{Value: "GET"}, &value.String{Value: "/"}}},
+	}
+
+	for i, tt := range tests {
+		err := Testing_run_request_Validate(tt.args)
+		if tt.isError != (err != nil) {
+			t.Errorf("[%d] Unexpected validation result: %v", i, err)
+		}
+	}
+}
+
+func Test_setRequestHeaders(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://localhost", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := setRequestHeaders(req, "\n  host: example.com\n  x-foo: bar\n"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if req.Host != "example.com" || req.Header.Get("X-Foo") != "bar" {
+		t.Errorf("Unexpected request headers: host=%s, headers=%v", req.Host, req.Header)
+	}
+	if err := setRequestHeaders(req, "invalid"); err == nil {
+		t.Errorf("Expected error for invalid header line")
+	}
+}
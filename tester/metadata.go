@@ -59,6 +59,15 @@ func (m *Metadata) MatchTags(tags []string) bool {
 	return false
 }
 
+// Check the test suite is selected by the suite name.
+// The name matches to the suite name or the describe group name, and empty name selects all suites
+func (m *Metadata) MatchSuite(suite, group string) bool {
+	if suite == "" {
+		return true
+	}
+	return m.Name == suite || (group != "" && group == suite)
+}
+
 // Find test metadata from annotation comment
 func getTestMetadata(sub *ast.SubroutineDeclaration) *Metadata {
 	metadata := &Metadata{
@@ -202,3 +202,28 @@ func TestTagsMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestSuiteMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		suite  string
+		group  string
+		expect bool
+	}{
+		{name: "no suite selected", suite: "", expect: true},
+		{name: "suite name match", suite: "recv test", expect: true},
+		{name: "suite name unmatch", suite: "fetch test", expect: false},
+		{name: "group name match", suite: "describe group", group: "describe group", expect: true},
+		{name: "group name unmatch", suite: "other group", group: "describe group", expect: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Metadata{Name: "recv test"}
+			actual := m.MatchSuite(tt.suite, tt.group)
+			if diff := cmp.Diff(tt.expect, actual); diff != "" {
+				t.Errorf("MatchSuite result unmatch, diff=%s", diff)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package shared
 import (
 	"math"
 	"sync"
+	"sync/atomic"
 
 	"github.com/ysugimoto/falco/ast"
 	"github.com/ysugimoto/falco/token"
@@ -29,11 +30,14 @@ func (t CoverageType) String() string {
 	}
 }
 
+// Coverage is shared between test files which may run in parallel,
+// so counters are stored as atomic value to be marked concurrently
 type Coverage struct {
-	Subroutines *sync.Map // map[string]uint64
-	Statements  *sync.Map // map[string]uint64
-	Branches    *sync.Map // map[string]uint64
+	Subroutines *sync.Map // map[string]*atomic.Uint64
+	Statements  *sync.Map // map[string]*atomic.Uint64
+	Branches    *sync.Map // map[string]*atomic.Uint64
 	NodeMap     *sync.Map // map[string]token.Token
+	NameMap     *sync.Map // map[string]string, subroutine names
 }
 
 func NewCoverage() *Coverage {
@@ -42,39 +46,43 @@ func NewCoverage() *Coverage {
 		Statements:  &sync.Map{},
 		Branches:    &sync.Map{},
 		NodeMap:     &sync.Map{},
+		NameMap:     &sync.Map{},
 	}
 }
 
 func (c *Coverage) MarkSubroutine(key string) {
 	if v, ok := c.Subroutines.Load(key); ok {
-		c.Subroutines.Swap(key, v.(uint64)+1) // nolint:errcheck
+		v.(*atomic.Uint64).Add(1) // nolint:errcheck
 	}
 }
 
 func (c *Coverage) MarkStatement(key string) {
 	if v, ok := c.Statements.Load(key); ok {
-		c.Statements.Swap(key, v.(uint64)+1) // nolint:errcheck
+		v.(*atomic.Uint64).Add(1) // nolint:errcheck
 	}
 }
 
 func (c *Coverage) MarkBranch(key string) {
 	if v, ok := c.Branches.Load(key); ok {
-		c.Branches.Swap(key, v.(uint64)+1) // nolint:errcheck
+		v.(*atomic.Uint64).Add(1) // nolint:errcheck
 	}
 }
 
 func (c *Coverage) SetupSubroutine(key string, node ast.Node) {
-	c.Subroutines.LoadOrStore(key, uint64(0))
+	c.Subroutines.LoadOrStore(key, &atomic.Uint64{})
 	c.NodeMap.LoadOrStore(key, node.GetMeta().Token)
+	if sub, ok := node.(*ast.SubroutineDeclaration); ok {
+		c.NameMap.LoadOrStore(key, sub.Name.Value)
+	}
 }
 
 func (c *Coverage) SetupStatement(key string, node ast.Node) {
-	c.Statements.LoadOrStore(key, uint64(0))
+	c.Statements.LoadOrStore(key, &atomic.Uint64{})
 	c.NodeMap.LoadOrStore(key, node.GetMeta().Token)
 }
 
 func (c *Coverage) SetupBranch(key string, node ast.Node) {
-	c.Branches.LoadOrStore(key, uint64(0))
+	c.Branches.LoadOrStore(key, &atomic.Uint64{})
 	c.NodeMap.LoadOrStore(key, node.GetMeta().Token)
 }
 
@@ -84,24 +92,29 @@ func (c *Coverage) Factory() *CoverageFactory {
 		Statements:  make(CoverageFactoryItem),
 		Branches:    make(CoverageFactoryItem),
 		NodeMap:     make(map[string]token.Token),
+		NameMap:     make(map[string]string),
 	}
 
 	c.Subroutines.Range(func(key, val any) bool {
-		r.Subroutines[key.(string)] = val.(uint64) // nolint:errcheck
+		r.Subroutines[key.(string)] = val.(*atomic.Uint64).Load() // nolint:errcheck
 		return true
 	})
 	c.Statements.Range(func(key, val any) bool {
-		r.Statements[key.(string)] = val.(uint64) // nolint:errcheck
+		r.Statements[key.(string)] = val.(*atomic.Uint64).Load() // nolint:errcheck
 		return true
 	})
 	c.Branches.Range(func(key, val any) bool {
-		r.Branches[key.(string)] = val.(uint64) // nolint:errcheck
+		r.Branches[key.(string)] = val.(*atomic.Uint64).Load() // nolint:errcheck
 		return true
 	})
 	c.NodeMap.Range(func(key, val any) bool {
 		r.NodeMap[key.(string)] = val.(token.Token) // nolint:errcheck
 		return true
 	})
+	c.NameMap.Range(func(key, val any) bool {
+		r.NameMap[key.(string)] = val.(string) // nolint:errcheck
+		return true
+	})
 
 	return r
 }
@@ -113,6 +126,7 @@ type CoverageFactory struct {
 	Statements  CoverageFactoryItem
 	Branches    CoverageFactoryItem
 	NodeMap     map[string]token.Token
+	NameMap     map[string]string
 }
 
 func (c *CoverageFactory) Report() *CoverageReport {
@@ -4,6 +4,7 @@ import (
 	ghttp "net/http"
 	"path/filepath"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -12,10 +13,8 @@ import (
 	"github.com/ysugimoto/falco/config"
 	"github.com/ysugimoto/falco/interpreter"
 	"github.com/ysugimoto/falco/interpreter/context"
-	"github.com/ysugimoto/falco/interpreter/function"
 	"github.com/ysugimoto/falco/interpreter/http"
 	"github.com/ysugimoto/falco/interpreter/value"
-	"github.com/ysugimoto/falco/interpreter/variable"
 	"github.com/ysugimoto/falco/lexer"
 	"github.com/ysugimoto/falco/parser"
 	"github.com/ysugimoto/falco/resolver"
@@ -35,6 +34,9 @@ type Tester struct {
 	config             *config.TestConfig
 	counter            *shared.Counter
 	coverage           *shared.Coverage
+
+	// Factory of the external debugger which is attached to each test interpreter
+	attach func(i *interpreter.Interpreter) interpreter.Debugger
 }
 
 func New(c *config.TestConfig, opts []context.Option) *Tester {
@@ -50,6 +52,21 @@ func New(c *config.TestConfig, opts []context.Option) *Tester {
 	return t
 }
 
+// Attach the external debugger like DAP to test interpreters.
+// The function is called for each test suite with the interpreter which runs the suite
+func (t *Tester) AttachDebugger(fn func(i *interpreter.Interpreter) interpreter.Debugger) {
+	t.attach = fn
+}
+
+// Create the debugger for each test suite
+func (t *Tester) newDebugger(i *interpreter.Interpreter) *Debugger {
+	d := NewDebugger()
+	if t.attach != nil {
+		d.attached = t.attach(i)
+	}
+	return d
+}
+
 // Find test target VCL files
 // Note that:
 // - Test files must have ".test.vcl" extension e.g default.test.vcl
@@ -71,21 +88,21 @@ func (t *Tester) listTestFiles(main string) ([]string, error) {
 	return dedupeFiles(testFiles), nil
 }
 
-// Only expose function for running tests
+// Run tests of test files which are found from the main VCL directory and include paths
 func (t *Tester) Run(main string) (*TestFactory, error) {
 	// Find test target VCL files
 	targetFiles, err := t.listTestFiles(main)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	// Run tests
-	var results []*TestResult
-	for i := range targetFiles {
-		result, err := t.run(targetFiles[i])
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-		results = append(results, result)
+	return t.RunFiles(targetFiles...)
+}
+
+// Run tests of the specified test files
+func (t *Tester) RunFiles(files ...string) (*TestFactory, error) {
+	results, err := t.runFiles(files)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
 	factory := &TestFactory{
@@ -98,6 +115,41 @@ func (t *Tester) Run(main string) (*TestFactory, error) {
 	return factory, nil
 }
 
+// Run test files concurrently up to parallel option.
+// Results are stored in the same order of test files in order to make output deterministic
+func (t *Tester) runFiles(files []string) ([]*TestResult, error) {
+	parallel := t.config.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]*TestResult, len(files))
+	errs := make([]error, len(files))
+	sem := make(chan struct{}, parallel)
+
+	var wg sync.WaitGroup
+	for i := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(index int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[index], errs[index] = t.run(files[index])
+		}(i)
+	}
+	wg.Wait()
+
+	// Report the error of the first file in order
+	for i := range errs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+	}
+	return results, nil
+}
+
 // Actually run testing method
 func (t *Tester) run(testFile string) (*TestResult, error) {
 	resolvers, err := resolver.NewFileResolvers(testFile, t.config.IncludePaths)
@@ -157,11 +209,11 @@ func (t *Tester) run(testFile string) (*TestResult, error) {
 				metadata := getTestMetadata(st)
 				for _, s := range metadata.Scopes {
 					// Attach new debugger for each test suite
-					d := NewDebugger()
+					d := t.newDebugger(i)
 					i.Debugger = d
 
-					// Skip this testsuite when marked as @skip or @tag matched
-					if metadata.Skip || metadata.MatchTags(t.config.Tags) {
+					// Skip this testsuite when marked as @skip, @tag matched or not selected
+					if metadata.Skip || metadata.MatchTags(t.config.Tags) || !metadata.MatchSuite(t.config.Suite, "") {
 						cases = append(cases, &TestCase{
 							Name:  metadata.Name,
 							Scope: s.String(),
@@ -239,11 +291,11 @@ func (t *Tester) runDescribedTests(
 		metadata := getTestMetadata(sub)
 		for _, s := range metadata.Scopes {
 			// Attach new debugger for each test suite
-			debugger := NewDebugger()
+			debugger := t.newDebugger(i)
 			i.Debugger = debugger
 
-			// Skip this testsuite when marked as @skip or @tag matched
-			if metadata.Skip || metadata.MatchTags(t.config.Tags) {
+			// Skip this testsuite when marked as @skip, @tag matched or not selected
+			if metadata.Skip || metadata.MatchTags(t.config.Tags) || !metadata.MatchSuite(t.config.Suite, d.Name.Value) {
 				cases = append(cases, &TestCase{
 					Name:  metadata.Name,
 					Scope: s.String(),
@@ -313,8 +365,8 @@ func (t *Tester) setupInterpreter(defs *tf.Definiions) *interpreter.Interpreter
 		}
 		return nil
 	}
-	variable.Inject(&tv.TestingVariables{})
-	function.Inject(tf.TestingFunctions(i, defs, t.counter, t.coverage))
+	i.InjectVariable(&tv.TestingVariables{Process: i.Process})
+	i.InjectFunctions(tf.TestingFunctions(i, defs, t.counter, t.coverage))
 
 	return i
 }
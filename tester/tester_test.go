@@ -0,0 +1,67 @@
+package tester
+
+import (
+	"testing"
+
+	"github.com/ysugimoto/falco/ast"
+	"github.com/ysugimoto/falco/config"
+	"github.com/ysugimoto/falco/interpreter"
+	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/resolver"
+)
+
+type recordDebugger struct {
+	files map[string]int
+}
+
+func (d *recordDebugger) Run(node ast.Node) interpreter.DebugState {
+	d.files[node.GetMeta().Token.File]++
+	return interpreter.DebugPass
+}
+func (d *recordDebugger) Message(msg string)                       {}
+func (d *recordDebugger) Log(stmt *ast.LogStatement, value string) {}
+
+func TestAttachDebugger(t *testing.T) {
+	main := "../examples/testing/group/group.vcl"
+	resolvers, err := resolver.NewFileResolvers(main, []string{})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+
+	tc := &config.TestConfig{
+		Filter: "*.test.vcl",
+		Suite:  "test_fetch",
+	}
+	tr := New(tc, []context.Option{context.WithResolver(resolvers[0])})
+	d := &recordDebugger{files: map[string]int{}}
+	var attached int
+	tr.AttachDebugger(func(i *interpreter.Interpreter) interpreter.Debugger {
+		attached++
+		return d
+	})
+
+	factory, err := tr.RunFiles("../examples/testing/group/group.test.vcl")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	if attached != 2 {
+		t.Errorf("Debugger should be attached for each suite, got=%d", attached)
+	}
+	if len(d.files) == 0 {
+		t.Errorf("Attached debugger should receive statements")
+	}
+
+	cases := factory.Results[0].Cases
+	if len(cases) != 2 {
+		t.Errorf("Unexpected number of cases, got=%d", len(cases))
+		return
+	}
+	if !cases[0].Skip || cases[0].Name != "test_recv" {
+		t.Errorf("test_recv should be skipped because it is not selected")
+	}
+	if cases[1].Skip || cases[1].Error != nil {
+		t.Errorf("test_fetch should pass, skip=%t, error=%v", cases[1].Skip, cases[1].Error)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/ysugimoto/falco/ast"
 	"github.com/ysugimoto/falco/interpreter/context"
+	"github.com/ysugimoto/falco/interpreter/process"
 	"github.com/ysugimoto/falco/interpreter/value"
 	iv "github.com/ysugimoto/falco/interpreter/variable"
 )
@@ -17,10 +18,22 @@ const (
 	TESTING_SYNTHETIC_BODY     = "testing.synthetic_body"
 	TESTING_ORIGIN_HOST_HEADER = "testing.origin_host_header"
 	TESTING_RETURN_VALUE       = "testing.return_value"
+	TESTING_FLOWS              = "testing.flows"
+
+	// Recorded request which would have been sent to the mocked backend
+	TESTING_BACKEND_REQUEST_COUNT   = "testing.backend_request_count"
+	TESTING_BACKEND_REQUEST_BACKEND = "testing.backend_request.backend"
+	TESTING_BACKEND_REQUEST_METHOD  = "testing.backend_request.method"
+	TESTING_BACKEND_REQUEST_URL     = "testing.backend_request.url"
+	TESTING_BACKEND_REQUEST_BODY    = "testing.backend_request.body"
+	TESTING_BACKEND_REQUEST_HTTP    = "testing.backend_request.http."
 )
 
 type TestingVariables struct {
 	iv.InjectVariable
+
+	// Process getter of the current request, used for inspecting subroutine flows
+	Process func() *process.Process
 }
 
 func (v *TestingVariables) Get(ctx *context.Context, scope context.Scope, name string) (value.Value, error) {
@@ -62,8 +75,55 @@ func (v *TestingVariables) Get(ctx *context.Context, scope context.Scope, name s
 			return value.Null, nil
 		}
 		return ctx.TestingReturnValue, nil
+	case TESTING_FLOWS:
+		if v.Process == nil {
+			return &value.String{}, nil
+		}
+		// Comma separated subroutine names in processed order
+		flows := v.Process().Flows
+		names := make([]string, len(flows))
+		for i := range flows {
+			names[i] = flows[i].Subroutine
+		}
+		return &value.String{Value: strings.Join(names, ",")}, nil
+	case TESTING_BACKEND_REQUEST_COUNT:
+		return &value.Integer{Value: int64(len(ctx.MockedBackendRequests))}, nil
+	}
+
+	if strings.HasPrefix(name, "testing.backend_request.") {
+		return getBackendRequestValue(ctx, name)
+	}
+
+	return nil, errors.New("Not Found")
+}
+
+// Get the value of the last recorded backend request, returns notset string if no request is recorded
+func getBackendRequestValue(ctx *context.Context, name string) (value.Value, error) {
+	if len(ctx.MockedBackendRequests) == 0 {
+		return &value.String{IsNotSet: true}, nil
+	}
+	last := ctx.MockedBackendRequests[len(ctx.MockedBackendRequests)-1]
+
+	switch name {
+	case TESTING_BACKEND_REQUEST_BACKEND:
+		return &value.String{Value: last.Backend}, nil
+	case TESTING_BACKEND_REQUEST_METHOD:
+		return &value.String{Value: last.Request.Method}, nil
+	case TESTING_BACKEND_REQUEST_URL:
+		return &value.String{Value: last.Request.URL.RequestURI()}, nil
+	case TESTING_BACKEND_REQUEST_BODY:
+		return &value.String{Value: last.Body}, nil
 	}
 
+	if header, found := strings.CutPrefix(name, TESTING_BACKEND_REQUEST_HTTP); found {
+		if header == "Host" {
+			return &value.String{Value: last.Request.Host}, nil
+		}
+		if v := last.Request.Header.Values(header); len(v) > 0 {
+			return &value.String{Value: strings.Join(v, ", ")}, nil
+		}
+		return &value.String{IsNotSet: true}, nil
+	}
 	return nil, errors.New("Not Found")
 }
 